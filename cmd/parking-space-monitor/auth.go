@@ -0,0 +1,60 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/auth"
+)
+
+// newAuthenticator configures request authentication from the environment:
+//
+//	AUTH_API_KEYS_FILE      JSON file of static API keys
+//	AUTH_JWT_HS256_SECRET   shared secret for HS256 tokens
+//	AUTH_JWT_RS256_KEY_FILE PEM RSA public key for RS256 tokens
+//	AUTH_JWT_ISSUER         required iss claim, if set
+//	AUTH_JWT_AUDIENCE       required aud claim, if set
+//
+// At least one credential source must be configured.
+func newAuthenticator() (*auth.Authenticator, error) {
+    a := &auth.Authenticator{
+        Issuer:   os.Getenv("AUTH_JWT_ISSUER"),
+        Audience: os.Getenv("AUTH_JWT_AUDIENCE"),
+        Leeway:   30 * time.Second,
+    }
+    configured := false
+
+    if path := os.Getenv("AUTH_API_KEYS_FILE"); path != "" {
+        f, err := os.Open(path)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        if err := a.LoadAPIKeys(f); err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        configured = true
+    }
+
+    if secret := os.Getenv("AUTH_JWT_HS256_SECRET"); secret != "" {
+        a.HMACSecret = []byte(secret)
+        configured = true
+    }
+
+    if path := os.Getenv("AUTH_JWT_RS256_KEY_FILE"); path != "" {
+        data, err := os.ReadFile(path)
+        if err != nil {
+            return nil, err
+        }
+        if a.RSAPublicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        configured = true
+    }
+
+    if !configured {
+        return nil, fmt.Errorf("no credentials configured, set AUTH_API_KEYS_FILE, AUTH_JWT_HS256_SECRET or AUTH_JWT_RS256_KEY_FILE")
+    }
+    return a, nil
+}
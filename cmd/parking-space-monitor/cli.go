@@ -0,0 +1,216 @@
+// cmd/parking-space-monitor/cli.go
+
+package main
+
+import (
+    "database/sql"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/importer"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/SangBejoo/parking-space-monitor/internal/snapshot"
+)
+
+// newRepository builds the aggregate repository used by snapshot commands.
+func newRepository(db *sql.DB) *repository.Repository {
+    return &repository.Repository{
+        DB:                db,
+        TaxiRepository:    &repository.TaxiRepository{DB: db},
+        TaxiRegistryRepository: &repository.TaxiRegistryRepository{DB: db},
+        PlaceRepository:   &repository.PlaceRepository{DB: db},
+        MappingRepository: &repository.MappingRepository{DB: db},
+        QueueRepository:   &repository.QueueRepository{DB: db},
+        EventRepository:   &repository.EventRepository{DB: db},
+        DwellRepository:   &repository.DwellRepository{DB: db},
+        ViolationRepository: &repository.ViolationRepository{DB: db},
+        AssignmentRepository: &repository.AssignmentRepository{DB: db},
+        RecommendationRepository: &repository.RecommendationRepository{DB: db},
+    }
+}
+
+// runCommand dispatches a CLI subcommand.
+func runCommand(db *sql.DB, name string, args []string) error {
+    switch name {
+    case "import-places":
+        return runImportPlaces(db, args)
+    case "export":
+        return runExport(db, args)
+    case "import":
+        return runImport(db, args)
+    case "purge":
+        return runPurge(db, args)
+    case "compact-occupancy":
+        return runCompactOccupancy(db, args)
+    default:
+        return fmt.Errorf("unknown command %q", name)
+    }
+}
+
+// runImportPlaces imports places from a GeoJSON, KML or WKT/CSV file and
+// prints the import result as JSON.
+//
+//	parking-space-monitor import-places [-format geojson|kml|wkt] [-name-property name] [-dry-run] FILE
+func runImportPlaces(db *sql.DB, args []string) error {
+    fs := flag.NewFlagSet("import-places", flag.ContinueOnError)
+    format := fs.String("format", "", "file format: geojson, kml or wkt (detected from the file extension by default)")
+    nameProperty := fs.String("name-property", importer.DefaultNameProperty, "property used as the place name")
+    dryRun := fs.Bool("dry-run", false, "show creates, updates and conflicts without committing")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if fs.NArg() != 1 {
+        return fmt.Errorf("expected exactly one file argument")
+    }
+
+    path := fs.Arg(0)
+    if *format == "" {
+        *format = importer.DetectFormat(filepath.Ext(path), "")
+    }
+
+    file, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    records, err := importer.Parse(*format, file, *nameProperty)
+    if err != nil {
+        return err
+    }
+
+    placeRepo := &repository.PlaceRepository{DB: db}
+    result, err := placeRepo.ImportPlaces(records, *dryRun)
+    if err != nil {
+        return err
+    }
+
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(result); err != nil {
+        return err
+    }
+
+    if !result.Committed && !result.DryRun {
+        return fmt.Errorf("nothing imported: %d invalid, %d conflicting records", len(result.Invalid), len(result.Conflicts))
+    }
+    return nil
+}
+
+// runExport writes a snapshot archive of places, taxis, mappings and taxi
+// durations to a file, or to stdout when no file is given.
+//
+//	parking-space-monitor export [-o FILE]
+func runExport(db *sql.DB, args []string) error {
+    fs := flag.NewFlagSet("export", flag.ContinueOnError)
+    output := fs.String("o", "", "archive to write (default stdout)")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    snap, err := newRepository(db).Snapshot()
+    if err != nil {
+        return err
+    }
+
+    if *output == "" {
+        return snapshot.Write(os.Stdout, snap)
+    }
+
+    file, err := os.Create(*output)
+    if err != nil {
+        return err
+    }
+    if err := snapshot.Write(file, snap); err != nil {
+        file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+// runImport restores a snapshot archive written by export.
+//
+//	parking-space-monitor import [-replace] FILE
+func runImport(db *sql.DB, args []string) error {
+    fs := flag.NewFlagSet("import", flag.ContinueOnError)
+    replace := fs.Bool("replace", false, "remove existing places, taxis and mappings before restoring")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if fs.NArg() != 1 {
+        return fmt.Errorf("expected exactly one archive argument")
+    }
+
+    file, err := os.Open(fs.Arg(0))
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    snap, err := snapshot.Read(file)
+    if err != nil {
+        return err
+    }
+    if err := newRepository(db).Restore(snap, *replace); err != nil {
+        return err
+    }
+
+    fmt.Fprintf(os.Stderr, "restored %d places, %d taxis, %d mappings, %d taxi durations, %d dwell sessions from snapshot v%d\n",
+        len(snap.Places), len(snap.Taxis), len(snap.Mappings), len(snap.Durations), len(snap.DwellSessions), snap.Version)
+    return nil
+}
+
+// runPurge permanently removes places and taxi locations that were
+// soft-deleted longer ago than the retention window, along with the
+// positions of the purged taxis.
+//
+//	parking-space-monitor purge [-retention 720h]
+func runPurge(db *sql.DB, args []string) error {
+    fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+    retention := fs.Duration("retention", repository.DefaultPurgeRetention, "how long soft-deleted rows are kept")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+    if *retention < 0 {
+        return fmt.Errorf("retention must not be negative")
+    }
+
+    result, err := newRepository(db).Purge(*retention)
+    if err != nil {
+        return err
+    }
+
+    fmt.Fprintf(os.Stderr, "purged %d places and %d taxi locations, with %d positions, deleted before %s\n",
+        result.Places, result.TaxiLocations, result.Positions, result.Cutoff.Format(time.RFC3339))
+    return nil
+}
+
+// runCompactOccupancy rolls up closed occupancy buckets into coarser ones and
+// removes rolled-up buckets past their retention. A retention of 0 keeps a
+// resolution forever.
+//
+//	parking-space-monitor compact-occupancy [-minute-retention 168h] [-hour-retention 2160h] [-day-retention 0]
+func runCompactOccupancy(db *sql.DB, args []string) error {
+    defaults := repository.DefaultOccupancyRetention
+    fs := flag.NewFlagSet("compact-occupancy", flag.ContinueOnError)
+    minute := fs.Duration("minute-retention", defaults.Minute, "how long minute buckets are kept")
+    hour := fs.Duration("hour-retention", defaults.Hour, "how long hour buckets are kept")
+    day := fs.Duration("day-retention", defaults.Day, "how long day buckets are kept")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    placeRepo := &repository.PlaceRepository{DB: db}
+    result, err := placeRepo.CompactOccupancy(repository.OccupancyRetention{Minute: *minute, Hour: *hour, Day: *day})
+    if err != nil {
+        return err
+    }
+
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    return enc.Encode(result)
+}
@@ -5,9 +5,12 @@ package main
 import (
     "log"
     "net/http"
+    "os"
 
     "github.com/gorilla/mux"
+    "github.com/SangBejoo/parking-space-monitor/internal/auth"
     "github.com/SangBejoo/parking-space-monitor/internal/handlers"
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
     "github.com/SangBejoo/parking-space-monitor/internal/repository"
     "github.com/SangBejoo/parking-space-monitor/internal/scheduler"
     "github.com/SangBejoo/parking-space-monitor/pkg/utils"
@@ -19,10 +22,35 @@ func main() {
     db := utils.InitDB(connStr)
     defer db.Close()
 
+    // Run a CLI subcommand instead of the server when one is given
+    if len(os.Args) > 1 {
+        if err := runCommand(db, os.Args[1], os.Args[2:]); err != nil {
+            log.Fatalf("%s: %v", os.Args[1], err)
+        }
+        return
+    }
+
+    // Taxis that stop reporting turn stale and then offline
+    presence, excludeStale, err := presenceFromEnv()
+    if err != nil {
+        log.Fatalf("Failed to configure taxi presence: %v", err)
+    }
+    notifyRebalancing, err := notifyRebalancingFromEnv()
+    if err != nil {
+        log.Fatalf("Failed to configure rebalancing: %v", err)
+    }
+
     // Initialize repositories
-    taxiRepo := &repository.TaxiRepository{DB: db}
+    taxiRepo := &repository.TaxiRepository{DB: db, Presence: presence}
+    registryRepo := &repository.TaxiRegistryRepository{DB: db}
     placeRepo := &repository.PlaceRepository{DB: db}
     mappingRepo := &repository.MappingRepository{DB: db}
+    queueRepo := &repository.QueueRepository{DB: db}
+    eventRepo := &repository.EventRepository{DB: db}
+    dwellRepo := &repository.DwellRepository{DB: db}
+    violationRepo := &repository.ViolationRepository{DB: db}
+    assignmentRepo := &repository.AssignmentRepository{DB: db}
+    recommendationRepo := &repository.RecommendationRepository{DB: db}
     // Initialize CountersRepository if needed
     // countersRepo := &repository.CountersRepository{DB: db} 
 
@@ -30,45 +58,146 @@ func main() {
     repo := &repository.Repository{
         DB:                db,
         TaxiRepository:    taxiRepo,
+        TaxiRegistryRepository: registryRepo,
         PlaceRepository:   placeRepo,
         MappingRepository: mappingRepo,
+        QueueRepository:   queueRepo,
+        EventRepository:   eventRepo,
+        DwellRepository:   dwellRepo,
+        ViolationRepository: violationRepo,
+        AssignmentRepository: assignmentRepo,
+        RecommendationRepository: recommendationRepo,
         // CountersRepository: countersRepo, // Add CountersRepository if needed
     }
 
     // Initialize scheduler
     sched := scheduler.NewScheduler(repo)
+    sched.ExcludeStale = excludeStale
+    sched.NotifyRebalancing = notifyRebalancing
 
     // Initialize handlers
-    taxiHandler := &handlers.TaxiHandler{Repo: taxiRepo}
+    taxiHandler := &handlers.TaxiHandler{Repo: taxiRepo, Registry: registryRepo}
+    registryHandler := &handlers.TaxiRegistryHandler{Repo: registryRepo}
     placeHandler := &handlers.PlaceHandler{Repo: placeRepo}
-    mappingHandler := &handlers.MappingHandler{Repo: mappingRepo, Scheduler: sched}
+    mappingHandler := &handlers.MappingHandler{Repo: mappingRepo, Places: placeRepo, Taxis: registryRepo, Scheduler: sched}
+    queueHandler := &handlers.QueueHandler{Repo: queueRepo}
+    exportHandler := &handlers.ExportHandler{Repo: repo}
+    purgeHandler := &handlers.PurgeHandler{Repo: repo}
+    reportHandler := &handlers.ReportHandler{Dwell: dwellRepo}
+    eventHandler := &handlers.EventHandler{Repo: eventRepo}
+    violationHandler := &handlers.ViolationHandler{Repo: violationRepo, Places: placeRepo}
+    assignmentHandler := &handlers.AssignmentHandler{Repo: assignmentRepo}
+    recommendationHandler := &handlers.RecommendationHandler{Repo: recommendationRepo}
+    heatmapHandler := &handlers.HeatmapHandler{Taxis: taxiRepo, Places: placeRepo}
+    auditor := &handlers.Auditor{Repo: &repository.AuditRepository{DB: db}}
+
+    // Describe how changes to each entity are recorded in the audit log
+    taxiAudit := handlers.AuditSpec{Entity: "taxi_location", IDField: "taxi_id", Load: taxiHandler.LoadTaxi}
+    registryAudit := handlers.AuditSpec{Entity: "taxi", IDField: "taxi_id", Load: registryHandler.LoadRegisteredTaxi}
+    placeAudit := handlers.AuditSpec{Entity: "place", IDField: "place_id", Load: placeHandler.LoadPlace}
+    importAudit := handlers.AuditSpec{Entity: "place", Action: models.AuditImport, Skip: handlers.IsDryRun}
+    mappingAudit := handlers.AuditSpec{Entity: "mapping", IDField: "id", Load: mappingHandler.LoadMapping}
+    taxiRestoreAudit := handlers.AuditSpec{Entity: "taxi_location", Action: models.AuditRestore, Load: taxiHandler.LoadTaxi}
+    placeRestoreAudit := handlers.AuditSpec{Entity: "place", Action: models.AuditRestore, Load: placeHandler.LoadPlace}
+    purgeAudit := handlers.AuditSpec{Entity: "deleted_rows", Action: models.AuditPurge}
+    disputeAudit := handlers.AuditSpec{Entity: "violation", Action: models.AuditDispute, Load: violationHandler.LoadViolation}
+    resolveAudit := handlers.AuditSpec{Entity: "violation", Action: models.AuditResolve, Load: violationHandler.LoadViolation}
+    assignmentAudit := handlers.AuditSpec{Entity: "mapping", IDField: "id", Load: assignmentHandler.LoadAssignment}
+
+    // Every request is authenticated; its operator scopes what it can see
+    authenticator, err := newAuthenticator()
+    if err != nil {
+        log.Fatalf("Failed to configure authentication: %v", err)
+    }
 
     // Initialize router
     router := mux.NewRouter()
+    router.Use(handlers.RequestID)
+    router.Use(handlers.Authenticate(authenticator))
 
     // Register CRUD routes for Taxis
-    router.HandleFunc("/taxi", taxiHandler.CreateTaxi).Methods("POST")
-    router.HandleFunc("/taxi", taxiHandler.GetAllTaxis).Methods("GET")
-    router.HandleFunc("/taxi/{id}", taxiHandler.GetTaxi).Methods("GET")
-    router.HandleFunc("/taxi/{id}", taxiHandler.UpdateTaxi).Methods("PUT")
-    router.HandleFunc("/taxi/{id}", taxiHandler.DeleteTaxi).Methods("DELETE")
+    router.HandleFunc("/taxi", handlers.Require(auth.RoleDriver, auditor.Audit(taxiAudit, taxiHandler.CreateTaxi))).Methods("POST")
+    router.HandleFunc("/taxi", handlers.Require(auth.RoleReadonly, taxiHandler.GetAllTaxis)).Methods("GET")
+    router.HandleFunc("/taxi/nearest", handlers.Require(auth.RoleReadonly, taxiHandler.GetNearestTaxis)).Methods("GET")
+    router.HandleFunc("/taxi/{id}", handlers.Require(auth.RoleReadonly, taxiHandler.GetTaxi)).Methods("GET")
+    router.HandleFunc("/taxi/{id}", handlers.Require(auth.RoleDriver, auditor.Audit(taxiAudit, taxiHandler.UpdateTaxi))).Methods("PUT")
+    router.HandleFunc("/taxi/{id}", handlers.Require(auth.RoleDispatcher, auditor.Audit(taxiAudit, taxiHandler.DeleteTaxi))).Methods("DELETE")
+    router.HandleFunc("/taxi/{id}/restore", handlers.Require(auth.RoleDispatcher, auditor.Audit(taxiRestoreAudit, taxiHandler.RestoreTaxi))).Methods("POST")
+    router.HandleFunc("/taxi/{id}/queue", handlers.Require(auth.RoleReadonly, queueHandler.GetTaxiPosition)).Methods("GET")
+
+    // Register CRUD routes for the taxi registry
+    router.HandleFunc("/taxis", handlers.Require(auth.RoleAdmin, auditor.Audit(registryAudit, registryHandler.RegisterTaxi))).Methods("POST")
+    router.HandleFunc("/taxis", handlers.Require(auth.RoleReadonly, registryHandler.GetAllRegisteredTaxis)).Methods("GET")
+    router.HandleFunc("/taxis/{id}", handlers.Require(auth.RoleReadonly, registryHandler.GetRegisteredTaxi)).Methods("GET")
+    router.HandleFunc("/taxis/{id}", handlers.Require(auth.RoleAdmin, auditor.Audit(registryAudit, registryHandler.UpdateRegisteredTaxi))).Methods("PUT")
+    router.HandleFunc("/taxis/{id}", handlers.Require(auth.RoleAdmin, auditor.Audit(registryAudit, registryHandler.DeleteRegisteredTaxi))).Methods("DELETE")
 
     // Register CRUD routes for Places
-    router.HandleFunc("/place", placeHandler.CreatePlace).Methods("POST")
-    router.HandleFunc("/place", placeHandler.GetAllPlaces).Methods("GET")
-    router.HandleFunc("/place/{id}", placeHandler.GetPlace).Methods("GET")
-    router.HandleFunc("/place/{id}", placeHandler.UpdatePlace).Methods("PUT")
-    router.HandleFunc("/place/{id}", placeHandler.DeletePlace).Methods("DELETE")
+    router.HandleFunc("/place", handlers.Require(auth.RoleAdmin, auditor.Audit(placeAudit, placeHandler.CreatePlace))).Methods("POST")
+    router.HandleFunc("/place", handlers.Require(auth.RoleReadonly, placeHandler.GetAllPlaces)).Methods("GET")
+    router.HandleFunc("/place/import", handlers.Require(auth.RoleAdmin, auditor.Audit(importAudit, placeHandler.ImportPlaces))).Methods("POST")
+    router.HandleFunc("/place/{id}", handlers.Require(auth.RoleReadonly, placeHandler.GetPlace)).Methods("GET")
+    router.HandleFunc("/place/{id}/versions", handlers.Require(auth.RoleReadonly, placeHandler.GetPlaceVersions)).Methods("GET")
+    router.HandleFunc("/place/{id}", handlers.Require(auth.RoleAdmin, auditor.Audit(placeAudit, placeHandler.UpdatePlace))).Methods("PUT")
+    router.HandleFunc("/place/{id}", handlers.Require(auth.RoleAdmin, auditor.Audit(placeAudit, placeHandler.DeletePlace))).Methods("DELETE")
+    router.HandleFunc("/place/{id}/restore", handlers.Require(auth.RoleAdmin, auditor.Audit(placeRestoreAudit, placeHandler.RestorePlace))).Methods("POST")
+
+    // Register routes for occupancy
+    router.HandleFunc("/occupancy", handlers.Require(auth.RoleReadonly, placeHandler.GetOccupancy)).Methods("GET")
+    router.HandleFunc("/place/{id}/occupancy/history", handlers.Require(auth.RoleReadonly, placeHandler.GetOccupancyHistory)).Methods("GET")
+    router.HandleFunc("/place/{id}/forecast", handlers.Require(auth.RoleReadonly, placeHandler.GetForecast)).Methods("GET")
+
+    // Register routes for the taxi density heatmap
+    router.HandleFunc("/heatmap", handlers.Require(auth.RoleReadonly, heatmapHandler.GetHeatmap)).Methods("GET")
+
+    // Register routes for events
+    router.HandleFunc("/events", handlers.Require(auth.RoleReadonly, eventHandler.GetEvents)).Methods("GET")
+
+    // Register routes for violations
+    router.HandleFunc("/violations", handlers.Require(auth.RoleReadonly, violationHandler.GetViolations)).Methods("GET")
+    router.HandleFunc("/violations/{id}", handlers.Require(auth.RoleReadonly, violationHandler.GetViolation)).Methods("GET")
+    router.HandleFunc("/violations/{id}/export", handlers.Require(auth.RoleReadonly, violationHandler.ExportViolation)).Methods("GET")
+    router.HandleFunc("/violations/{id}/dispute", handlers.Require(auth.RoleDriver, auditor.Audit(disputeAudit, violationHandler.DisputeViolation))).Methods("POST")
+    router.HandleFunc("/violations/{id}/resolve", handlers.Require(auth.RoleAdmin, auditor.Audit(resolveAudit, violationHandler.ResolveViolation))).Methods("POST")
+
+    // Register routes for reports
+    router.HandleFunc("/reports/dwell", handlers.Require(auth.RoleReadonly, reportHandler.GetDwellReport)).Methods("GET")
+
+    // Register routes for Place queues
+    router.HandleFunc("/place/{id}/queue", handlers.Require(auth.RoleReadonly, queueHandler.GetQueue)).Methods("GET")
+    router.HandleFunc("/place/{id}/queue/dispatch", handlers.Require(auth.RoleDispatcher, queueHandler.DispatchNext)).Methods("POST")
 
     // Register CRUD routes for Mappings
-    router.HandleFunc("/mapping", mappingHandler.CreateMapping).Methods("POST")
-    router.HandleFunc("/mapping", mappingHandler.GetAllMappings).Methods("GET")
-    router.HandleFunc("/mapping/{id}", mappingHandler.GetMapping).Methods("GET")
-    router.HandleFunc("/mapping/{id}", mappingHandler.UpdateMapping).Methods("PUT")
-    router.HandleFunc("/mapping/{id}", mappingHandler.DeleteMapping).Methods("DELETE")
+    router.HandleFunc("/mapping", handlers.Require(auth.RoleDispatcher, auditor.Audit(mappingAudit, mappingHandler.CreateMapping))).Methods("POST")
+    router.HandleFunc("/mapping", handlers.Require(auth.RoleReadonly, mappingHandler.GetAllMappings)).Methods("GET")
+    router.HandleFunc("/mapping/{id}", handlers.Require(auth.RoleReadonly, mappingHandler.GetMapping)).Methods("GET")
+    router.HandleFunc("/mapping/{id}", handlers.Require(auth.RoleDispatcher, auditor.Audit(mappingAudit, mappingHandler.UpdateMapping))).Methods("PUT")
+    router.HandleFunc("/mapping/{id}", handlers.Require(auth.RoleDispatcher, auditor.Audit(mappingAudit, mappingHandler.DeleteMapping))).Methods("DELETE")
+
+    // Register routes for dispatch assignments
+    router.HandleFunc("/assignments", handlers.Require(auth.RoleDispatcher, auditor.Audit(assignmentAudit, assignmentHandler.RequestAssignment))).Methods("POST")
+    router.HandleFunc("/assignments", handlers.Require(auth.RoleReadonly, assignmentHandler.GetAssignments)).Methods("GET")
+    router.HandleFunc("/assignments/{id}", handlers.Require(auth.RoleReadonly, assignmentHandler.GetAssignment)).Methods("GET")
+    router.HandleFunc("/assignments/{id}/accept", handlers.Require(auth.RoleDriver, auditor.Audit(assignmentAudit, assignmentHandler.AcceptAssignment))).Methods("POST")
+    router.HandleFunc("/assignments/{id}/reject", handlers.Require(auth.RoleDriver, auditor.Audit(assignmentAudit, assignmentHandler.RejectAssignment))).Methods("POST")
+    router.HandleFunc("/assignments/{id}/pickup", handlers.Require(auth.RoleDriver, auditor.Audit(assignmentAudit, assignmentHandler.PickUpAssignment))).Methods("POST")
+    router.HandleFunc("/assignments/{id}/cancel", handlers.Require(auth.RoleDispatcher, auditor.Audit(assignmentAudit, assignmentHandler.CancelAssignment))).Methods("POST")
+
+    // Register routes for rebalancing recommendations
+    router.HandleFunc("/recommendations", handlers.Require(auth.RoleReadonly, recommendationHandler.GetRecommendations)).Methods("GET")
+
+    // Register routes for the audit log
+    router.HandleFunc("/audit", handlers.Require(auth.RoleAdmin, auditor.GetAuditLog)).Methods("GET")
+
+    // Register routes for snapshots
+    router.HandleFunc("/export", handlers.Require(auth.RoleAdmin, exportHandler.Export)).Methods("GET")
+
+    // Register routes for maintenance jobs
+    router.HandleFunc("/admin/purge", handlers.Require(auth.RoleAdmin, auditor.Audit(purgeAudit, purgeHandler.Purge))).Methods("POST")
+    router.HandleFunc("/admin/occupancy/compact", handlers.Require(auth.RoleAdmin, placeHandler.CompactOccupancy)).Methods("POST")
 
     // Register routes for Scheduler
-    router.HandleFunc("/mapping/trigger", mappingHandler.TriggerMapping).Methods("POST")
+    router.HandleFunc("/mapping/trigger", handlers.Require(auth.RoleAdmin, mappingHandler.TriggerMapping)).Methods("POST")
 
     // Start the server
     log.Println("Starting server on :8080")
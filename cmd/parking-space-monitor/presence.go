@@ -0,0 +1,43 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// presenceFromEnv configures when taxis count as stale or offline:
+//
+//	TAXI_STALE_AFTER    duration without a location update before a taxi is stale (default 5m)
+//	TAXI_OFFLINE_AFTER  duration without a location update before a taxi is offline (default 30m)
+//	TAXI_EXCLUDE_STALE  take stale taxis out of occupancy too, not only offline ones
+func presenceFromEnv() (models.Presence, bool, error) {
+    presence := models.DefaultPresence
+    for name, dst := range map[string]*time.Duration{
+        "TAXI_STALE_AFTER":   &presence.StaleAfter,
+        "TAXI_OFFLINE_AFTER": &presence.OfflineAfter,
+    } {
+        if v := os.Getenv(name); v != "" {
+            d, err := time.ParseDuration(v)
+            if err != nil || d <= 0 {
+                return presence, false, fmt.Errorf("invalid %s %q", name, v)
+            }
+            *dst = d
+        }
+    }
+    if presence.OfflineAfter < presence.StaleAfter {
+        return presence, false, fmt.Errorf("TAXI_OFFLINE_AFTER must not be shorter than TAXI_STALE_AFTER")
+    }
+
+    excludeStale := false
+    if v := os.Getenv("TAXI_EXCLUDE_STALE"); v != "" {
+        var err error
+        if excludeStale, err = strconv.ParseBool(v); err != nil {
+            return presence, false, fmt.Errorf("invalid TAXI_EXCLUDE_STALE %q", v)
+        }
+    }
+    return presence, excludeStale, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+)
+
+// notifyRebalancingFromEnv reports whether new rebalancing recommendations
+// are also raised as taxi.rebalance events:
+//
+//	REBALANCE_NOTIFY  record an event for every new recommendation (default false)
+//
+// There is no push channel to drivers; driver apps follow GET /events.
+func notifyRebalancingFromEnv() (bool, error) {
+    v := os.Getenv("REBALANCE_NOTIFY")
+    if v == "" {
+        return false, nil
+    }
+    notify, err := strconv.ParseBool(v)
+    if err != nil {
+        return false, fmt.Errorf("invalid REBALANCE_NOTIFY %q", v)
+    }
+    return notify, nil
+}
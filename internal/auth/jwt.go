@@ -0,0 +1,168 @@
+// internal/auth/jwt.go
+package auth
+
+import (
+    "crypto"
+    "crypto/hmac"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// jwtHeader is the JOSE header of a compact JWT.
+type jwtHeader struct {
+    Alg string `json:"alg"`
+    Typ string `json:"typ"`
+}
+
+// jwtClaims are the registered claims checked here plus the claims that
+// make up a Principal.
+type jwtClaims struct {
+    Subject   string   `json:"sub"`
+    Issuer    string   `json:"iss"`
+    Audience  audience `json:"aud"`
+    ExpiresAt *int64   `json:"exp"`
+    NotBefore *int64   `json:"nbf"`
+
+    Role     Role   `json:"role"`
+    Operator string `json:"operator"`
+    TaxiID   string `json:"taxi_id"`
+}
+
+// audience accepts the aud claim as a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+    var single string
+    if err := json.Unmarshal(b, &single); err == nil {
+        *a = audience{single}
+        return nil
+    }
+    var many []string
+    if err := json.Unmarshal(b, &many); err != nil {
+        return errors.New("aud must be a string or an array of strings")
+    }
+    *a = many
+    return nil
+}
+
+func (a audience) contains(s string) bool {
+    for _, v := range a {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
+
+// AuthenticateToken verifies a compact HS256 or RS256 JWT and returns its
+// principal. The algorithm must match a configured key, so an HS256 token
+// can never be checked against the RSA public key. Tokens must carry exp.
+func (a *Authenticator) AuthenticateToken(token string) (*Principal, error) {
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+    }
+
+    var header jwtHeader
+    if err := decodeSegment(parts[0], &header); err != nil {
+        return nil, fmt.Errorf("%w: invalid header", ErrInvalidCredentials)
+    }
+
+    signingInput := []byte(parts[0] + "." + parts[1])
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidCredentials)
+    }
+
+    switch {
+    case header.Alg == "HS256" && a.HMACSecret != nil:
+        mac := hmac.New(sha256.New, a.HMACSecret)
+        mac.Write(signingInput)
+        if !hmac.Equal(signature, mac.Sum(nil)) {
+            return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+        }
+    case header.Alg == "RS256" && a.RSAPublicKey != nil:
+        digest := sha256.Sum256(signingInput)
+        if err := rsa.VerifyPKCS1v15(a.RSAPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+            return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredentials)
+        }
+    default:
+        return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredentials, header.Alg)
+    }
+
+    var claims jwtClaims
+    if err := decodeSegment(parts[1], &claims); err != nil {
+        return nil, fmt.Errorf("%w: invalid claims", ErrInvalidCredentials)
+    }
+    if err := a.checkClaims(claims); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+    }
+
+    p := Principal{Subject: claims.Subject, Role: claims.Role, Operator: claims.Operator, TaxiID: claims.TaxiID}
+    if err := p.Validate(); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+    }
+    return &p, nil
+}
+
+// checkClaims validates the time, issuer and audience claims.
+func (a *Authenticator) checkClaims(c jwtClaims) error {
+    now := time.Now()
+    if c.ExpiresAt == nil {
+        return errors.New("token has no exp")
+    }
+    if now.After(time.Unix(*c.ExpiresAt, 0).Add(a.Leeway)) {
+        return errors.New("token expired")
+    }
+    if c.NotBefore != nil && now.Add(a.Leeway).Before(time.Unix(*c.NotBefore, 0)) {
+        return errors.New("token not yet valid")
+    }
+    if a.Issuer != "" && c.Issuer != a.Issuer {
+        return errors.New("unexpected issuer")
+    }
+    if a.Audience != "" && !c.Audience.contains(a.Audience) {
+        return errors.New("unexpected audience")
+    }
+    return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+    b, err := base64.RawURLEncoding.DecodeString(seg)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(b, v)
+}
+
+// ParseRSAPublicKey reads an RSA public key from PEM, either as a PKIX
+// "PUBLIC KEY" or a PKCS #1 "RSA PUBLIC KEY" block.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return nil, errors.New("no PEM block found")
+    }
+
+    switch block.Type {
+    case "PUBLIC KEY":
+        key, err := x509.ParsePKIXPublicKey(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+        rsaKey, ok := key.(*rsa.PublicKey)
+        if !ok {
+            return nil, errors.New("public key is not an RSA key")
+        }
+        return rsaKey, nil
+    case "RSA PUBLIC KEY":
+        return x509.ParsePKCS1PublicKey(block.Bytes)
+    }
+    return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
@@ -0,0 +1,213 @@
+// internal/auth/jwt_test.go
+package auth
+
+import (
+    "crypto"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "testing"
+    "time"
+)
+
+var testSecret = []byte("test-secret")
+
+// testRSAKey is generated once; 2048 bits keeps the tests quick.
+var testRSAKey = func() *rsa.PrivateKey {
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        panic(err)
+    }
+    return key
+}()
+
+// signToken builds a compact JWT. alg "none" yields an empty signature;
+// key is an HMAC secret for HS256 and an *rsa.PrivateKey for RS256.
+func signToken(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+    t.Helper()
+    header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        t.Fatal(err)
+    }
+    input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+    var signature []byte
+    switch alg {
+    case "HS256":
+        mac := hmac.New(sha256.New, key.([]byte))
+        mac.Write([]byte(input))
+        signature = mac.Sum(nil)
+    case "RS256":
+        digest := sha256.Sum256([]byte(input))
+        if signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:]); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// claimsWith returns valid dispatcher claims expiring in an hour, with the
+// given overrides; a nil override removes the claim.
+func claimsWith(overrides map[string]interface{}) map[string]interface{} {
+    claims := map[string]interface{}{
+        "sub":      "console",
+        "role":     "dispatcher",
+        "operator": "acme",
+        "exp":      time.Now().Add(time.Hour).Unix(),
+    }
+    for k, v := range overrides {
+        if v == nil {
+            delete(claims, k)
+        } else {
+            claims[k] = v
+        }
+    }
+    return claims
+}
+
+func TestAuthenticateTokenAlgorithms(t *testing.T) {
+    publicDER, err := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+    hmacOnly := &Authenticator{HMACSecret: testSecret}
+    rsaOnly := &Authenticator{RSAPublicKey: &testRSAKey.PublicKey}
+    both := &Authenticator{HMACSecret: testSecret, RSAPublicKey: &testRSAKey.PublicKey}
+
+    tests := []struct {
+        name  string
+        a     *Authenticator
+        token string
+        ok    bool
+    }{
+        {"HS256", hmacOnly, signToken(t, "HS256", testSecret, claimsWith(nil)), true},
+        {"RS256", rsaOnly, signToken(t, "RS256", testRSAKey, claimsWith(nil)), true},
+        {"both keys HS256", both, signToken(t, "HS256", testSecret, claimsWith(nil)), true},
+        {"both keys RS256", both, signToken(t, "RS256", testRSAKey, claimsWith(nil)), true},
+        {"HS256 wrong secret", hmacOnly, signToken(t, "HS256", []byte("other"), claimsWith(nil)), false},
+        {"HS256 without secret", rsaOnly, signToken(t, "HS256", testSecret, claimsWith(nil)), false},
+        {"RS256 without key", hmacOnly, signToken(t, "RS256", testRSAKey, claimsWith(nil)), false},
+        // The public key is no HMAC secret, whatever the header claims
+        {"HS256 signed with public key PEM", rsaOnly, signToken(t, "HS256", publicPEM, claimsWith(nil)), false},
+        {"HS256 signed with public key DER", rsaOnly, signToken(t, "HS256", publicDER, claimsWith(nil)), false},
+        {"alg none", both, signToken(t, "none", nil, claimsWith(nil)), false},
+        {"alg None", both, signToken(t, "None", nil, claimsWith(nil)), false},
+        {"malformed", both, "not.a-token", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            p, err := tt.a.AuthenticateToken(tt.token)
+            if !tt.ok {
+                if !errors.Is(err, ErrInvalidCredentials) {
+                    t.Fatalf("got principal %v, error %v; want ErrInvalidCredentials", p, err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if p.Subject != "console" || p.Role != RoleDispatcher || p.Operator != "acme" {
+                t.Errorf("got principal %+v", p)
+            }
+        })
+    }
+}
+
+func TestAuthenticateTokenClaims(t *testing.T) {
+    now := time.Now()
+    tests := []struct {
+        name   string
+        a      Authenticator
+        claims map[string]interface{}
+        ok     bool
+    }{
+        {"valid", Authenticator{}, claimsWith(nil), true},
+        {"no exp", Authenticator{}, claimsWith(map[string]interface{}{"exp": nil}), false},
+        {"expired", Authenticator{}, claimsWith(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}), false},
+        {"expired within leeway", Authenticator{Leeway: 2 * time.Minute},
+            claimsWith(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}), true},
+        {"expired beyond leeway", Authenticator{Leeway: 30 * time.Second},
+            claimsWith(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}), false},
+        {"not yet valid", Authenticator{}, claimsWith(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}), false},
+        {"nbf within leeway", Authenticator{Leeway: 2 * time.Minute},
+            claimsWith(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}), true},
+        {"nbf beyond leeway", Authenticator{Leeway: 30 * time.Second},
+            claimsWith(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}), false},
+        {"nbf passed", Authenticator{}, claimsWith(map[string]interface{}{"nbf": now.Add(-time.Minute).Unix()}), true},
+        {"issuer matches", Authenticator{Issuer: "idp"}, claimsWith(map[string]interface{}{"iss": "idp"}), true},
+        {"issuer differs", Authenticator{Issuer: "idp"}, claimsWith(map[string]interface{}{"iss": "other"}), false},
+        {"issuer missing", Authenticator{Issuer: "idp"}, claimsWith(nil), false},
+        {"issuer not required", Authenticator{}, claimsWith(map[string]interface{}{"iss": "anyone"}), true},
+        {"audience string", Authenticator{Audience: "psm"}, claimsWith(map[string]interface{}{"aud": "psm"}), true},
+        {"audience array", Authenticator{Audience: "psm"},
+            claimsWith(map[string]interface{}{"aud": []string{"billing", "psm"}}), true},
+        {"audience differs", Authenticator{Audience: "psm"}, claimsWith(map[string]interface{}{"aud": []string{"billing"}}), false},
+        {"audience missing", Authenticator{Audience: "psm"}, claimsWith(nil), false},
+        {"audience malformed", Authenticator{}, claimsWith(map[string]interface{}{"aud": 42}), false},
+        {"unknown role", Authenticator{}, claimsWith(map[string]interface{}{"role": "root"}), false},
+        {"driver without taxi", Authenticator{}, claimsWith(map[string]interface{}{"role": "driver"}), false},
+        {"driver with taxi", Authenticator{}, claimsWith(map[string]interface{}{"role": "driver", "taxi_id": "T1"}), true},
+        {"dispatcher without operator", Authenticator{}, claimsWith(map[string]interface{}{"operator": nil}), false},
+        {"admin without operator", Authenticator{},
+            claimsWith(map[string]interface{}{"role": "admin", "operator": nil}), true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a := tt.a
+            a.HMACSecret = testSecret
+            _, err := a.AuthenticateToken(signToken(t, "HS256", testSecret, tt.claims))
+            if tt.ok && err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if !tt.ok && !errors.Is(err, ErrInvalidCredentials) {
+                t.Fatalf("got error %v, want ErrInvalidCredentials", err)
+            }
+        })
+    }
+}
+
+func TestParseRSAPublicKey(t *testing.T) {
+    pkix, err := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        name string
+        pem  []byte
+        ok   bool
+    }{
+        {"PKIX", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}), true},
+        {"PKCS1", pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&testRSAKey.PublicKey)}), true},
+        {"private key", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testRSAKey)}), false},
+        {"not PEM", []byte("ssh-rsa AAAA"), false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            key, err := ParseRSAPublicKey(tt.pem)
+            if !tt.ok {
+                if err == nil {
+                    t.Fatal("expected an error")
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if !key.Equal(&testRSAKey.PublicKey) {
+                t.Error("parsed a different key")
+            }
+        })
+    }
+}
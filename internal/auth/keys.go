@@ -0,0 +1,33 @@
+// internal/auth/keys.go
+package auth
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+)
+
+// apiKeyEntry is one element of an API key file.
+type apiKeyEntry struct {
+    Key string `json:"key"`
+    Principal
+}
+
+// LoadAPIKeys reads a JSON array of API keys and registers them, e.g.
+//
+//	[{"key": "s3cret", "sub": "ops-console", "role": "dispatcher", "operator": "acme"}]
+func (a *Authenticator) LoadAPIKeys(r io.Reader) error {
+    var entries []apiKeyEntry
+    dec := json.NewDecoder(r)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&entries); err != nil {
+        return fmt.Errorf("invalid API key file: %v", err)
+    }
+
+    for i, e := range entries {
+        if err := a.AddAPIKey(e.Key, e.Principal); err != nil {
+            return fmt.Errorf("API key %d: %v", i, err)
+        }
+    }
+    return nil
+}
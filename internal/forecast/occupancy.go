@@ -0,0 +1,240 @@
+// internal/forecast/occupancy.go
+package forecast
+
+import (
+    "math"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// Alphas are the smoothing factors tried on every forecast; the one with the
+// lowest backtest error is used.
+var Alphas = []float64{0.1, 0.2, 0.3, 0.5, 0.7, 0.9}
+
+// Damping is the share of the smoothed deviation from the seasonal average
+// carried over to each following interval, so forecasts further ahead lean
+// on the seasonal average alone.
+const Damping = 0.8
+
+// BacktestPoints is how many of the most recent intervals are replayed to
+// measure accuracy, a week of hours.
+const BacktestPoints = 7 * 24
+
+// z80 is the standard normal quantile bounding a two-sided 80% range.
+const z80 = 1.2816
+
+// Occupancy forecasts the average occupancy of a place for n intervals of
+// step starting at start, from its history in intervals of the same step.
+//
+// The forecast is the average occupancy seen at the same weekday and hour in
+// loc, falling back to the same hour of any day and then to every interval,
+// plus the recent deviation from that average, exponentially smoothed and
+// damped towards zero the further ahead it looks. Averages only ever include
+// intervals before the one predicted, so the backtest replays exactly what
+// the forecast would have said at the time.
+func Occupancy(history *models.OccupancyHistory, step time.Duration, start time.Time, n int, loc *time.Location) *models.OccupancyForecast {
+    points := history.Points
+    backtestFrom := len(points) - BacktestPoints
+    if backtestFrom < 0 {
+        backtestFrom = 0
+    }
+
+    var best *replay
+    for _, alpha := range Alphas {
+        r := run(points, step, loc, alpha, backtestFrom)
+        if best == nil || (len(r.errors) > 0 && r.mae() < best.mae()) {
+            best = r
+        }
+    }
+
+    forecast := &models.OccupancyForecast{
+        PlaceID:       history.PlaceID,
+        Step:          history.Step,
+        Timezone:      loc.String(),
+        HistoryFrom:   history.From,
+        HistoryTo:     history.To,
+        HistoryPoints: len(points),
+        Alpha:         best.model.alpha,
+        Damping:       Damping,
+        Points:        make([]models.ForecastPoint, 0, n),
+        Backtest:      best.accuracy(),
+    }
+
+    spread := z80 * forecast.Backtest.RMSE
+    for i := 0; i < n; i++ {
+        t := start.Add(time.Duration(i) * step)
+        expected, _ := best.model.predict(t)
+        forecast.Points = append(forecast.Points, models.ForecastPoint{
+            Start:    t.UTC(),
+            Expected: round(expected),
+            Low:      round(math.Max(0, expected-spread)),
+            High:     round(expected + spread),
+        })
+    }
+    return forecast
+}
+
+// mean is a running average.
+type mean struct {
+    sum float64
+    n   int
+}
+
+func (m *mean) add(v float64) {
+    m.sum += v
+    m.n++
+}
+
+func (m *mean) value() (float64, bool) {
+    if m == nil || m.n == 0 {
+        return 0, false
+    }
+    return m.sum / float64(m.n), true
+}
+
+// model is the state of the forecaster after the intervals seen so far.
+type model struct {
+    loc   *time.Location
+    step  time.Duration
+    alpha float64
+
+    slots map[int]*mean // by weekday and hour
+    hours map[int]*mean // by hour
+    all   mean
+
+    level float64   // smoothed deviation from the seasonal average
+    last  time.Time // start of the last interval seen
+}
+
+// seasonal returns the average occupancy of the intervals seen at the same
+// weekday and hour as t, or of the closest fallback that has any.
+func (m *model) seasonal(t time.Time) (float64, bool) {
+    local := t.In(m.loc)
+    if v, ok := m.slots[int(local.Weekday())*24+local.Hour()].value(); ok {
+        return v, true
+    }
+    if v, ok := m.hours[local.Hour()].value(); ok {
+        return v, true
+    }
+    return m.all.value()
+}
+
+// levelAt returns the smoothed deviation carried forward to t, damped once
+// for every interval between the last one seen and t.
+func (m *model) levelAt(t time.Time) float64 {
+    if m.last.IsZero() {
+        return 0
+    }
+    gap := float64(t.Sub(m.last)/m.step) - 1
+    return m.level * math.Pow(Damping, math.Max(0, gap))
+}
+
+// predict returns the expected occupancy at t, or false before any interval
+// has been seen.
+func (m *model) predict(t time.Time) (float64, bool) {
+    base, ok := m.seasonal(t)
+    if !ok {
+        return 0, false
+    }
+    return math.Max(0, base+m.levelAt(t)), true
+}
+
+// observe learns the occupancy of the interval starting at t.
+func (m *model) observe(t time.Time, occupancy float64) {
+    if base, ok := m.seasonal(t); ok {
+        m.level = m.alpha*(occupancy-base) + (1-m.alpha)*m.levelAt(t)
+    }
+    m.last = t
+
+    local := t.In(m.loc)
+    slot, hour := int(local.Weekday())*24+local.Hour(), local.Hour()
+    if m.slots[slot] == nil {
+        m.slots[slot] = &mean{}
+    }
+    if m.hours[hour] == nil {
+        m.hours[hour] = &mean{}
+    }
+    m.slots[slot].add(occupancy)
+    m.hours[hour].add(occupancy)
+    m.all.add(occupancy)
+}
+
+// replay is a model run over a history together with the errors of the
+// predictions it made for the backtested intervals.
+type replay struct {
+    model    *model
+    errors   []float64 // predicted minus actual
+    baseline []float64 // seasonal average minus actual
+    actuals  []float64
+}
+
+// run feeds the history to a new model in order, predicting every interval
+// from backtestFrom on before learning it.
+func run(points []models.OccupancyPoint, step time.Duration, loc *time.Location, alpha float64, backtestFrom int) *replay {
+    r := &replay{model: &model{loc: loc, step: step, alpha: alpha, slots: map[int]*mean{}, hours: map[int]*mean{}}}
+    for i, point := range points {
+        if i >= backtestFrom {
+            if predicted, ok := r.model.predict(point.Start); ok {
+                base, _ := r.model.seasonal(point.Start)
+                r.errors = append(r.errors, predicted-point.Avg)
+                r.baseline = append(r.baseline, base-point.Avg)
+                r.actuals = append(r.actuals, point.Avg)
+            }
+        }
+        r.model.observe(point.Start, point.Avg)
+    }
+    return r
+}
+
+// mae returns the mean absolute error of the backtest.
+func (r *replay) mae() float64 {
+    return meanAbs(r.errors)
+}
+
+// accuracy summarises the backtest errors.
+func (r *replay) accuracy() models.ForecastAccuracy {
+    accuracy := models.ForecastAccuracy{Points: len(r.errors)}
+    if len(r.errors) == 0 {
+        return accuracy
+    }
+
+    var squares, percents float64
+    occupied := 0
+    for i, e := range r.errors {
+        squares += e * e
+        if r.actuals[i] > 0 {
+            percents += math.Abs(e) / r.actuals[i] * 100
+            occupied++
+        }
+    }
+
+    accuracy.MAE = round(r.mae())
+    accuracy.RMSE = round(math.Sqrt(squares / float64(len(r.errors))))
+    accuracy.BaselineMAE = round(meanAbs(r.baseline))
+    if occupied > 0 {
+        mape := round(percents / float64(occupied))
+        accuracy.MAPE = &mape
+    }
+    if baseline := meanAbs(r.baseline); baseline > 0 {
+        skill := round(1 - r.mae()/baseline)
+        accuracy.Skill = &skill
+    }
+    return accuracy
+}
+
+func meanAbs(values []float64) float64 {
+    if len(values) == 0 {
+        return 0
+    }
+    var sum float64
+    for _, v := range values {
+        sum += math.Abs(v)
+    }
+    return sum / float64(len(values))
+}
+
+// round rounds to two decimals.
+func round(v float64) float64 {
+    return math.Round(v*100) / 100
+}
@@ -0,0 +1,231 @@
+// internal/forecast/occupancy_test.go
+package forecast
+
+import (
+    "math"
+    "math/rand"
+    "testing"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// seriesStart is a Monday midnight.
+var seriesStart = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// seasonalValue is a synthetic weekly pattern: busy in the day, quiet at
+// night and busier still at weekends.
+func seasonalValue(t time.Time) float64 {
+    v := 2.0
+    if h := t.Hour(); h >= 7 && h < 20 {
+        v += float64(h - 6)
+    }
+    if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+        v += 5
+    }
+    return v
+}
+
+// hourlySeries returns weeks of hourly points, with value computing the
+// occupancy of the i-th point.
+func hourlySeries(weeks int, value func(i int, t time.Time) float64) *models.OccupancyHistory {
+    n := weeks * 7 * 24
+    history := &models.OccupancyHistory{PlaceID: 1, Step: "hour", From: seriesStart, To: seriesStart.Add(time.Duration(n) * time.Hour)}
+    for i := 0; i < n; i++ {
+        t := seriesStart.Add(time.Duration(i) * time.Hour)
+        history.Points = append(history.Points, models.OccupancyPoint{Start: t, Samples: 60, Avg: value(i, t)})
+    }
+    return history
+}
+
+func TestOccupancyLearnsSeasonalPattern(t *testing.T) {
+    history := hourlySeries(4, func(_ int, t time.Time) float64 { return seasonalValue(t) })
+    forecast := Occupancy(history, time.Hour, history.To, 48, time.UTC)
+
+    if forecast.Backtest.Points != BacktestPoints {
+        t.Errorf("backtested %d points, want %d", forecast.Backtest.Points, BacktestPoints)
+    }
+    if forecast.Backtest.MAE != 0 || forecast.Backtest.RMSE != 0 {
+        t.Errorf("a repeating pattern should be predicted exactly, got %+v", forecast.Backtest)
+    }
+    if forecast.Backtest.Skill != nil {
+        t.Errorf("skill is undefined when the baseline is perfect, got %v", *forecast.Backtest.Skill)
+    }
+    for _, p := range forecast.Points {
+        if want := seasonalValue(p.Start); p.Expected != want || p.Low != want || p.High != want {
+            t.Fatalf("at %v got %+v, want %v", p.Start, p, want)
+        }
+    }
+}
+
+// A lasting shift is best followed by the most responsive smoothing.
+func TestOccupancyPicksHighAlphaForLevelShift(t *testing.T) {
+    history := hourlySeries(4, func(i int, t time.Time) float64 {
+        if i >= 3*7*24 {
+            return seasonalValue(t) + 10
+        }
+        return seasonalValue(t)
+    })
+    forecast := Occupancy(history, time.Hour, history.To, 1, time.UTC)
+
+    if want := Alphas[len(Alphas)-1]; forecast.Alpha != want {
+        t.Errorf("alpha = %v, want %v", forecast.Alpha, want)
+    }
+    checkSkill(t, forecast.Backtest, func(skill float64) bool { return skill > 0.5 })
+}
+
+// Noise around the pattern is best ignored by the least responsive smoothing.
+func TestOccupancyPicksLowAlphaForNoise(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    history := hourlySeries(8, func(_ int, t time.Time) float64 {
+        return math.Max(0, seasonalValue(t)+rng.NormFloat64()*2)
+    })
+    forecast := Occupancy(history, time.Hour, history.To, 1, time.UTC)
+
+    if want := Alphas[0]; forecast.Alpha != want {
+        t.Errorf("alpha = %v, want %v", forecast.Alpha, want)
+    }
+}
+
+func TestOccupancyDampsDeviationAhead(t *testing.T) {
+    history := hourlySeries(4, func(i int, t time.Time) float64 {
+        if i >= 3*7*24 {
+            return seasonalValue(t) + 100
+        }
+        return seasonalValue(t)
+    })
+    forecast := Occupancy(history, time.Hour, history.To, 6, time.UTC)
+
+    // The seasonal average of each slot is taken over the four weeks seen
+    first := forecast.Points[0]
+    base := func(t time.Time) float64 { return seasonalValue(t) + 100.0/4 }
+    deviation := first.Expected - base(first.Start)
+    if deviation <= 0 {
+        t.Fatalf("expected a positive deviation, got %v", deviation)
+    }
+    for i, p := range forecast.Points {
+        want := base(p.Start) + deviation*math.Pow(Damping, float64(i))
+        if math.Abs(p.Expected-want) > 0.02 {
+            t.Errorf("point %d expected %v, want %v", i, p.Expected, want)
+        }
+    }
+}
+
+func TestModelDampsAcrossGaps(t *testing.T) {
+    m := &model{loc: time.UTC, step: time.Hour, alpha: 0.5, slots: map[int]*mean{}, hours: map[int]*mean{}}
+    t0 := seriesStart
+    m.observe(t0, 10)
+    m.observe(t0.Add(24*time.Hour), 20)
+    if m.level != 5 {
+        t.Fatalf("level = %v, want 5", m.level)
+    }
+
+    tests := []struct {
+        after time.Duration
+        want  float64
+    }{
+        {0, 5},
+        {time.Hour, 5},
+        {2 * time.Hour, 5 * Damping},
+        {4 * time.Hour, 5 * math.Pow(Damping, 3)},
+    }
+    last := m.last
+    for _, tt := range tests {
+        if got := m.levelAt(last.Add(tt.after)); math.Abs(got-tt.want) > 1e-9 {
+            t.Errorf("level %v after the last interval = %v, want %v", tt.after, got, tt.want)
+        }
+    }
+
+    // An interval after a gap starts from the damped level. No interval was
+    // seen at its hour, so its seasonal average is that of all of them
+    m.observe(last.Add(3*time.Hour), 0)
+    want := 0.5*(0-15) + 0.5*5*Damping*Damping
+    if math.Abs(m.level-want) > 1e-9 {
+        t.Errorf("level after a gap = %v, want %v", m.level, want)
+    }
+}
+
+func TestOccupancyWithoutHistory(t *testing.T) {
+    history := &models.OccupancyHistory{PlaceID: 1, Step: "hour"}
+    forecast := Occupancy(history, time.Hour, seriesStart, 3, time.UTC)
+
+    if len(forecast.Points) != 3 {
+        t.Fatalf("got %d points, want 3", len(forecast.Points))
+    }
+    for _, p := range forecast.Points {
+        if p.Expected != 0 || p.Low != 0 || p.High != 0 {
+            t.Errorf("got %+v, want zero", p)
+        }
+    }
+    if forecast.Backtest.Points != 0 || forecast.Backtest.MAPE != nil || forecast.Backtest.Skill != nil {
+        t.Errorf("got backtest %+v, want none", forecast.Backtest)
+    }
+}
+
+func TestAccuracy(t *testing.T) {
+    tests := []struct {
+        name     string
+        replay   replay
+        mae      float64
+        rmse     float64
+        mape     *float64
+        baseline float64
+        skill    *float64
+    }{
+        {
+            name:   "zero actuals skipped by MAPE",
+            replay: replay{errors: []float64{3, 1, -2}, actuals: []float64{0, 10, 20}, baseline: []float64{4, 2, -4}},
+            mae: 2, rmse: 2.16, mape: ptr(10), baseline: 3.33, skill: ptr(0.4),
+        },
+        {
+            name:   "all actuals zero",
+            replay: replay{errors: []float64{1, -1}, actuals: []float64{0, 0}, baseline: []float64{1, 1}},
+            mae: 1, rmse: 1, mape: nil, baseline: 1, skill: ptr(0),
+        },
+        {
+            name:   "worse than baseline",
+            replay: replay{errors: []float64{4, -4}, actuals: []float64{8, 8}, baseline: []float64{2, -2}},
+            mae: 4, rmse: 4, mape: ptr(50), baseline: 2, skill: ptr(-1),
+        },
+        {
+            name:   "perfect baseline",
+            replay: replay{errors: []float64{0, 0}, actuals: []float64{5, 5}, baseline: []float64{0, 0}},
+            mae: 0, rmse: 0, mape: ptr(0), baseline: 0, skill: nil,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            a := tt.replay.accuracy()
+            if a.Points != len(tt.replay.errors) || a.MAE != tt.mae || a.RMSE != tt.rmse || a.BaselineMAE != tt.baseline {
+                t.Errorf("got %+v, want mae %v rmse %v baseline %v", a, tt.mae, tt.rmse, tt.baseline)
+            }
+            checkOptional(t, "mape", a.MAPE, tt.mape)
+            checkOptional(t, "skill", a.Skill, tt.skill)
+        })
+    }
+}
+
+func ptr(v float64) *float64 {
+    return &v
+}
+
+func checkOptional(t *testing.T, name string, got, want *float64) {
+    t.Helper()
+    switch {
+    case got == nil && want == nil:
+    case got == nil || want == nil:
+        t.Errorf("%s = %v, want %v", name, got, want)
+    case *got != *want:
+        t.Errorf("%s = %v, want %v", name, *got, *want)
+    }
+}
+
+func checkSkill(t *testing.T, a models.ForecastAccuracy, ok func(float64) bool) {
+    t.Helper()
+    if a.Skill == nil {
+        t.Fatalf("no skill in %+v", a)
+    }
+    if !ok(*a.Skill) {
+        t.Errorf("skill = %v (mae %v, baseline %v)", *a.Skill, a.MAE, a.BaselineMAE)
+    }
+}
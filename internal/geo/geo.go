@@ -0,0 +1,287 @@
+// internal/geo/geo.go
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth in kilometres.
+const EarthRadiusKm = 6371.0088
+
+// HaversineKm returns the great-circle distance between two coordinates in kilometres.
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+    dLat := toRadians(lat2 - lat1)
+    dLon := toRadians(lon2 - lon1)
+
+    a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+        math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+    return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// BBox is an axis-aligned bounding box in degrees.
+type BBox struct {
+    MinLon float64
+    MinLat float64
+    MaxLon float64
+    MaxLat float64
+}
+
+// BBoxAround returns a box that contains every point within radiusKm of the
+// given coordinate. The box is clamped to valid coordinates and does not wrap
+// around the antimeridian, so it may be larger than needed but never smaller.
+func BBoxAround(lat, lon, radiusKm float64) BBox {
+    dLat := radiusKm / EarthRadiusKm * 180 / math.Pi
+
+    dLon := 180.0
+    if cosLat := math.Cos(toRadians(lat)); cosLat > 1e-9 {
+        dLon = math.Min(180, dLat/cosLat)
+    }
+
+    return BBox{
+        MinLon: math.Max(-180, lon-dLon),
+        MinLat: math.Max(-90, lat-dLat),
+        MaxLon: math.Min(180, lon+dLon),
+        MaxLat: math.Min(90, lat+dLat),
+    }
+}
+
+// Contains reports whether the coordinate lies inside the box.
+func (b BBox) Contains(lon, lat float64) bool {
+    return lon >= b.MinLon && lon <= b.MaxLon && lat >= b.MinLat && lat <= b.MaxLat
+}
+
+func toRadians(deg float64) float64 {
+    return deg * math.Pi / 180
+}
+
+// LatLon is a single geographic coordinate.
+type LatLon struct {
+    Lat float64
+    Lon float64
+}
+
+// Point represents a geographic coordinate.
+type Point struct {
+    X float64 // longitude
+    Y float64 // latitude
+}
+
+// Intersects reports whether two boxes overlap.
+func (b BBox) Intersects(o BBox) bool {
+    return b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon && b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat
+}
+
+// BoundsOf returns the bounding box of a polygon ring.
+func BoundsOf(polygon []Point) BBox {
+    box := BBox{MinLon: math.Inf(1), MinLat: math.Inf(1), MaxLon: math.Inf(-1), MaxLat: math.Inf(-1)}
+    for _, p := range polygon {
+        box.MinLon = math.Min(box.MinLon, p.X)
+        box.MinLat = math.Min(box.MinLat, p.Y)
+        box.MaxLon = math.Max(box.MaxLon, p.X)
+        box.MaxLat = math.Max(box.MaxLat, p.Y)
+    }
+    return box
+}
+
+// PointInPolygon checks if a point lies within a polygon using the ray-casting algorithm.
+func PointInPolygon(longitude, latitude float64, polygon []Point) bool {
+    intersects := false
+    j := len(polygon) - 1
+    for i := 0; i < len(polygon); i++ {
+        xi, yi := polygon[i].X, polygon[i].Y
+        xj, yj := polygon[j].X, polygon[j].Y
+
+        intersect := ((yi > latitude) != (yj > latitude)) &&
+            (longitude < (xj-xi)*(latitude-yi)/(yj-yi)+xi)
+        if intersect {
+            intersects = !intersects
+        }
+        j = i
+    }
+    return intersects
+}
+
+// PointInAnyPolygon reports whether a point lies within any of several
+// polygon rings, such as the parts of a MultiPolygon.
+func PointInAnyPolygon(longitude, latitude float64, polygons [][]Point) bool {
+    for _, polygon := range polygons {
+        if PointInPolygon(longitude, latitude, polygon) {
+            return true
+        }
+    }
+    return false
+}
+
+// PolygonIntersectsBBox reports whether a polygon ring and a box share any area.
+func PolygonIntersectsBBox(polygon []Point, box BBox) bool {
+    if len(polygon) == 0 || !BoundsOf(polygon).Intersects(box) {
+        return false
+    }
+
+    for _, p := range polygon {
+        if box.Contains(p.X, p.Y) {
+            return true
+        }
+    }
+
+    corners := []Point{
+        {box.MinLon, box.MinLat},
+        {box.MaxLon, box.MinLat},
+        {box.MaxLon, box.MaxLat},
+        {box.MinLon, box.MaxLat},
+    }
+    for _, c := range corners {
+        if PointInPolygon(c.X, c.Y, polygon) {
+            return true
+        }
+    }
+
+    for i := range polygon {
+        a, b := polygon[i], polygon[(i+1)%len(polygon)]
+        for k := range corners {
+            if segmentsIntersect(a, b, corners[k], corners[(k+1)%len(corners)]) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// DistanceToPolygonKm returns the distance from a coordinate to the nearest
+// edge of a polygon ring, or 0 when the coordinate lies inside it. Edges are
+// measured on a local equirectangular projection, which is accurate at the
+// scale of a city.
+func DistanceToPolygonKm(lat, lon float64, polygon []Point) float64 {
+    if len(polygon) == 0 {
+        return math.Inf(1)
+    }
+    if PointInPolygon(lon, lat, polygon) {
+        return 0
+    }
+
+    kx := EarthRadiusKm * toRadians(1) * math.Cos(toRadians(lat))
+    ky := EarthRadiusKm * toRadians(1)
+    project := func(p Point) (float64, float64) {
+        return (p.X - lon) * kx, (p.Y - lat) * ky
+    }
+
+    best := math.Inf(1)
+    for i := range polygon {
+        ax, ay := project(polygon[i])
+        bx, by := project(polygon[(i+1)%len(polygon)])
+        best = math.Min(best, distanceToSegment(ax, ay, bx, by))
+    }
+    return best
+}
+
+// distanceToSegment returns the distance from the origin to segment AB.
+func distanceToSegment(ax, ay, bx, by float64) float64 {
+    dx, dy := bx-ax, by-ay
+    t := 0.0
+    if lenSq := dx*dx + dy*dy; lenSq > 0 {
+        t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lenSq))
+    }
+    return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// segmentsIntersect reports whether segments PQ and RS touch or cross.
+func segmentsIntersect(p, q, r, s Point) bool {
+    d1 := orientation(r, s, p)
+    d2 := orientation(r, s, q)
+    d3 := orientation(p, q, r)
+    d4 := orientation(p, q, s)
+
+    if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+        return true
+    }
+    return (d1 == 0 && onSegment(r, s, p)) || (d2 == 0 && onSegment(r, s, q)) ||
+        (d3 == 0 && onSegment(p, q, r)) || (d4 == 0 && onSegment(p, q, s))
+}
+
+func orientation(a, b, c Point) float64 {
+    return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+func onSegment(a, b, p Point) bool {
+    return math.Min(a.X, b.X) <= p.X && p.X <= math.Max(a.X, b.X) &&
+        math.Min(a.Y, b.Y) <= p.Y && p.Y <= math.Max(a.Y, b.Y)
+}
+
+// RingArea returns the absolute planar area of a ring in square degrees.
+func RingArea(ring []Point) float64 {
+    area := 0.0
+    for i := range ring {
+        j := (i + 1) % len(ring)
+        area += ring[i].X*ring[j].Y - ring[j].X*ring[i].Y
+    }
+    return math.Abs(area) / 2
+}
+
+// Centroid returns the centre of mass of a ring, or the average of its
+// vertices when the ring has no area.
+func Centroid(ring []Point) Point {
+    var area, cx, cy float64
+    for i := range ring {
+        j := (i + 1) % len(ring)
+        cross := ring[i].X*ring[j].Y - ring[j].X*ring[i].Y
+        area += cross
+        cx += (ring[i].X + ring[j].X) * cross
+        cy += (ring[i].Y + ring[j].Y) * cross
+    }
+    if area != 0 {
+        return Point{X: cx / (3 * area), Y: cy / (3 * area)}
+    }
+
+    var c Point
+    for _, p := range ring {
+        c.X += p.X / float64(len(ring))
+        c.Y += p.Y / float64(len(ring))
+    }
+    return c
+}
+
+// CentroidOf returns the centre of mass of several rings taken together,
+// weighting each by its area, or the average of their centroids when none
+// has any.
+func CentroidOf(rings [][]Point) Point {
+    var total float64
+    var c Point
+    for _, ring := range rings {
+        area := RingArea(ring)
+        centre := Centroid(ring)
+        total += area
+        c.X += centre.X * area
+        c.Y += centre.Y * area
+    }
+    if total != 0 {
+        return Point{X: c.X / total, Y: c.Y / total}
+    }
+
+    c = Point{}
+    for _, ring := range rings {
+        centre := Centroid(ring)
+        c.X += centre.X / float64(len(rings))
+        c.Y += centre.Y / float64(len(rings))
+    }
+    return c
+}
+
+// RingSelfIntersects reports whether any two non-adjacent edges of a closed
+// ring touch or cross.
+func RingSelfIntersects(ring []Point) bool {
+    // A closed ring repeats its first point; drop it so edges wrap cleanly.
+    if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+        ring = ring[:n-1]
+    }
+
+    n := len(ring)
+    for i := 0; i < n; i++ {
+        for j := i + 1; j < n; j++ {
+            if j == i+1 || (i == 0 && j == n-1) {
+                continue
+            }
+            if segmentsIntersect(ring[i], ring[(i+1)%n], ring[j], ring[(j+1)%n]) {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -0,0 +1,79 @@
+// internal/geo/geohash.go
+package geo
+
+// geohashAlphabet is the base32 alphabet of geohashes.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxGeohashPrecision is the longest geohash supported, about 5 metres.
+const MaxGeohashPrecision = 9
+
+// Geohash returns the geohash of a coordinate with the given number of
+// characters. Each character narrows the cell down by five bits, alternating
+// between longitude and latitude.
+func Geohash(lat, lon float64, precision int) string {
+    box := BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}
+    hash := make([]byte, 0, precision)
+    even := true
+    bits, ch := 0, 0
+    for len(hash) < precision {
+        ch <<= 1
+        if even {
+            if mid := (box.MinLon + box.MaxLon) / 2; lon >= mid {
+                ch |= 1
+                box.MinLon = mid
+            } else {
+                box.MaxLon = mid
+            }
+        } else {
+            if mid := (box.MinLat + box.MaxLat) / 2; lat >= mid {
+                ch |= 1
+                box.MinLat = mid
+            } else {
+                box.MaxLat = mid
+            }
+        }
+        even = !even
+
+        if bits++; bits == 5 {
+            hash = append(hash, geohashAlphabet[ch])
+            bits, ch = 0, 0
+        }
+    }
+    return string(hash)
+}
+
+// GeohashBounds returns the cell a geohash stands for. Characters outside the
+// alphabet are treated as zero.
+func GeohashBounds(hash string) BBox {
+    box := BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}
+    even := true
+    for i := 0; i < len(hash); i++ {
+        ch := 0
+        for j := 0; j < len(geohashAlphabet); j++ {
+            if geohashAlphabet[j] == hash[i] {
+                ch = j
+                break
+            }
+        }
+        for bit := 4; bit >= 0; bit-- {
+            set := ch>>bit&1 == 1
+            if even {
+                mid := (box.MinLon + box.MaxLon) / 2
+                if set {
+                    box.MinLon = mid
+                } else {
+                    box.MaxLon = mid
+                }
+            } else {
+                mid := (box.MinLat + box.MaxLat) / 2
+                if set {
+                    box.MinLat = mid
+                } else {
+                    box.MaxLat = mid
+                }
+            }
+            even = !even
+        }
+    }
+    return box
+}
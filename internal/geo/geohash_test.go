@@ -0,0 +1,90 @@
+// internal/geo/geohash_test.go
+package geo
+
+import (
+    "math"
+    "math/rand"
+    "testing"
+)
+
+func TestGeohashKnownVectors(t *testing.T) {
+    tests := []struct {
+        lat, lon  float64
+        precision int
+        want      string
+    }{
+        {57.64911, 10.40744, 9, "u4pruydqq"},
+        {42.6, -5.6, 5, "ezs42"},
+        {-25.382708, -49.265506, 9, "6gkzwgjzn"},
+        {0, 0, 5, "s0000"},
+        {-0.000001, -0.000001, 5, "7zzzz"},
+        {-90, -180, 4, "0000"},
+        {90, 180, 4, "zzzz"},
+        {51.5074, -0.1278, 1, "g"},
+        {51.5074, -0.1278, 0, ""},
+    }
+    for _, tt := range tests {
+        if got := Geohash(tt.lat, tt.lon, tt.precision); got != tt.want {
+            t.Errorf("Geohash(%v, %v, %d) = %q, want %q", tt.lat, tt.lon, tt.precision, got, tt.want)
+        }
+    }
+}
+
+func TestGeohashBoundsKnownVectors(t *testing.T) {
+    tests := []struct {
+        hash string
+        want BBox
+    }{
+        {"", BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}},
+        {"s", BBox{MinLon: 0, MinLat: 0, MaxLon: 45, MaxLat: 45}},
+        {"7", BBox{MinLon: -45, MinLat: -45, MaxLon: 0, MaxLat: 0}},
+        {"ezs42", BBox{MinLon: -5.625, MinLat: 42.5830078125, MaxLon: -5.5810546875, MaxLat: 42.626953125}},
+    }
+    for _, tt := range tests {
+        if got := GeohashBounds(tt.hash); got != tt.want {
+            t.Errorf("GeohashBounds(%q) = %+v, want %+v", tt.hash, got, tt.want)
+        }
+    }
+}
+
+// Every point lies inside the cell of its own geohash, and the centre of that
+// cell hashes back to the same geohash.
+func TestGeohashRoundTrip(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    points := [][2]float64{{0, 0}, {-90, -180}, {90, 180}, {-6.2088, 106.8456}, {89.9999, -179.9999}, {-89.9999, 179.9999}}
+    for i := 0; i < 500; i++ {
+        points = append(points, [2]float64{rng.Float64()*180 - 90, rng.Float64()*360 - 180})
+    }
+
+    for _, p := range points {
+        lat, lon := p[0], p[1]
+        for precision := 1; precision <= MaxGeohashPrecision; precision++ {
+            hash := Geohash(lat, lon, precision)
+            if len(hash) != precision {
+                t.Fatalf("Geohash(%v, %v, %d) = %q has the wrong length", lat, lon, precision, hash)
+            }
+            box := GeohashBounds(hash)
+            if !box.Contains(lon, lat) {
+                t.Fatalf("bounds %+v of %q do not contain %v, %v", box, hash, lat, lon)
+            }
+            centre := Geohash((box.MinLat+box.MaxLat)/2, (box.MinLon+box.MaxLon)/2, precision)
+            if centre != hash {
+                t.Fatalf("centre of %q hashes to %q", hash, centre)
+            }
+            if prefix := Geohash(lat, lon, precision-1); hash[:precision-1] != prefix {
+                t.Fatalf("%q does not extend %q", hash, prefix)
+            }
+        }
+    }
+}
+
+// A precision 9 cell is about 4.8 by 4.8 metres at the equator.
+func TestGeohashCellSize(t *testing.T) {
+    box := GeohashBounds(Geohash(0.00001, 0.00001, MaxGeohashPrecision))
+    kmPerDegree := EarthRadiusKm * math.Pi / 180
+    width := (box.MaxLon - box.MinLon) * kmPerDegree * 1000
+    height := (box.MaxLat - box.MinLat) * kmPerDegree * 1000
+    if math.Abs(width-4.77) > 0.05 || math.Abs(height-4.77) > 0.05 {
+        t.Errorf("cell is %.2f by %.2f metres, want about 4.77", width, height)
+    }
+}
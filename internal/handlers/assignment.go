@@ -0,0 +1,196 @@
+// internal/handlers/assignment.go
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/gorilla/mux"
+)
+
+// AssignmentHandler handles HTTP requests for dispatch assignments.
+type AssignmentHandler struct {
+    Repo *repository.AssignmentRepository
+}
+
+// repo returns the assignment repository scoped to the calling operator.
+func (ah *AssignmentHandler) repo(r *http.Request) *repository.AssignmentRepository {
+    return ah.Repo.ForOperator(operatorOf(r))
+}
+
+// assignmentID parses the assignment ID in the path, writing a problem when
+// it is invalid.
+func assignmentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+    id, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil || id <= 0 {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid assignment ID")
+        return 0, false
+    }
+    return id, true
+}
+
+// RequestAssignment offers a booking to the taxi at the head of a place's
+// queue. The driver has timeout_seconds (60 by default) to accept.
+func (ah *AssignmentHandler) RequestAssignment(w http.ResponseWriter, r *http.Request) {
+    var req models.AssignmentRequest
+    if !decodeJSON(w, r, &req) {
+        return
+    }
+
+    assignment, err := ah.repo(r).Request(req, actorOf(r))
+    if errors.Is(err, repository.ErrNotFound) {
+        writeProblem(w, r, http.StatusNotFound, CodeQueueEmpty, "No taxi is waiting at the place")
+        return
+    } else if err != nil {
+        writeError(w, r, err, "Failed to request assignment")
+        return
+    }
+
+    writeJSON(w, http.StatusCreated, assignment)
+}
+
+// GetAssignments retrieves a page of assignments, optionally filtered by
+// status=, place_id=, taxi_id= and booking_ref=.
+func (ah *AssignmentHandler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    opts, err := parseListOptions(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    filter := repository.AssignmentFilter{Status: params.Get("status"), TaxiID: params.Get("taxi_id"), BookingRef: params.Get("booking_ref")}
+    switch filter.Status {
+    case "", models.MappingOffered, models.MappingAccepted, models.MappingPickedUp, models.MappingCancelled:
+    default:
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid status, expected offered, accepted, picked_up or cancelled")
+        return
+    }
+    if v := params.Get("place_id"); v != "" {
+        placeID, err := strconv.Atoi(v)
+        if err != nil || placeID <= 0 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place_id")
+            return
+        }
+        filter.PlaceID = placeID
+    }
+
+    assignments, next, err := ah.repo(r).GetAssignments(filter, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query assignments")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: assignments, NextCursor: next})
+}
+
+// GetAssignment retrieves a single assignment with its status history.
+func (ah *AssignmentHandler) GetAssignment(w http.ResponseWriter, r *http.Request) {
+    id, ok := assignmentID(w, r)
+    if !ok {
+        return
+    }
+
+    assignment, err := ah.repo(r).GetAssignment(id)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve assignment")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, assignment)
+}
+
+// LoadAssignment returns an assignment for the audit log.
+func (ah *AssignmentHandler) LoadAssignment(r *http.Request, id string) (interface{}, error) {
+    assignmentID, err := strconv.Atoi(id)
+    if err != nil {
+        return nil, err
+    }
+    return ah.repo(r).GetAssignment(assignmentID)
+}
+
+// forDriver loads the assignment in the path and rejects drivers answering
+// for another taxi.
+func (ah *AssignmentHandler) forDriver(w http.ResponseWriter, r *http.Request) (int, bool) {
+    id, ok := assignmentID(w, r)
+    if !ok {
+        return 0, false
+    }
+
+    assignment, err := ah.repo(r).GetAssignment(id)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve assignment")
+        return 0, false
+    }
+    return id, checkTaxiAccess(w, r, assignment.TaxiID)
+}
+
+// AcceptAssignment accepts an offer before it expires.
+func (ah *AssignmentHandler) AcceptAssignment(w http.ResponseWriter, r *http.Request) {
+    id, ok := ah.forDriver(w, r)
+    if !ok {
+        return
+    }
+
+    assignment, err := ah.repo(r).Accept(id, actorOf(r))
+    if err != nil {
+        writeError(w, r, err, "Failed to accept assignment")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, assignment)
+}
+
+// RejectAssignment turns an offer down. The booking goes to the next taxi
+// in the queue, which is returned as reoffered.
+func (ah *AssignmentHandler) RejectAssignment(w http.ResponseWriter, r *http.Request) {
+    id, ok := ah.forDriver(w, r)
+    if !ok {
+        return
+    }
+
+    outcome, err := ah.repo(r).Reject(id, actorOf(r))
+    if err != nil {
+        writeError(w, r, err, "Failed to reject assignment")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, outcome)
+}
+
+// PickUpAssignment records that the passenger of an accepted assignment was
+// picked up.
+func (ah *AssignmentHandler) PickUpAssignment(w http.ResponseWriter, r *http.Request) {
+    id, ok := ah.forDriver(w, r)
+    if !ok {
+        return
+    }
+
+    assignment, err := ah.repo(r).PickUp(id, actorOf(r))
+    if err != nil {
+        writeError(w, r, err, "Failed to record pickup")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, assignment)
+}
+
+// CancelAssignment withdraws an offered or accepted assignment.
+func (ah *AssignmentHandler) CancelAssignment(w http.ResponseWriter, r *http.Request) {
+    id, ok := assignmentID(w, r)
+    if !ok {
+        return
+    }
+
+    outcome, err := ah.repo(r).Cancel(id, actorOf(r))
+    if err != nil {
+        writeError(w, r, err, "Failed to cancel assignment")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, outcome)
+}
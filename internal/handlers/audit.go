@@ -0,0 +1,208 @@
+// internal/handlers/audit.go
+package handlers
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/gorilla/mux"
+)
+
+// AuditSpec describes how changes to one kind of entity are audited.
+type AuditSpec struct {
+    Entity string
+    // IDField names the JSON field holding the entity ID in request and
+    // response bodies, for routes without an {id} in the path.
+    IDField string
+    // Load returns the current state of an entity. Without it the response
+    // body is recorded as the after state.
+    Load func(r *http.Request, id string) (interface{}, error)
+    // Action overrides the action derived from the request method.
+    Action string
+    // Skip, when it reports true, runs the handler without auditing.
+    Skip func(r *http.Request) bool
+}
+
+// Auditor records the changes made by wrapped handlers in the audit log.
+type Auditor struct {
+    Repo *repository.AuditRepository
+}
+
+// Audit wraps a mutating handler. The entity is loaded before and after the
+// handler runs, and a successful change is recorded with the caller, the
+// request ID and both states. Failing to record is logged, not reported.
+func (a *Auditor) Audit(spec AuditSpec, h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if spec.Skip != nil && spec.Skip(r) {
+            h(w, r)
+            return
+        }
+
+        id := mux.Vars(r)["id"]
+        if id == "" && r.Method == http.MethodPost && spec.IDField != "" {
+            id = peekID(r, spec.IDField)
+        }
+
+        var before json.RawMessage
+        if id != "" {
+            before = a.load(r, spec, id)
+        }
+
+        rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+        h(rec, r)
+        if rec.status < 200 || rec.status >= 300 {
+            return
+        }
+
+        entry := models.AuditEntry{
+            RequestID: requestIDOf(r),
+            Action:    spec.Action,
+            Entity:    spec.Entity,
+            EntityID:  id,
+            Before:    before,
+        }
+        if entry.Action == "" {
+            entry.Action = auditAction(r.Method, before != nil)
+        }
+        if principal := principalOf(r); principal != nil {
+            entry.Actor, entry.ActorRole, entry.OperatorID = principal.Subject, string(principal.Role), principal.Operator
+        }
+
+        if entry.EntityID == "" && spec.IDField != "" {
+            entry.EntityID = jsonField(rec.body.Bytes(), spec.IDField)
+        }
+        switch {
+        case entry.Action == models.AuditDelete:
+        case spec.Load != nil && entry.EntityID != "":
+            entry.After = a.load(r, spec, entry.EntityID)
+        default:
+            entry.After = json.RawMessage(rec.body.Bytes())
+        }
+
+        if err := a.Repo.Record(entry); err != nil {
+            log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+        }
+    }
+}
+
+// load returns the JSON state of an entity, or nil if it does not exist.
+func (a *Auditor) load(r *http.Request, spec AuditSpec, id string) json.RawMessage {
+    if spec.Load == nil {
+        return nil
+    }
+
+    v, err := spec.Load(r, id)
+    if err != nil {
+        if !errors.Is(err, repository.ErrNotFound) {
+            log.Printf("%s %s: failed to load %s %s for audit: %v", r.Method, r.URL.Path, spec.Entity, id, err)
+        }
+        return nil
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return nil
+    }
+    return b
+}
+
+// auditAction derives the action of a request from its method. A POST to an
+// entity that already existed is an upsert and counts as an update.
+func auditAction(method string, existed bool) string {
+    switch {
+    case method == http.MethodDelete:
+        return models.AuditDelete
+    case method == http.MethodPost && !existed:
+        return models.AuditCreate
+    }
+    return models.AuditUpdate
+}
+
+// peekID reads an ID field from a JSON request body and leaves the body
+// intact for the handler.
+func peekID(r *http.Request, field string) string {
+    b, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+    r.Body = struct {
+        io.Reader
+        io.Closer
+    }{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+    if err != nil {
+        return ""
+    }
+    return jsonField(b, field)
+}
+
+// jsonField returns a top-level string or number field of a JSON object.
+func jsonField(b []byte, field string) string {
+    var obj map[string]json.RawMessage
+    if json.Unmarshal(b, &obj) != nil {
+        return ""
+    }
+
+    var v interface{}
+    dec := json.NewDecoder(bytes.NewReader(obj[field]))
+    dec.UseNumber()
+    if dec.Decode(&v) != nil {
+        return ""
+    }
+    switch v := v.(type) {
+    case string:
+        return v
+    case json.Number:
+        return v.String()
+    }
+    return ""
+}
+
+// responseRecorder passes a response through while keeping its status and body.
+type responseRecorder struct {
+    http.ResponseWriter
+    status int
+    body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+    rr.status = status
+    rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+    rr.body.Write(b)
+    return rr.ResponseWriter.Write(b)
+}
+
+// GetAuditLog retrieves a page of audit entries, optionally filtered by
+// entity=, id= and since= (RFC 3339).
+func (a *Auditor) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    opts, err := parseListOptions(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    filter := repository.AuditFilter{Entity: params.Get("entity"), EntityID: params.Get("id")}
+    if v := params.Get("since"); v != "" {
+        since, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid since")
+            return
+        }
+        filter.Since = &since
+    }
+
+    entries, next, err := a.Repo.ForOperator(operatorOf(r)).GetAuditLog(filter, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query audit log")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: entries, NextCursor: next})
+}
@@ -0,0 +1,99 @@
+// internal/handlers/auth.go
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/auth"
+)
+
+// APIKeyHeader carries a static API key. JWTs are sent as
+// "Authorization: Bearer <token>".
+const APIKeyHeader = "X-API-Key"
+
+// contextKey namespaces the request context values set by middleware.
+type contextKey int
+
+const (
+    principalKey contextKey = iota
+    requestIDKey
+)
+
+// Authenticate is middleware that resolves the caller of every request from
+// a bearer JWT or an API key and rejects requests without valid credentials.
+func Authenticate(a *auth.Authenticator) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            var principal *auth.Principal
+            var err error
+
+            authorization := r.Header.Get("Authorization")
+            switch {
+            case authorization != "":
+                scheme, token, ok := strings.Cut(authorization, " ")
+                if !ok || !strings.EqualFold(scheme, "Bearer") {
+                    err = auth.ErrInvalidCredentials
+                    break
+                }
+                principal, err = a.AuthenticateToken(strings.TrimSpace(token))
+            case r.Header.Get(APIKeyHeader) != "":
+                principal, err = a.AuthenticateAPIKey(r.Header.Get(APIKeyHeader))
+            default:
+                w.Header().Set("WWW-Authenticate", `Bearer realm="parking-space-monitor"`)
+                writeProblem(w, r, http.StatusUnauthorized, CodeUnauthenticated, "Missing credentials")
+                return
+            }
+
+            if errors.Is(err, auth.ErrInvalidCredentials) {
+                w.Header().Set("WWW-Authenticate", `Bearer realm="parking-space-monitor", error="invalid_token"`)
+                writeProblem(w, r, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid credentials")
+                return
+            } else if err != nil {
+                writeError(w, r, err, "Failed to authenticate")
+                return
+            }
+
+            next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, principal)))
+        })
+    }
+}
+
+// Require wraps a handler so it only runs for callers holding at least role.
+func Require(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        principal := principalOf(r)
+        if principal == nil || !principal.Role.Allows(role) {
+            writeProblem(w, r, http.StatusForbidden, CodeForbidden, "Requires role "+string(role))
+            return
+        }
+        h(w, r)
+    }
+}
+
+// principalOf returns the authenticated caller of a request, or nil outside
+// Authenticate.
+func principalOf(r *http.Request) *auth.Principal {
+    principal, _ := r.Context().Value(principalKey).(*auth.Principal)
+    return principal
+}
+
+// actorOf returns the subject of the caller of a request.
+func actorOf(r *http.Request) string {
+    if principal := principalOf(r); principal != nil {
+        return principal.Subject
+    }
+    return ""
+}
+
+// checkTaxiAccess rejects drivers acting for any taxi but their own.
+func checkTaxiAccess(w http.ResponseWriter, r *http.Request, taxiID string) bool {
+    principal := principalOf(r)
+    if principal != nil && principal.Role == auth.RoleDriver && principal.TaxiID != taxiID {
+        writeProblem(w, r, http.StatusForbidden, CodeForbidden, "Drivers may only act for their own taxi")
+        return false
+    }
+    return true
+}
@@ -0,0 +1,106 @@
+// internal/handlers/auth_test.go
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/auth"
+    "github.com/gorilla/mux"
+)
+
+// testAuthenticator knows one API key per test principal.
+func testAuthenticator(t *testing.T) *auth.Authenticator {
+    t.Helper()
+    a := &auth.Authenticator{}
+    principals := map[string]auth.Principal{
+        "driver-t1":  {Subject: "driver-1", Role: auth.RoleDriver, Operator: "acme", TaxiID: "T1"},
+        "dispatcher": {Subject: "console", Role: auth.RoleDispatcher, Operator: "acme"},
+        "readonly":   {Subject: "viewer", Role: auth.RoleReadonly, Operator: "acme"},
+    }
+    for key, p := range principals {
+        if err := a.AddAPIKey(key, p); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return a
+}
+
+func TestCheckTaxiAccess(t *testing.T) {
+    router := mux.NewRouter()
+    router.Use(Authenticate(testAuthenticator(t)))
+    router.HandleFunc("/taxis/{id}", Require(auth.RoleDriver, func(w http.ResponseWriter, r *http.Request) {
+        if checkTaxiAccess(w, r, mux.Vars(r)["id"]) {
+            w.WriteHeader(http.StatusNoContent)
+        }
+    })).Methods(http.MethodPut)
+
+    tests := []struct {
+        name   string
+        key    string
+        taxi   string
+        status int
+    }{
+        {"driver on own taxi", "driver-t1", "T1", http.StatusNoContent},
+        {"driver on another taxi", "driver-t1", "T2", http.StatusForbidden},
+        {"dispatcher on any taxi", "dispatcher", "T2", http.StatusNoContent},
+        {"readonly below driver", "readonly", "T1", http.StatusForbidden},
+        {"unknown key", "nope", "T1", http.StatusUnauthorized},
+        {"no credentials", "", "T1", http.StatusUnauthorized},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodPut, "/taxis/"+tt.taxi, nil)
+            if tt.key != "" {
+                req.Header.Set(APIKeyHeader, tt.key)
+            }
+            rec := httptest.NewRecorder()
+            router.ServeHTTP(rec, req)
+            if rec.Code != tt.status {
+                t.Errorf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+            }
+        })
+    }
+}
+
+// A driver is refused before the handler touches its repositories, so the
+// real handlers need none here.
+func TestTaxiHandlersRefuseOtherDriversTaxi(t *testing.T) {
+    th := &TaxiHandler{}
+    router := mux.NewRouter()
+    router.Use(Authenticate(testAuthenticator(t)))
+    router.HandleFunc("/taxis", th.CreateTaxi).Methods(http.MethodPost)
+    router.HandleFunc("/taxis/{id}", th.UpdateTaxi).Methods(http.MethodPut)
+
+    tests := []struct {
+        name   string
+        method string
+        path   string
+        body   string
+    }{
+        {"create", http.MethodPost, "/taxis", `{"taxi_id": "T2", "longitude": 106.8, "latitude": -6.2}`},
+        {"update", http.MethodPut, "/taxis/T2", `{"longitude": 106.8, "latitude": -6.2}`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+            req.Header.Set(APIKeyHeader, "driver-t1")
+            rec := httptest.NewRecorder()
+            router.ServeHTTP(rec, req)
+
+            if rec.Code != http.StatusForbidden {
+                t.Fatalf("got status %d, want 403: %s", rec.Code, rec.Body)
+            }
+            var problem Problem
+            if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+                t.Fatal(err)
+            }
+            if problem.Code != CodeForbidden {
+                t.Errorf("got code %q, want %q", problem.Code, CodeForbidden)
+            }
+        })
+    }
+}
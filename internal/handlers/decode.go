@@ -0,0 +1,69 @@
+// internal/handlers/decode.go
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+)
+
+// maxPayloadSize caps the size of a JSON request body.
+const maxPayloadSize = 1 << 20
+
+// decodeJSON decodes a single JSON object into v and checks v's validation
+// rules. It writes the problem response itself and reports whether the
+// handler may continue.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+    return decodeBody(w, r, v) && validate(w, r, v)
+}
+
+// decodeBody decodes a single JSON object into v, rejecting unknown fields
+// and trailing data, without running validation rules.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+    dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+    dec.DisallowUnknownFields()
+
+    if err := dec.Decode(v); err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidPayload, payloadError(err))
+        return false
+    }
+    if err := dec.Decode(&struct{}{}); err != io.EOF {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidPayload, "Request body must contain a single JSON object")
+        return false
+    }
+    return true
+}
+
+// validate checks v's validation rules and writes a 422 listing every
+// offending field when any fail.
+func validate(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+    if errs := validation.Struct(v); len(errs) > 0 {
+        writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, errs)
+        return false
+    }
+    return true
+}
+
+// payloadError turns a JSON decoding error into a client-facing message.
+func payloadError(err error) string {
+    var syntaxErr *json.SyntaxError
+    var typeErr *json.UnmarshalTypeError
+    var maxErr *http.MaxBytesError
+
+    switch {
+    case errors.As(err, &syntaxErr):
+        return fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset)
+    case errors.As(err, &typeErr):
+        return fmt.Sprintf("Field %q must be of type %s", typeErr.Field, typeErr.Type)
+    case errors.As(err, &maxErr):
+        return fmt.Sprintf("Request body must not exceed %d bytes", maxErr.Limit)
+    case errors.Is(err, io.EOF):
+        return "Request body must not be empty"
+    }
+    // DisallowUnknownFields reports `json: unknown field "x"`
+    return "Invalid request payload: " + err.Error()
+}
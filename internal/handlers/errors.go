@@ -0,0 +1,103 @@
+// internal/handlers/errors.go
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "log"
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+)
+
+// problemContentType is the media type of error responses (RFC 7807).
+const problemContentType = "application/problem+json"
+
+// Stable, machine-readable error codes carried in every problem response.
+const (
+    CodeInvalidPayload     = "invalid_payload"
+    CodeInvalidParameter   = "invalid_parameter"
+    CodeInvalidSort        = "invalid_sort"
+    CodeInvalidCursor      = "invalid_cursor"
+    CodeValidationFailed   = "validation_failed"
+    CodeNotFound           = "not_found"
+    CodeConflict           = "conflict"
+    CodeQueueEmpty         = "queue_empty"
+    CodeInsufficientHistory = "insufficient_history"
+    CodeTooManyCells       = "too_many_cells"
+    CodeUnauthenticated    = "unauthenticated"
+    CodeInvalidCredentials = "invalid_credentials"
+    CodeForbidden          = "forbidden"
+    CodeInternal           = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+    Type     string `json:"type"`
+    Title    string `json:"title"`
+    Status   int    `json:"status"`
+    Detail   string `json:"detail,omitempty"`
+    Instance string `json:"instance,omitempty"`
+    Code     string `json:"code"`
+
+    // Errors lists the offending fields of a validation failure.
+    Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+// writeProblem writes an application/problem+json response.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+    writeProblemDetails(w, r, Problem{Status: status, Code: code, Detail: detail})
+}
+
+// writeFieldErrors writes a problem response listing the offending fields.
+func writeFieldErrors(w http.ResponseWriter, r *http.Request, status int, code string, errs []validation.FieldError) {
+    writeProblemDetails(w, r, Problem{
+        Status: status,
+        Code:   code,
+        Detail: "One or more fields are invalid",
+        Errors: errs,
+    })
+}
+
+// writeProblemDetails fills in the standard members of a problem and writes it.
+func writeProblemDetails(w http.ResponseWriter, r *http.Request, problem Problem) {
+    problem.Type = "urn:parking-space-monitor:problem:" + problem.Code
+    problem.Title = http.StatusText(problem.Status)
+    problem.Instance = r.URL.Path
+
+    w.Header().Set("Content-Type", problemContentType)
+    w.WriteHeader(problem.Status)
+    json.NewEncoder(w).Encode(problem)
+}
+
+// writeError maps a repository error to a problem response. Not-found and
+// conflict errors carry their own message; anything else is logged and
+// reported as an internal error with the given detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+    switch {
+    case errors.Is(err, repository.ErrNotFound):
+        writeProblem(w, r, http.StatusNotFound, CodeNotFound, err.Error())
+    case errors.Is(err, repository.ErrConflict):
+        writeProblem(w, r, http.StatusConflict, CodeConflict, err.Error())
+    case errors.Is(err, repository.ErrInvalidSort):
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidSort, err.Error())
+    case errors.Is(err, repository.ErrInvalidCursor):
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidCursor, err.Error())
+    default:
+        log.Printf("%s %s: %s: %v", r.Method, r.URL.Path, detail, err)
+        writeProblem(w, r, http.StatusInternalServerError, CodeInternal, detail)
+    }
+}
+
+// writeJSON writes v as an application/json response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+// writeMessage writes a short JSON confirmation for operations without a body.
+func writeMessage(w http.ResponseWriter, status int, message string) {
+    writeJSON(w, status, map[string]string{"message": message})
+}
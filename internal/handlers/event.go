@@ -0,0 +1,44 @@
+// internal/handlers/event.go
+package handlers
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+// EventHandler handles HTTP requests for events.
+type EventHandler struct {
+    Repo *repository.EventRepository
+}
+
+// GetEvents retrieves a page of events of the calling operator, optionally
+// filtered by type=, taxi_id= and since= (RFC 3339).
+func (eh *EventHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    opts, err := parseListOptions(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    filter := repository.EventFilter{Type: params.Get("type"), TaxiID: params.Get("taxi_id")}
+    if v := params.Get("since"); v != "" {
+        since, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid since")
+            return
+        }
+        filter.Since = &since
+    }
+
+    events, next, err := eh.Repo.ForOperator(operatorOf(r)).GetEvents(filter, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query events")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: events, NextCursor: next})
+}
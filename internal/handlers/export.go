@@ -0,0 +1,38 @@
+// internal/handlers/export.go
+package handlers
+
+import (
+    "bytes"
+    "fmt"
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/SangBejoo/parking-space-monitor/internal/snapshot"
+)
+
+// ExportHandler handles HTTP requests for state snapshots.
+type ExportHandler struct {
+    Repo *repository.Repository
+}
+
+// Export streams a snapshot archive of the places, taxis, mappings and taxi
+// durations visible to the calling operator.
+func (eh *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+    snap, err := eh.Repo.ForOperator(operatorOf(r)).Snapshot()
+    if err != nil {
+        writeError(w, r, err, "Failed to export snapshot")
+        return
+    }
+
+    // Build the archive first so a failure can still be reported as an error
+    var buf bytes.Buffer
+    if err := snapshot.Write(&buf, snap); err != nil {
+        writeError(w, r, err, "Failed to write snapshot")
+        return
+    }
+
+    filename := fmt.Sprintf("snapshot-%s.tar.gz", snap.CreatedAt.Format("20060102T150405Z"))
+    w.Header().Set("Content-Type", "application/gzip")
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+    w.Write(buf.Bytes())
+}
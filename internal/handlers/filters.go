@@ -0,0 +1,141 @@
+// internal/handlers/filters.go
+package handlers
+
+import (
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+const (
+    // defaultPageSize is used when a list request does not give a limit.
+    defaultPageSize = 100
+    // maxPageSize caps the limit a list request may ask for.
+    maxPageSize = 1000
+)
+
+// listResponse is the envelope returned by every list endpoint.
+type listResponse struct {
+    Data       interface{} `json:"data"`
+    NextCursor *string     `json:"next_cursor"`
+}
+
+// parseListOptions parses limit=, cursor=, sort= (prefix with - for
+// descending) and updated_since= (RFC 3339) from a query string.
+func parseListOptions(params url.Values) (repository.ListOptions, error) {
+    opts := repository.ListOptions{Limit: defaultPageSize, Cursor: params.Get("cursor")}
+
+    if v := params.Get("limit"); v != "" {
+        limit, err := strconv.Atoi(v)
+        if err != nil || limit <= 0 || limit > maxPageSize {
+            return opts, fmt.Errorf("Invalid limit")
+        }
+        opts.Limit = limit
+    }
+
+    if v := params.Get("sort"); v != "" {
+        opts.Sort = strings.TrimPrefix(v, "-")
+        opts.Desc = strings.HasPrefix(v, "-")
+    }
+
+    if v := params.Get("updated_since"); v != "" {
+        since, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            return opts, fmt.Errorf("Invalid updated_since")
+        }
+        opts.UpdatedSince = &since
+    }
+
+    return opts, nil
+}
+
+// spatialParams holds the spatial query parameters shared by the list endpoints.
+type spatialParams struct {
+    BBox    *geo.BBox
+    Near    *geo.LatLon
+    RadiusM float64
+    PlaceID int
+}
+
+// parseSpatialParams parses bbox=minLon,minLat,maxLon,maxLat, near=lat,lon with
+// radius_m=, and place_id= from a query string.
+func parseSpatialParams(params url.Values) (spatialParams, error) {
+    var sp spatialParams
+
+    if v := params.Get("bbox"); v != "" {
+        values, err := parseFloats(v, 4)
+        if err != nil {
+            return sp, fmt.Errorf("Invalid bbox")
+        }
+        box := geo.BBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+        if box.MinLon > box.MaxLon || box.MinLat > box.MaxLat ||
+            box.MinLon < -180 || box.MaxLon > 180 || box.MinLat < -90 || box.MaxLat > 90 {
+            return sp, fmt.Errorf("Invalid bbox")
+        }
+        sp.BBox = &box
+    }
+
+    near, radius := params.Get("near"), params.Get("radius_m")
+    if (near == "") != (radius == "") {
+        return sp, fmt.Errorf("near and radius_m must be given together")
+    }
+    if near != "" {
+        values, err := parseFloats(near, 2)
+        if err != nil || values[0] < -90 || values[0] > 90 || values[1] < -180 || values[1] > 180 {
+            return sp, fmt.Errorf("Invalid near")
+        }
+        radiusM, err := strconv.ParseFloat(radius, 64)
+        if err != nil || radiusM <= 0 {
+            return sp, fmt.Errorf("Invalid radius_m")
+        }
+        sp.Near = &geo.LatLon{Lat: values[0], Lon: values[1]}
+        sp.RadiusM = radiusM
+    }
+
+    if v := params.Get("place_id"); v != "" {
+        placeID, err := strconv.Atoi(v)
+        if err != nil || placeID <= 0 {
+            return sp, fmt.Errorf("Invalid place_id")
+        }
+        sp.PlaceID = placeID
+    }
+
+    return sp, nil
+}
+
+// parseIncludeDeleted parses include_deleted=, which adds soft-deleted rows
+// to a list.
+func parseIncludeDeleted(params url.Values) (bool, error) {
+    v := params.Get("include_deleted")
+    if v == "" {
+        return false, nil
+    }
+    include, err := strconv.ParseBool(v)
+    if err != nil {
+        return false, fmt.Errorf("Invalid include_deleted")
+    }
+    return include, nil
+}
+
+// parseFloats parses a comma-separated list of exactly n numbers.
+func parseFloats(v string, n int) ([]float64, error) {
+    parts := strings.Split(v, ",")
+    if len(parts) != n {
+        return nil, fmt.Errorf("expected %d values, got %d", n, len(parts))
+    }
+
+    values := make([]float64, n)
+    for i, part := range parts {
+        f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+        if err != nil {
+            return nil, err
+        }
+        values[i] = f
+    }
+    return values, nil
+}
@@ -0,0 +1,77 @@
+// internal/handlers/forecast.go
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/forecast"
+    "github.com/gorilla/mux"
+)
+
+// Limits of a forecast request.
+const (
+    maxForecastIntervals = 7 * 24
+    maxForecastWeeks     = 52
+)
+
+// GetForecast estimates the occupancy of a place for the coming hours from
+// its hourly occupancy history, starting with the current hour. intervals=
+// is how many hours (1 by default, up to a week) and weeks= how much history
+// to learn from (8 by default). Weekday and hour are taken in tz=, which
+// defaults to the time zone of the place's schedule and then UTC. The
+// response carries backtest accuracy over the most recent week.
+func (ph *PlaceHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
+    placeID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    params := r.URL.Query()
+    intervals := 1
+    if v := params.Get("intervals"); v != "" {
+        if intervals, err = strconv.Atoi(v); err != nil || intervals < 1 || intervals > maxForecastIntervals {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid intervals, expected 1 to 168")
+            return
+        }
+    }
+    weeks := 8
+    if v := params.Get("weeks"); v != "" {
+        if weeks, err = strconv.Atoi(v); err != nil || weeks < 1 || weeks > maxForecastWeeks {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid weeks, expected 1 to 52")
+            return
+        }
+    }
+
+    place, err := ph.repo(r).GetPlaceByID(placeID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query place")
+        return
+    }
+
+    timezone := params.Get("tz")
+    if timezone == "" && place.Schedule != nil {
+        timezone = place.Schedule.Timezone
+    }
+    loc, err := time.LoadLocation(timezone)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid tz")
+        return
+    }
+
+    // The current hour is still being sampled, so it is forecast, not learned
+    now := time.Now().UTC().Truncate(time.Hour)
+    history, err := ph.repo(r).GetOccupancyHistory(placeID, "hour", now.AddDate(0, 0, -7*weeks), now)
+    if err != nil {
+        writeError(w, r, err, "Failed to query occupancy history")
+        return
+    }
+    if len(history.Points) == 0 {
+        writeProblem(w, r, http.StatusUnprocessableEntity, CodeInsufficientHistory, "The place has no occupancy history to learn from")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, forecast.Occupancy(history, time.Hour, now, intervals, loc))
+}
@@ -0,0 +1,170 @@
+// internal/handlers/geojson.go
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// geoJSONContentType is the media type of GeoJSON responses (RFC 7946).
+const geoJSONContentType = "application/geo+json"
+
+// wantsGeoJSON reports whether the client asked for GeoJSON, either with
+// Accept: application/geo+json or with ?format=geojson.
+func wantsGeoJSON(r *http.Request) bool {
+    if r.URL.Query().Get("format") == "geojson" {
+        return true
+    }
+    return strings.Contains(r.Header.Get("Accept"), geoJSONContentType)
+}
+
+// writeGeoJSON encodes a GeoJSON object with the GeoJSON content type.
+func writeGeoJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", geoJSONContentType)
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+// taxiFeature converts a taxi location to a Point feature. placeID is the
+// place the taxi is currently inside, or 0.
+func taxiFeature(taxi models.TaxiLocation, placeID int) models.Feature {
+    properties := map[string]interface{}{
+        "taxi_id":    taxi.TaxiID,
+        "place_id":   nil,
+        "status":     taxi.Status,
+        "updated_at": taxi.UpdatedAt,
+    }
+    if placeID > 0 {
+        properties["place_id"] = placeID
+    }
+
+    return models.Feature{
+        Type: "Feature",
+        ID:   taxi.TaxiID,
+        Geometry: &models.Geometry{
+            Type:        "Point",
+            Coordinates: []float64{taxi.Longitude, taxi.Latitude},
+        },
+        Properties: properties,
+    }
+}
+
+// placeFeature converts a place to a Polygon feature carrying its current
+// occupancy and the taxis inside it, and whether it is open when known.
+func placeFeature(place models.Place, taxiIDs []string) models.Feature {
+    if taxiIDs == nil {
+        taxiIDs = []string{}
+    }
+    properties := map[string]interface{}{
+        "place_id":   place.PlaceID,
+        "place_name": place.PlaceName,
+        "version":    place.Version,
+        "type":       place.Type,
+        "occupancy":  len(taxiIDs),
+        "taxi_ids":   taxiIDs,
+    }
+    if place.OpenState != nil {
+        properties["open"] = place.OpenState.Open
+        properties["next_transition"] = place.OpenState.NextTransition
+    }
+    return models.Feature{
+        Type:       "Feature",
+        ID:         place.PlaceID,
+        Geometry:   placeGeometry(place),
+        Properties: properties,
+    }
+}
+
+// mappingFeature converts a mapping to a feature shaped like its place.
+func mappingFeature(mapping models.Mapping, place *models.Place) models.Feature {
+    feature := models.Feature{
+        Type: "Feature",
+        ID:   mapping.ID,
+        Properties: map[string]interface{}{
+            "id":         mapping.ID,
+            "place_id":   mapping.PlaceID,
+            "place_name": mapping.PlaceName,
+            "taxi_id":    mapping.TaxiID,
+        },
+    }
+    if place != nil {
+        feature.Geometry = placeGeometry(*place)
+    }
+    return feature
+}
+
+// violationFeatures converts a violation to the polygon of its place and the
+// trail of the taxi, both carrying the violation's details. A trail of a
+// single position is a Point; an empty trail is left out.
+func violationFeatures(violation models.Violation, place models.Place) []models.Feature {
+    properties := func(kind string) map[string]interface{} {
+        return map[string]interface{}{
+            "feature":      kind,
+            "violation_id": violation.ID,
+            "taxi_id":      violation.TaxiID,
+            "place_id":     violation.PlaceID,
+            "place_name":   place.PlaceName,
+            "rule":         violation.Rule,
+            "message":      violation.Message,
+            "status":       violation.Status,
+            "detected_at":  violation.DetectedAt,
+            "closed_at":    violation.ClosedAt,
+            "resolution":   violation.Resolution,
+        }
+    }
+
+    features := []models.Feature{{
+        Type:       "Feature",
+        ID:         "place",
+        Geometry:   placeGeometry(place),
+        Properties: properties("place"),
+    }}
+    if len(violation.Evidence) == 0 {
+        return features
+    }
+
+    coordinates := make([][]float64, 0, len(violation.Evidence))
+    times := make([]time.Time, 0, len(violation.Evidence))
+    for _, point := range violation.Evidence {
+        coordinates = append(coordinates, []float64{point.Longitude, point.Latitude})
+        times = append(times, point.RecordedAt)
+    }
+    trail := properties("trail")
+    trail["recorded_at"] = times
+
+    geometry := &models.Geometry{Type: "LineString", Coordinates: coordinates}
+    if len(coordinates) == 1 {
+        geometry = &models.Geometry{Type: "Point", Coordinates: coordinates[0]}
+    }
+    return append(features, models.Feature{Type: "Feature", ID: "trail", Geometry: geometry, Properties: trail})
+}
+
+// heatmapFeature converts a heatmap cell to a Polygon feature of its bounds.
+func heatmapFeature(cell models.HeatmapCell) models.Feature {
+    b := cell.Bounds
+    ring := [][]float64{{b.MinLon, b.MinLat}, {b.MaxLon, b.MinLat}, {b.MaxLon, b.MaxLat}, {b.MinLon, b.MaxLat}, {b.MinLon, b.MinLat}}
+    return models.Feature{
+        Type:     "Feature",
+        ID:       cell.Cell,
+        Geometry: &models.Geometry{Type: "Polygon", Coordinates: [][][]float64{ring}},
+        Properties: map[string]interface{}{
+            "cell":  cell.Cell,
+            "count": cell.Count,
+            "taxis": cell.Taxis,
+        },
+    }
+}
+
+// placeGeometry returns the area of a place as a Polygon or MultiPolygon
+// geometry, as stored; an untyped polygon is a Polygon.
+func placeGeometry(place models.Place) *models.Geometry {
+    geometryType := place.Polygon.Type
+    if geometryType == "" {
+        geometryType = "Polygon"
+    }
+    return &models.Geometry{Type: geometryType, Coordinates: place.Polygon.GeometryCoordinates()}
+}
@@ -0,0 +1,180 @@
+// internal/handlers/heatmap.go
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/heatmap"
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+// Limits of a heatmap request.
+const (
+    maxHeatmapCells   = 10000
+    maxHeatmapSpan    = 31 * 24 * time.Hour
+    minHeatmapCellM   = 10
+    maxHeatmapCellM   = 100000
+    defaultGeohashLen = 7
+)
+
+// HeatmapHandler handles HTTP requests for taxi density heatmaps.
+type HeatmapHandler struct {
+    Taxis  *repository.TaxiRepository
+    Places *repository.PlaceRepository
+}
+
+// GetHeatmap bins taxi positions into cells and returns them as GeoJSON
+// polygons carrying the number of positions and of distinct taxis, densest
+// first.
+//
+// source=current (the default) uses the current location of every taxi that
+// is not offline; source=history the positions recorded between from= and
+// to= (RFC 3339, the last 24 hours by default, at most 31 days). cells=geohash
+// (the default) bins by geohashes of precision= characters (1-9, default 7,
+// about 150 m); cells=grid by squares of cell_m= metres (default 250).
+// bbox= limits the area, outside_places=true drops positions inside any
+// place, and min_count= drops sparse cells.
+func (hh *HeatmapHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    sp, err := parseSpatialParams(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+    if sp.Near != nil || sp.PlaceID > 0 {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Heatmaps are limited by bbox only")
+        return
+    }
+    q := repository.PositionQuery{BBox: sp.BBox}
+
+    switch params.Get("source") {
+    case "", "current":
+    case "history":
+        to := time.Now()
+        if v := params.Get("to"); v != "" {
+            if to, err = time.Parse(time.RFC3339, v); err != nil {
+                writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid to")
+                return
+            }
+        }
+        from := to.Add(-24 * time.Hour)
+        if v := params.Get("from"); v != "" {
+            if from, err = time.Parse(time.RFC3339, v); err != nil {
+                writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid from")
+                return
+            }
+        }
+        if !from.Before(to) || to.Sub(from) > maxHeatmapSpan {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "from must be before to and at most 31 days earlier")
+            return
+        }
+        q.From, q.To = &from, &to
+    default:
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid source, expected current or history")
+        return
+    }
+
+    var binner heatmap.Binner
+    switch params.Get("cells") {
+    case "", "geohash":
+        precision := defaultGeohashLen
+        if v := params.Get("precision"); v != "" {
+            if precision, err = strconv.Atoi(v); err != nil || precision < 1 || precision > geo.MaxGeohashPrecision {
+                writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid precision, expected 1 to 9")
+                return
+            }
+        }
+        binner = heatmap.Geohash{Precision: precision}
+    case "grid":
+        cellM := 250.0
+        if v := params.Get("cell_m"); v != "" {
+            if cellM, err = strconv.ParseFloat(v, 64); err != nil || cellM < minHeatmapCellM || cellM > maxHeatmapCellM {
+                writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid cell_m, expected 10 to 100000")
+                return
+            }
+        }
+        binner = heatmap.Grid{CellM: cellM}
+    default:
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid cells, expected geohash or grid")
+        return
+    }
+
+    minCount := 1
+    if v := params.Get("min_count"); v != "" {
+        if minCount, err = strconv.Atoi(v); err != nil || minCount < 1 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid min_count")
+            return
+        }
+    }
+
+    var places []placeArea
+    if v := params.Get("outside_places"); v != "" {
+        outside, err := strconv.ParseBool(v)
+        if err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid outside_places")
+            return
+        }
+        if outside {
+            if places, err = hh.placeAreas(r); err != nil {
+                writeError(w, r, err, "Failed to query places")
+                return
+            }
+        }
+    }
+
+    h := heatmap.New(binner, maxHeatmapCells)
+    err = hh.Taxis.ForOperator(operatorOf(r)).EachPosition(q, func(taxiID string, lon, lat float64) error {
+        for _, place := range places {
+            if place.bounds.Contains(lon, lat) && geo.PointInPolygon(lon, lat, place.ring) {
+                return nil
+            }
+        }
+        return h.Add(taxiID, lat, lon)
+    })
+    if errors.Is(err, heatmap.ErrTooManyCells) {
+        writeProblem(w, r, http.StatusUnprocessableEntity, CodeTooManyCells, "The positions fall into more than 10000 cells; use a coarser precision or a smaller bbox")
+        return
+    } else if err != nil {
+        writeError(w, r, err, "Failed to query positions")
+        return
+    }
+
+    cells := h.Cells(minCount)
+    features := make([]models.Feature, 0, len(cells))
+    for _, cell := range cells {
+        features = append(features, heatmapFeature(cell))
+    }
+    writeGeoJSON(w, http.StatusOK, models.NewFeatureCollection(features))
+}
+
+// placeArea is the outer ring of a place, or of one part of a MultiPolygon
+// place, with its bounds, to test positions against quickly.
+type placeArea struct {
+    ring   []geo.Point
+    bounds geo.BBox
+}
+
+// placeAreas returns the areas of the places visible to the caller.
+func (hh *HeatmapHandler) placeAreas(r *http.Request) ([]placeArea, error) {
+    places, _, err := hh.Places.ForOperator(operatorOf(r)).GetAllPlaces(repository.PlaceFilter{}, repository.ListOptions{})
+    if err != nil {
+        return nil, err
+    }
+    areas := make([]placeArea, 0, len(places))
+    for _, place := range places {
+        rings, err := place.Polygon.OuterRings()
+        if err != nil {
+            continue
+        }
+        for _, ring := range rings {
+            areas = append(areas, placeArea{ring: ring, bounds: geo.BoundsOf(ring)})
+        }
+    }
+    return areas, nil
+}
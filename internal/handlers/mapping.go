@@ -2,52 +2,115 @@
 package handlers
 
 import (
-    "encoding/json"
-    "fmt"  
+    "errors"
     "net/http"
     "strconv"
 
-    "database/sql" // Added import
-
     "github.com/SangBejoo/parking-space-monitor/internal/models"
     "github.com/SangBejoo/parking-space-monitor/internal/repository"
     "github.com/SangBejoo/parking-space-monitor/internal/scheduler"
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
     "github.com/gorilla/mux"
 )
 
 // MappingHandler handles HTTP requests for Mapping operations.
 type MappingHandler struct {
     Repo *repository.MappingRepository
+    Places *repository.PlaceRepository
+    Taxis *repository.TaxiRegistryRepository
     Scheduler *scheduler.Scheduler
 }
 
+// repo returns the mapping repository scoped to the calling operator.
+func (mh *MappingHandler) repo(r *http.Request) *repository.MappingRepository {
+    return mh.Repo.ForOperator(operatorOf(r))
+}
+
 // CreateMapping handles the creation of a new mapping.
 func (mh *MappingHandler) CreateMapping(w http.ResponseWriter, r *http.Request) {
     var mapping models.Mapping
-    if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    if !decodeJSON(w, r, &mapping) {
+        return
+    }
+
+    if !mh.checkReferences(w, r, mapping) {
         return
     }
 
-    if err := mh.Repo.InsertMapping(mapping); err != nil {
-        http.Error(w, "Failed to create mapping", http.StatusInternalServerError)
+    mappingID, err := mh.repo(r).InsertMapping(mapping)
+    if err != nil {
+        writeError(w, r, err, "Failed to create mapping")
         return
     }
 
-    w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(mapping)
+    mapping.ID = mappingID
+
+    writeJSON(w, http.StatusCreated, mapping)
 }
 
-// GetAllMappings retrieves all mappings.
+// checkReferences verifies that the place and taxi of a mapping exist and
+// writes a 422 naming the missing ones otherwise.
+func (mh *MappingHandler) checkReferences(w http.ResponseWriter, r *http.Request, mapping models.Mapping) bool {
+    var errs []validation.FieldError
+
+    if _, err := mh.Places.ForOperator(operatorOf(r)).GetPlaceByID(mapping.PlaceID); errors.Is(err, repository.ErrNotFound) {
+        errs = append(errs, validation.FieldError{Field: "place_id", Code: "not_found", Message: "place does not exist"})
+    } else if err != nil {
+        writeError(w, r, err, "Failed to check mapping place")
+        return false
+    }
+
+    exists, err := mh.Taxis.ForOperator(operatorOf(r)).IsRegistered(mapping.TaxiID)
+    if err != nil {
+        writeError(w, r, err, "Failed to check mapping taxi")
+        return false
+    }
+    if !exists {
+        errs = append(errs, validation.FieldError{Field: "taxi_id", Code: "not_found", Message: "taxi is not registered"})
+    }
+
+    if len(errs) > 0 {
+        writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, errs)
+        return false
+    }
+    return true
+}
+
+// GetAllMappings retrieves a page of mappings.
 func (mh *MappingHandler) GetAllMappings(w http.ResponseWriter, r *http.Request) {
-    mappings, err := mh.Repo.GetAllMappings()
+    opts, err := parseListOptions(r.URL.Query())
     if err != nil {
-        http.Error(w, "Failed to retrieve mappings", http.StatusInternalServerError)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(mappings)
+    mappings, next, err := mh.repo(r).GetAllMappings(opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve mappings")
+        return
+    }
+
+    if wantsGeoJSON(r) {
+        places := make(map[int]*models.Place)
+        features := make([]models.Feature, 0, len(mappings))
+        for _, mapping := range mappings {
+            place, ok := places[mapping.PlaceID]
+            if !ok {
+                if place, err = mh.Places.ForOperator(operatorOf(r)).GetPlaceByID(mapping.PlaceID); err != nil {
+                    writeError(w, r, err, "Failed to retrieve mapping places")
+                    return
+                }
+                places[mapping.PlaceID] = place
+            }
+            features = append(features, mappingFeature(mapping, place))
+        }
+        collection := models.NewFeatureCollection(features)
+        collection.NextCursor = next
+        writeGeoJSON(w, http.StatusOK, collection)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: mappings, NextCursor: next})
 }
 
 // GetMapping retrieves a single mapping by ID.
@@ -56,21 +119,36 @@ func (mh *MappingHandler) GetMapping(w http.ResponseWriter, r *http.Request) {
     idStr := vars["id"]
     mappingID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid mapping ID")
         return
     }
 
-    mapping, err := mh.Repo.GetMappingByID(mappingID)
-    if err == sql.ErrNoRows {
-        http.Error(w, "Mapping not found", http.StatusNotFound)
+    mapping, err := mh.repo(r).GetMappingByID(mappingID)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve mapping")
         return
-    } else if err != nil {
-        http.Error(w, "Failed to retrieve mapping", http.StatusInternalServerError)
+    }
+
+    if wantsGeoJSON(r) {
+        place, err := mh.Places.ForOperator(operatorOf(r)).GetPlaceByID(mapping.PlaceID)
+        if err != nil {
+            writeError(w, r, err, "Failed to retrieve mapping place")
+            return
+        }
+        writeGeoJSON(w, http.StatusOK, mappingFeature(*mapping, place))
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(mapping)
+    writeJSON(w, http.StatusOK, mapping)
+}
+
+// LoadMapping returns a mapping for the audit log.
+func (mh *MappingHandler) LoadMapping(r *http.Request, id string) (interface{}, error) {
+    mappingID, err := strconv.Atoi(id)
+    if err != nil {
+        return nil, err
+    }
+    return mh.repo(r).GetMappingByID(mappingID)
 }
 
 // UpdateMapping handles updating an existing mapping.
@@ -79,28 +157,26 @@ func (mh *MappingHandler) UpdateMapping(w http.ResponseWriter, r *http.Request)
     idStr := vars["id"]
     mappingID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid mapping ID")
         return
     }
 
     var mapping models.Mapping
-    if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    if !decodeJSON(w, r, &mapping) {
+        return
+    }
+
+    if !mh.checkReferences(w, r, mapping) {
         return
     }
 
     mapping.ID = mappingID
-    if err := mh.Repo.UpdateMapping(mapping); err != nil {
-        if err.Error() == "mapping not found" {
-            http.Error(w, "Mapping not found", http.StatusNotFound)
-            return
-        }
-        http.Error(w, "Failed to update mapping", http.StatusInternalServerError)
+    if err := mh.repo(r).UpdateMapping(mapping); err != nil {
+        writeError(w, r, err, "Failed to update mapping")
         return
     }
 
-    w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(mapping)
+    writeJSON(w, http.StatusOK, mapping)
 }
 
 // DeleteMapping handles deleting a mapping by ID.
@@ -109,21 +185,16 @@ func (mh *MappingHandler) DeleteMapping(w http.ResponseWriter, r *http.Request)
     idStr := vars["id"]
     mappingID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid mapping ID")
         return
     }
 
-    if err := mh.Repo.DeleteMapping(mappingID); err != nil {
-        if err.Error() == "mapping not found" {
-            http.Error(w, "Mapping not found", http.StatusNotFound)
-            return
-        }
-        http.Error(w, "Failed to delete mapping", http.StatusInternalServerError)
+    if err := mh.repo(r).DeleteMapping(mappingID); err != nil {
+        writeError(w, r, err, "Failed to delete mapping")
         return
     }
 
-    w.WriteHeader(http.StatusOK)
-    w.Write([]byte("Mapping deleted successfully"))
+    writeMessage(w, http.StatusOK, "Mapping deleted successfully")
 }
 
 // Additional Handlers for Scheduler Integration
@@ -133,5 +204,5 @@ func (mh *MappingHandler) TriggerMapping(w http.ResponseWriter, r *http.Request)
     go func() {
         mh.Scheduler.MapTaxiLocations()
     }()
-    fmt.Fprintf(w, "Mapping process triggered manually.")
+    writeMessage(w, http.StatusAccepted, "Mapping process triggered manually.")
 }
\ No newline at end of file
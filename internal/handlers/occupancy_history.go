@@ -0,0 +1,109 @@
+// internal/handlers/occupancy_history.go
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+// maxHistoryPoints caps how many buckets one history request may span.
+const maxHistoryPoints = 10000
+
+// stepAliases maps the accepted step= values to occupancy resolutions.
+var stepAliases = map[string]string{
+    "minute": "minute", "1m": "minute",
+    "hour": "hour", "1h": "hour",
+    "day": "day", "1d": "day",
+}
+
+// GetOccupancyHistory returns the min, max and average occupancy of a place
+// per bucket. from= and to= are RFC 3339 and default to the last 24 hours;
+// step= is minute, hour or day (1m, 1h, 1d) and defaults to hour.
+func (ph *PlaceHandler) GetOccupancyHistory(w http.ResponseWriter, r *http.Request) {
+    placeID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    params := r.URL.Query()
+    resolution := "hour"
+    if v := params.Get("step"); v != "" {
+        var ok bool
+        if resolution, ok = stepAliases[v]; !ok {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid step, expected minute, hour or day")
+            return
+        }
+    }
+
+    to := time.Now()
+    if v := params.Get("to"); v != "" {
+        if to, err = time.Parse(time.RFC3339, v); err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid to")
+            return
+        }
+    }
+    from := to.Add(-24 * time.Hour)
+    if v := params.Get("from"); v != "" {
+        if from, err = time.Parse(time.RFC3339, v); err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid from")
+            return
+        }
+    }
+    if !from.Before(to) {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "from must be before to")
+        return
+    }
+    if step, _ := repository.OccupancyStep(resolution); to.Sub(from)/step > maxHistoryPoints {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Range too long for step "+resolution)
+        return
+    }
+
+    history, err := ph.repo(r).GetOccupancyHistory(placeID, resolution, from, to)
+    if err != nil {
+        writeError(w, r, err, "Failed to query occupancy history")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, history)
+}
+
+// CompactOccupancy rolls up closed occupancy buckets and removes those past
+// their retention. minute_retention=, hour_retention= and day_retention= are
+// Go durations; 0 keeps a resolution forever. It spans every operator, so
+// only a platform admin may run it.
+func (ph *PlaceHandler) CompactOccupancy(w http.ResponseWriter, r *http.Request) {
+    if operatorOf(r) != "" {
+        writeProblem(w, r, http.StatusForbidden, CodeForbidden, "Occupancy compaction requires a platform admin")
+        return
+    }
+
+    retention := repository.DefaultOccupancyRetention
+    params := r.URL.Query()
+    for name, dst := range map[string]*time.Duration{
+        "minute_retention": &retention.Minute,
+        "hour_retention":   &retention.Hour,
+        "day_retention":    &retention.Day,
+    } {
+        if v := params.Get(name); v != "" {
+            d, err := time.ParseDuration(v)
+            if err != nil || d < 0 {
+                writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid "+name)
+                return
+            }
+            *dst = d
+        }
+    }
+
+    result, err := ph.Repo.CompactOccupancy(retention)
+    if err != nil {
+        writeError(w, r, err, "Failed to compact occupancy history")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, result)
+}
@@ -2,13 +2,13 @@
 package handlers
 
 import (
-    "encoding/json"
+    "io"
     "net/http"
     "strconv"
-	"fmt"
+    "time"
 
-    "database/sql"
     "github.com/gorilla/mux"
+    "github.com/SangBejoo/parking-space-monitor/internal/importer"
     "github.com/SangBejoo/parking-space-monitor/internal/models"
     "github.com/SangBejoo/parking-space-monitor/internal/repository"
 )
@@ -18,59 +18,181 @@ type PlaceHandler struct {
     Repo *repository.PlaceRepository
 }
 
+// repo returns the place repository scoped to the calling operator.
+func (ph *PlaceHandler) repo(r *http.Request) *repository.PlaceRepository {
+    return ph.Repo.ForOperator(operatorOf(r))
+}
+
 // CreatePlace handles the creation of a new place.
 func (ph *PlaceHandler) CreatePlace(w http.ResponseWriter, r *http.Request) {
     var place models.Place
-    if err := json.NewDecoder(r.Body).Decode(&place); err != nil {
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    if !decodeJSON(w, r, &place) || !checkOperator(w, r, place.OperatorID) {
         return
     }
 
-    placeID, err := ph.Repo.CreatePlace(place)
+    placeID, err := ph.repo(r).CreatePlace(place)
     if err != nil {
-        http.Error(w, "Failed to create place", http.StatusInternalServerError)
+        writeError(w, r, err, "Failed to create place")
         return
     }
 
     place.PlaceID = placeID
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(place)
+    place.OperatorID = operatorOf(r)
+    writeJSON(w, http.StatusCreated, place)
 }
 
-// GetAllPlaces retrieves all places.
+// GetAllPlaces retrieves a page of places, optionally filtered by bbox,
+// near/radius_m or place_id. Soft-deleted places are only listed with
+// include_deleted=true.
 func (ph *PlaceHandler) GetAllPlaces(w http.ResponseWriter, r *http.Request) {
-    places, err := ph.Repo.GetAllPlaces()
+    sp, err := parseSpatialParams(r.URL.Query())
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    includeDeleted, err := parseIncludeDeleted(r.URL.Query())
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    opts, err := parseListOptions(r.URL.Query())
     if err != nil {
-        http.Error(w, "Failed to query places", http.StatusInternalServerError)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(places)
+    places, next, err := ph.repo(r).GetAllPlaces(repository.PlaceFilter{
+        BBox:    sp.BBox,
+        Near:    sp.Near,
+        RadiusM: sp.RadiusM,
+        PlaceID: sp.PlaceID,
+
+        IncludeDeleted: includeDeleted,
+    }, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query places")
+        return
+    }
+    now := time.Now()
+    for i := range places {
+        withOpenState(&places[i], now)
+    }
+
+    if wantsGeoJSON(r) {
+        occupancy, err := ph.repo(r).GetOccupancy()
+        if err != nil {
+            writeError(w, r, err, "Failed to query occupancy")
+            return
+        }
+
+        features := make([]models.Feature, 0, len(places))
+        for _, place := range places {
+            features = append(features, placeFeature(place, occupancy[place.PlaceID]))
+        }
+        collection := models.NewFeatureCollection(features)
+        collection.NextCursor = next
+        writeGeoJSON(w, http.StatusOK, collection)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: places, NextCursor: next})
 }
 
-// GetPlace retrieves a single place by ID.
+// GetPlace retrieves a single place by ID. With at= (RFC 3339) it returns
+// the version of the place in effect at that time.
 func (ph *PlaceHandler) GetPlace(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     idStr := vars["id"]
     placeID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid place ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    if v := r.URL.Query().Get("at"); v != "" {
+        at, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid at")
+            return
+        }
+        ph.getPlaceAt(w, r, placeID, at)
+        return
+    }
+
+    place, err := ph.repo(r).GetPlaceByID(placeID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query place")
         return
     }
+    withOpenState(place, time.Now())
 
-    place, err := ph.Repo.GetPlaceByID(placeID)
-    if err == sql.ErrNoRows {
-        http.Error(w, "Place not found", http.StatusNotFound)
+    if wantsGeoJSON(r) {
+        occupancy, err := ph.repo(r).GetOccupancy()
+        if err != nil {
+            writeError(w, r, err, "Failed to query occupancy")
+            return
+        }
+        writeGeoJSON(w, http.StatusOK, placeFeature(*place, occupancy[place.PlaceID]))
         return
-    } else if err != nil {
-        http.Error(w, "Failed to query place", http.StatusInternalServerError)
+    }
+
+    writeJSON(w, http.StatusOK, place)
+}
+
+// withOpenState sets whether a place operates at now and when that changes.
+func withOpenState(place *models.Place, now time.Time) {
+    state := place.State(now)
+    place.OpenState = &state
+}
+
+// getPlaceAt writes the version of a place in effect at a time.
+func (ph *PlaceHandler) getPlaceAt(w http.ResponseWriter, r *http.Request, placeID int, at time.Time) {
+    version, err := ph.repo(r).GetPlaceAt(placeID, at)
+    if err != nil {
+        writeError(w, r, err, "Failed to query place version")
+        return
+    }
+
+    if wantsGeoJSON(r) {
+        // Occupancy is only known for the present
+        feature := placeFeature(version.Place(), nil)
+        delete(feature.Properties, "occupancy")
+        delete(feature.Properties, "taxi_ids")
+        feature.Properties["valid_from"] = version.ValidFrom
+        feature.Properties["valid_to"] = version.ValidTo
+        writeGeoJSON(w, http.StatusOK, feature)
+        return
+    }
+
+    writeJSON(w, http.StatusOK, version)
+}
+
+// GetPlaceVersions lists every version of a place, oldest first.
+func (ph *PlaceHandler) GetPlaceVersions(w http.ResponseWriter, r *http.Request) {
+    placeID, err := strconv.Atoi(mux.Vars(r)["id"])
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    versions, err := ph.repo(r).GetPlaceVersions(placeID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query place versions")
         return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(place)
+    writeJSON(w, http.StatusOK, versions)
+}
+
+// LoadPlace returns a place for the audit log.
+func (ph *PlaceHandler) LoadPlace(r *http.Request, id string) (interface{}, error) {
+    placeID, err := strconv.Atoi(id)
+    if err != nil {
+        return nil, err
+    }
+    return ph.repo(r).GetPlaceByID(placeID)
 }
 
 // UpdatePlace handles updating an existing place.
@@ -79,26 +201,21 @@ func (ph *PlaceHandler) UpdatePlace(w http.ResponseWriter, r *http.Request) {
     idStr := vars["id"]
     placeID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid place ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
         return
     }
 
     var place models.Place
-    if err := json.NewDecoder(r.Body).Decode(&place); err != nil {
-        http.Error(w, "Invalid request payload", http.StatusBadRequest)
+    if !decodeJSON(w, r, &place) || !checkOperator(w, r, place.OperatorID) {
         return
     }
 
-    if err := ph.Repo.UpdatePlace(placeID, place); err != nil {
-        if err.Error() == "place not found" {
-            http.Error(w, "Place not found", http.StatusNotFound)
-            return
-        }
-        http.Error(w, "Failed to update place", http.StatusInternalServerError)
+    if err := ph.repo(r).UpdatePlace(placeID, place); err != nil {
+        writeError(w, r, err, "Failed to update place")
         return
     }
 
-    fmt.Fprintf(w, "Place updated.")
+    writeMessage(w, http.StatusOK, "Place updated.")
 }
 
 // DeletePlace handles deleting a place by ID.
@@ -107,18 +224,115 @@ func (ph *PlaceHandler) DeletePlace(w http.ResponseWriter, r *http.Request) {
     idStr := vars["id"]
     placeID, err := strconv.Atoi(idStr)
     if err != nil {
-        http.Error(w, "Invalid place ID", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    if err := ph.repo(r).DeletePlace(placeID); err != nil {
+        writeError(w, r, err, "Failed to delete place")
         return
     }
 
-    if err := ph.Repo.DeletePlace(placeID); err != nil {
-        if err.Error() == "place not found" {
-            http.Error(w, "Place not found", http.StatusNotFound)
+    writeMessage(w, http.StatusOK, "Place deleted.")
+}
+
+// RestorePlace handles undoing the soft delete of a place.
+func (ph *PlaceHandler) RestorePlace(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    idStr := vars["id"]
+    placeID, err := strconv.Atoi(idStr)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    if err := ph.repo(r).RestorePlace(placeID); err != nil {
+        writeError(w, r, err, "Failed to restore place")
+        return
+    }
+
+    writeMessage(w, http.StatusOK, "Place restored.")
+}
+
+// GetOccupancy reports how many taxis are inside each visible place, in total
+// and for the calling operator, optionally limited to one place_id.
+func (ph *PlaceHandler) GetOccupancy(w http.ResponseWriter, r *http.Request) {
+    placeID := 0
+    if v := r.URL.Query().Get("place_id"); v != "" {
+        var err error
+        if placeID, err = strconv.Atoi(v); err != nil || placeID <= 0 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place_id")
             return
         }
-        http.Error(w, "Failed to delete place", http.StatusInternalServerError)
+    }
+
+    report, err := ph.repo(r).GetOccupancyReport(placeID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query occupancy")
         return
     }
 
-    fmt.Fprintf(w, "Place deleted.")
-}
\ No newline at end of file
+    writeJSON(w, http.StatusOK, report)
+}
+
+// IsDryRun reports whether an import request only previews its changes.
+func IsDryRun(r *http.Request) bool {
+    dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+    return dryRun
+}
+
+// maxImportSize caps the size of an uploaded import file.
+const maxImportSize = 32 << 20
+
+// ImportPlaces handles bulk creation and update of places from a GeoJSON,
+// KML or WKT/CSV file, sent either as the raw body or as the "file" field
+// of a multipart form. With dry_run=true nothing is committed.
+func (ph *PlaceHandler) ImportPlaces(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    dryRun := false
+    if v := params.Get("dry_run"); v != "" {
+        var err error
+        if dryRun, err = strconv.ParseBool(v); err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid dry_run")
+            return
+        }
+    }
+
+    r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+    var body io.Reader = r.Body
+    detected := importer.DetectFormat("", r.Header.Get("Content-Type"))
+    if file, header, err := r.FormFile("file"); err == nil {
+        defer file.Close()
+        body = file
+        detected = importer.DetectFormat(header.Filename, header.Header.Get("Content-Type"))
+    }
+
+    format := params.Get("format")
+    if format == "" {
+        format = detected
+    }
+    if format == "" {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Unable to detect import format, set format=geojson|kml|wkt")
+        return
+    }
+
+    records, err := importer.Parse(format, body, params.Get("name_property"))
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidPayload, err.Error())
+        return
+    }
+
+    result, err := ph.repo(r).ImportPlaces(records, dryRun)
+    if err != nil {
+        writeError(w, r, err, "Failed to import places")
+        return
+    }
+
+    status := http.StatusOK
+    if !result.Committed && !result.DryRun {
+        status = http.StatusUnprocessableEntity
+    }
+    writeJSON(w, status, result)
+}
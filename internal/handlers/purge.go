@@ -0,0 +1,36 @@
+// internal/handlers/purge.go
+package handlers
+
+import (
+    "net/http"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+// PurgeHandler handles HTTP requests that remove soft-deleted rows for good.
+type PurgeHandler struct {
+    Repo *repository.Repository
+}
+
+// Purge removes the places and taxi locations visible to the calling operator
+// that were soft-deleted longer ago than retention= (a Go duration such as
+// 720h, 30 days by default), along with the positions of the purged taxis.
+func (ph *PurgeHandler) Purge(w http.ResponseWriter, r *http.Request) {
+    retention := repository.DefaultPurgeRetention
+    if v := r.URL.Query().Get("retention"); v != "" {
+        var err error
+        if retention, err = time.ParseDuration(v); err != nil || retention < 0 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid retention")
+            return
+        }
+    }
+
+    result, err := ph.Repo.ForOperator(operatorOf(r)).Purge(retention)
+    if err != nil {
+        writeError(w, r, err, "Failed to purge deleted rows")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, result)
+}
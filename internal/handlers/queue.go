@@ -0,0 +1,74 @@
+// internal/handlers/queue.go
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/gorilla/mux"
+)
+
+// QueueHandler handles HTTP requests for place queues.
+type QueueHandler struct {
+    Repo *repository.QueueRepository
+}
+
+// repo returns the queue repository scoped to the calling operator.
+func (qh *QueueHandler) repo(r *http.Request) *repository.QueueRepository {
+    return qh.Repo.ForOperator(operatorOf(r))
+}
+
+// GetQueue retrieves the ordered queue of a place.
+func (qh *QueueHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    placeID, err := strconv.Atoi(vars["id"])
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    entries, err := qh.repo(r).GetQueue(placeID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query queue")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, entries)
+}
+
+// DispatchNext pops the taxi at the head of a place's queue.
+func (qh *QueueHandler) DispatchNext(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    placeID, err := strconv.Atoi(vars["id"])
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place ID")
+        return
+    }
+
+    entry, err := qh.repo(r).DispatchHead(placeID)
+    if errors.Is(err, repository.ErrNotFound) {
+        writeProblem(w, r, http.StatusNotFound, CodeQueueEmpty, "Queue is empty")
+        return
+    } else if err != nil {
+        writeError(w, r, err, "Failed to dispatch taxi")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, entry)
+}
+
+// GetTaxiPosition retrieves a taxi's own position in its place's queue.
+func (qh *QueueHandler) GetTaxiPosition(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    taxiID := vars["id"]
+
+    entry, err := qh.repo(r).GetPosition(taxiID)
+    if err != nil {
+        writeError(w, r, err, "Failed to query queue position")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, entry)
+}
@@ -0,0 +1,49 @@
+// internal/handlers/recommendation.go
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+)
+
+// RecommendationHandler handles HTTP requests for rebalancing recommendations.
+type RecommendationHandler struct {
+    Repo *repository.RecommendationRepository
+}
+
+// GetRecommendations retrieves a page of the moves proposed after the last
+// scheduler run, nearest first, optionally filtered by taxi_id=,
+// from_place_id= and to_place_id=.
+func (rh *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    opts, err := parseListOptions(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    filter := repository.RecommendationFilter{TaxiID: params.Get("taxi_id")}
+    for name, dst := range map[string]*int{"from_place_id": &filter.FromPlaceID, "to_place_id": &filter.ToPlaceID} {
+        v := params.Get(name)
+        if v == "" {
+            continue
+        }
+        placeID, err := strconv.Atoi(v)
+        if err != nil || placeID <= 0 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid "+name)
+            return
+        }
+        *dst = placeID
+    }
+
+    recs, next, err := rh.Repo.ForOperator(operatorOf(r)).GetRecommendations(filter, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query recommendations")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: recs, NextCursor: next})
+}
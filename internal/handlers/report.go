@@ -0,0 +1,150 @@
+// internal/handlers/report.go
+package handlers
+
+import (
+    "encoding/csv"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/reports"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+)
+
+// csvContentType is the media type of CSV reports.
+const csvContentType = "text/csv"
+
+// ReportHandler handles HTTP requests for analytics reports.
+type ReportHandler struct {
+    Dwell *repository.DwellRepository
+}
+
+// wantsCSV reports whether the client asked for CSV, with format=csv or an
+// Accept header.
+func wantsCSV(r *http.Request) bool {
+    if r.URL.Query().Get("format") == "csv" {
+        return true
+    }
+    return strings.Contains(r.Header.Get("Accept"), csvContentType)
+}
+
+// parseDwellFilter parses place_id=, taxi_id=, from= and to= (RFC 3339),
+// hours=H-H (hours of the day a session starts in, e.g. 6-9 or 22-6), tz=
+// (the IANA time zone those hours and grouping by hour are taken in, UTC by
+// default) and include_open= from a query string.
+func parseDwellFilter(params url.Values) (repository.DwellFilter, error) {
+    var filter repository.DwellFilter
+
+    if v := params.Get("place_id"); v != "" {
+        placeID, err := strconv.Atoi(v)
+        if err != nil || placeID <= 0 {
+            return filter, fmt.Errorf("Invalid place_id")
+        }
+        filter.PlaceID = placeID
+    }
+
+    if v := params.Get("taxi_id"); v != "" {
+        if !validation.IsID(v) {
+            return filter, fmt.Errorf("Invalid taxi_id")
+        }
+        filter.TaxiID = v
+    }
+
+    for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+        if v := params.Get(name); v != "" {
+            t, err := time.Parse(time.RFC3339, v)
+            if err != nil {
+                return filter, fmt.Errorf("Invalid %s", name)
+            }
+            *dst = &t
+        }
+    }
+
+    if v := params.Get("hours"); v != "" {
+        bounds := strings.SplitN(v, "-", 2)
+        if len(bounds) != 2 {
+            return filter, fmt.Errorf("Invalid hours")
+        }
+        from, err1 := strconv.Atoi(bounds[0])
+        to, err2 := strconv.Atoi(bounds[1])
+        if err1 != nil || err2 != nil || from < 0 || from > 23 || to < 0 || to > 24 {
+            return filter, fmt.Errorf("Invalid hours")
+        }
+        filter.HourFrom, filter.HourTo = from, to%24
+    }
+
+    if v := params.Get("tz"); v != "" {
+        // Local is the server's zone, which the database knows nothing of
+        if _, err := time.LoadLocation(v); err != nil || v == "Local" {
+            return filter, fmt.Errorf("Invalid tz")
+        }
+        filter.TimeZone = v
+    }
+
+    if v := params.Get("include_open"); v != "" {
+        includeOpen, err := strconv.ParseBool(v)
+        if err != nil {
+            return filter, fmt.Errorf("Invalid include_open")
+        }
+        filter.IncludeOpen = includeOpen
+    }
+
+    return filter, nil
+}
+
+// GetDwellReport reports count, average, p50, p90, p99 and maximum dwell time
+// in seconds, grouped by group_by=place (default), taxi or hour. It answers
+// in CSV with format=csv or Accept: text/csv, and in JSON otherwise.
+func (rh *ReportHandler) GetDwellReport(w http.ResponseWriter, r *http.Request) {
+    groupBy := r.URL.Query().Get("group_by")
+    if groupBy == "" {
+        groupBy = reports.GroupByPlace
+    }
+    if !reports.ValidGroupBy(groupBy) {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid group_by, expected place, taxi or hour")
+        return
+    }
+
+    filter, err := parseDwellFilter(r.URL.Query())
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    report, err := rh.Dwell.ForOperator(operatorOf(r)).GetDwellReport(filter, groupBy)
+    if err != nil {
+        writeError(w, r, err, "Failed to compute dwell report")
+        return
+    }
+
+    if !wantsCSV(r) {
+        writeJSON(w, http.StatusOK, report)
+        return
+    }
+
+    w.Header().Set("Content-Type", csvContentType)
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "dwell-by-"+groupBy+".csv"))
+    out := csv.NewWriter(w)
+    out.Write([]string{groupBy, "count", "avg_seconds", "p50_seconds", "p90_seconds", "p99_seconds", "max_seconds"})
+    for _, stat := range report.Stats {
+        out.Write([]string{
+            stat.Group,
+            strconv.Itoa(stat.Count),
+            formatSeconds(stat.AvgSeconds),
+            formatSeconds(stat.P50Seconds),
+            formatSeconds(stat.P90Seconds),
+            formatSeconds(stat.P99Seconds),
+            formatSeconds(stat.MaxSeconds),
+        })
+    }
+    out.Flush()
+}
+
+// formatSeconds formats a duration in seconds for CSV output.
+func formatSeconds(seconds float64) string {
+    return strconv.FormatFloat(seconds, 'f', 1, 64)
+}
@@ -0,0 +1,88 @@
+// internal/handlers/report_test.go
+package handlers
+
+import (
+    "net/url"
+    "testing"
+    "time"
+)
+
+func TestParseDwellFilterHours(t *testing.T) {
+    tests := []struct {
+        hours    string
+        from, to int
+        ok       bool
+    }{
+        {"6-9", 6, 9, true},
+        {"22-6", 22, 6, true},
+        {"23-0", 23, 0, true},
+        {"22-24", 22, 0, true},
+        {"0-24", 0, 0, true},
+        {"5-5", 5, 5, true},
+        {"24-1", 0, 0, false},
+        {"-1-3", 0, 0, false},
+        {"3-25", 0, 0, false},
+        {"6", 0, 0, false},
+        {"a-b", 0, 0, false},
+        {"6-", 0, 0, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.hours, func(t *testing.T) {
+            filter, err := parseDwellFilter(url.Values{"hours": {tt.hours}})
+            if !tt.ok {
+                if err == nil {
+                    t.Errorf("got %d-%d, want an error", filter.HourFrom, filter.HourTo)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if filter.HourFrom != tt.from || filter.HourTo != tt.to {
+                t.Errorf("got %d-%d, want %d-%d", filter.HourFrom, filter.HourTo, tt.from, tt.to)
+            }
+        })
+    }
+}
+
+func TestParseDwellFilter(t *testing.T) {
+    filter, err := parseDwellFilter(url.Values{
+        "place_id":     {"7"},
+        "taxi_id":      {"T-1"},
+        "from":         {"2026-03-01T00:00:00+07:00"},
+        "to":           {"2026-03-02T00:00:00Z"},
+        "include_open": {"true"},
+        "tz":           {"Asia/Jakarta"},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if filter.PlaceID != 7 || filter.TaxiID != "T-1" || !filter.IncludeOpen || filter.TimeZone != "Asia/Jakarta" {
+        t.Errorf("got %+v", filter)
+    }
+    if filter.From == nil || !filter.From.Equal(time.Date(2026, 2, 28, 17, 0, 0, 0, time.UTC)) {
+        t.Errorf("from = %v", filter.From)
+    }
+    if filter.To == nil || !filter.To.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+        t.Errorf("to = %v", filter.To)
+    }
+    if filter, _ := parseDwellFilter(url.Values{}); filter.TimeZone != "" {
+        t.Errorf("default tz = %q, want UTC", filter.TimeZone)
+    }
+    if _, err := parseDwellFilter(url.Values{"tz": {"Local"}}); err == nil {
+        t.Errorf("tz=Local accepted")
+    }
+
+    for name, value := range map[string]string{
+        "place_id":     "0",
+        "taxi_id":      "not an id",
+        "from":         "yesterday",
+        "to":           "2026-03-02",
+        "include_open": "maybe",
+        "tz":           "Mars/Olympus_Mons",
+    } {
+        if _, err := parseDwellFilter(url.Values{name: {value}}); err == nil {
+            t.Errorf("%s=%s accepted", name, value)
+        }
+    }
+}
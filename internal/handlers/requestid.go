@@ -0,0 +1,37 @@
+// internal/handlers/requestid.go
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+)
+
+// RequestIDHeader carries the ID of a request in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID is middleware that tags every request with an ID, keeping a
+// well-formed X-Request-ID sent by the client and generating one otherwise.
+// The ID is echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(RequestIDHeader)
+        if !validation.IsID(id) {
+            b := make([]byte, 16)
+            rand.Read(b)
+            id = hex.EncodeToString(b)
+        }
+
+        w.Header().Set(RequestIDHeader, id)
+        next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+    })
+}
+
+// requestIDOf returns the ID of a request, or "" outside RequestID.
+func requestIDOf(r *http.Request) string {
+    id, _ := r.Context().Value(requestIDKey).(string)
+    return id
+}
@@ -2,33 +2,48 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-
-	"database/sql"
+	"strconv"
 
 	"github.com/SangBejoo/parking-space-monitor/internal/models"
 	"github.com/SangBejoo/parking-space-monitor/internal/repository"
+	"github.com/SangBejoo/parking-space-monitor/internal/validation"
 	"github.com/gorilla/mux"
 )
 
 // TaxiHandler handles HTTP requests for Taxi operations.
 type TaxiHandler struct {
-	Repo *repository.TaxiRepository
+	Repo     *repository.TaxiRepository
+	Registry *repository.TaxiRegistryRepository
+}
+
+// repo returns the taxi repository scoped to the calling operator.
+func (th *TaxiHandler) repo(r *http.Request) *repository.TaxiRepository {
+	return th.Repo.ForOperator(operatorOf(r))
 }
 
 // CreateTaxi handles the creation of a new taxi.
 func (th *TaxiHandler) CreateTaxi(w http.ResponseWriter, r *http.Request) {
 	var location models.TaxiLocation
-	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &location) || !checkTaxiAccess(w, r, location.TaxiID) {
 		return
 	}
 
-	if err := th.Repo.CreateTaxi(location); err != nil {
-		http.Error(w, "Failed to create taxi location", http.StatusInternalServerError)
+	registered, err := th.Registry.ForOperator(operatorOf(r)).IsRegistered(location.TaxiID)
+	if err != nil {
+		writeError(w, r, err, "Failed to check taxi registration")
+		return
+	}
+	if !registered {
+		writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, []validation.FieldError{
+			{Field: "taxi_id", Code: "not_found", Message: "taxi is not registered"},
+		})
+		return
+	}
+
+	if err := th.repo(r).CreateTaxi(location); err != nil {
+		writeError(w, r, err, "Failed to create taxi location")
 		return
 	}
 
@@ -38,20 +53,69 @@ func (th *TaxiHandler) CreateTaxi(w http.ResponseWriter, r *http.Request) {
 		"latitude":  location.Latitude,
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusCreated, response)
 }
 
-// GetAllTaxis retrieves all taxis.
+// GetAllTaxis retrieves a page of taxis, optionally filtered by bbox,
+// near/radius_m, place_id or status (online, stale or offline). Soft-deleted
+// locations are only listed with include_deleted=true.
 func (th *TaxiHandler) GetAllTaxis(w http.ResponseWriter, r *http.Request) {
-	taxis, err := th.Repo.GetAllTaxis()
+	sp, err := parseSpatialParams(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	includeDeleted, err := parseIncludeDeleted(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Failed to query taxi locations", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != models.TaxiOnline && status != models.TaxiStale && status != models.TaxiOffline {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid status, expected online, stale or offline")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(taxis)
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	taxis, next, err := th.repo(r).GetAllTaxis(repository.TaxiFilter{
+		BBox:    sp.BBox,
+		Near:    sp.Near,
+		RadiusM: sp.RadiusM,
+		PlaceID: sp.PlaceID,
+
+		IncludeDeleted: includeDeleted,
+		Status:         status,
+	}, opts)
+	if err != nil {
+		writeError(w, r, err, "Failed to query taxi locations")
+		return
+	}
+
+	if wantsGeoJSON(r) {
+		currentPlaces, err := th.repo(r).GetCurrentPlaces()
+		if err != nil {
+			writeError(w, r, err, "Failed to query taxi places")
+			return
+		}
+
+		features := make([]models.Feature, 0, len(taxis))
+		for _, taxi := range taxis {
+			features = append(features, taxiFeature(taxi, currentPlaces[taxi.TaxiID]))
+		}
+		collection := models.NewFeatureCollection(features)
+		collection.NextCursor = next
+		writeGeoJSON(w, http.StatusOK, collection)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Data: taxis, NextCursor: next})
 }
 
 // GetTaxi retrieves a single taxi by ID.
@@ -59,40 +123,61 @@ func (th *TaxiHandler) GetTaxi(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	taxiID := vars["id"]
 
-	taxi, err := th.Repo.GetTaxiByID(taxiID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Taxi not found", http.StatusNotFound)
+	taxi, err := th.repo(r).GetTaxiByID(taxiID)
+	if err != nil {
+		writeError(w, r, err, "Failed to query taxi location")
 		return
-	} else if err != nil {
-		http.Error(w, "Failed to query taxi location", http.StatusInternalServerError)
+	}
+
+	if wantsGeoJSON(r) {
+		currentPlaces, err := th.repo(r).GetCurrentPlaces()
+		if err != nil {
+			writeError(w, r, err, "Failed to query taxi places")
+			return
+		}
+		writeGeoJSON(w, http.StatusOK, taxiFeature(*taxi, currentPlaces[taxi.TaxiID]))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(taxi)
+	writeJSON(w, http.StatusOK, taxi)
+}
+
+// LoadTaxi returns a taxi location for the audit log.
+func (th *TaxiHandler) LoadTaxi(r *http.Request, id string) (interface{}, error) {
+	return th.repo(r).GetTaxiByID(id)
 }
 
 // UpdateTaxi handles updating an existing taxi.
 func (th *TaxiHandler) UpdateTaxi(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	taxiID := vars["id"]
+	if !checkTaxiAccess(w, r, taxiID) {
+		return
+	}
 
 	var location models.TaxiLocation
-	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeBody(w, r, &location) {
 		return
 	}
 
-	if err := th.Repo.UpdateTaxi(taxiID, location); err != nil {
-		if err.Error() == "taxi not found" {
-			http.Error(w, "Taxi not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to update taxi location", http.StatusInternalServerError)
+	// The path names the taxi; a taxi_id in the body may only repeat it
+	if location.TaxiID != "" && location.TaxiID != taxiID {
+		writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, []validation.FieldError{
+			{Field: "taxi_id", Code: "mismatch", Message: "does not match the taxi in the path"},
+		})
+		return
+	}
+	location.TaxiID = taxiID
+	if !validate(w, r, &location) {
+		return
+	}
+
+	if err := th.repo(r).UpdateTaxi(taxiID, location); err != nil {
+		writeError(w, r, err, "Failed to update taxi location")
 		return
 	}
 
-	fmt.Fprintf(w, "Taxi location updated.")
+	writeMessage(w, http.StatusOK, "Taxi location updated.")
 }
 
 // DeleteTaxi handles deleting a taxi by ID.
@@ -102,17 +187,91 @@ func (th *TaxiHandler) DeleteTaxi(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Received DELETE request for Taxi ID: %s", taxiID)
 
-	if err := th.Repo.DeleteTaxi(taxiID); err != nil {
+	if err := th.repo(r).DeleteTaxi(taxiID); err != nil {
 		log.Printf("Error deleting Taxi ID %s: %v", taxiID, err)
-		if err.Error() == "taxi not found" {
-			http.Error(w, "Taxi not found", http.StatusNotFound)
+		writeError(w, r, err, "Failed to delete taxi location")
+		return
+	}
+
+	log.Printf("Successfully deleted Taxi ID: %s", taxiID)
+	writeMessage(w, http.StatusOK, "Taxi location deleted.")
+}
+
+// RestoreTaxi handles undoing the soft delete of a taxi location.
+func (th *TaxiHandler) RestoreTaxi(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taxiID := vars["id"]
+
+	if err := th.repo(r).RestoreTaxi(taxiID); err != nil {
+		writeError(w, r, err, "Failed to restore taxi location")
+		return
+	}
+
+	writeMessage(w, http.StatusOK, "Taxi location restored.")
+}
+
+// maxNearestLimit caps how many taxis a single nearest lookup may return.
+const maxNearestLimit = 100
+
+// GetNearestTaxis retrieves the taxis closest to a point.
+func (th *TaxiHandler) GetNearestTaxis(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	lat, err := strconv.ParseFloat(params.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid lat")
+		return
+	}
+	lon, err := strconv.ParseFloat(params.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid lon")
+		return
+	}
+
+	query := repository.NearestQuery{Latitude: lat, Longitude: lon, Limit: 10}
+
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxNearestLimit {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid limit")
+			return
+		}
+		query.Limit = limit
+	}
+	if v := params.Get("max_km"); v != "" {
+		maxKm, err := strconv.ParseFloat(v, 64)
+		if err != nil || maxKm <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid max_km")
+			return
+		}
+		query.MaxKm = maxKm
+	}
+	if v := params.Get("place_id"); v != "" {
+		placeID, err := strconv.Atoi(v)
+		if err != nil || placeID <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place_id")
 			return
 		}
-		http.Error(w, "Failed to delete taxi location", http.StatusInternalServerError)
+		query.PlaceID = placeID
+	}
+	if v := params.Get("exclude_mapped"); v != "" {
+		excludeMapped, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid exclude_mapped")
+			return
+		}
+		query.ExcludeMapped = excludeMapped
+	}
+	if query.PlaceID > 0 && query.ExcludeMapped {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "place_id and exclude_mapped cannot be combined")
 		return
 	}
 
-	log.Printf("Successfully deleted Taxi ID: %s", taxiID)
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Taxi location deleted.")
-}
\ No newline at end of file
+	taxis, err := th.repo(r).FindNearest(query)
+	if err != nil {
+		writeError(w, r, err, "Failed to query nearest taxis")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, taxis)
+}
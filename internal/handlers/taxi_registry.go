@@ -0,0 +1,110 @@
+// internal/handlers/taxi_registry.go
+package handlers
+
+import (
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+    "github.com/gorilla/mux"
+)
+
+// TaxiRegistryHandler handles HTTP requests for the taxi registry.
+type TaxiRegistryHandler struct {
+    Repo *repository.TaxiRegistryRepository
+}
+
+// repo returns the registry repository scoped to the calling operator.
+func (rh *TaxiRegistryHandler) repo(r *http.Request) *repository.TaxiRegistryRepository {
+    return rh.Repo.ForOperator(operatorOf(r))
+}
+
+// RegisterTaxi handles registering a new taxi.
+func (rh *TaxiRegistryHandler) RegisterTaxi(w http.ResponseWriter, r *http.Request) {
+    var taxi models.Taxi
+    if !decodeJSON(w, r, &taxi) || !checkOperator(w, r, taxi.OperatorID) {
+        return
+    }
+
+    created, err := rh.repo(r).RegisterTaxi(taxi)
+    if err != nil {
+        writeError(w, r, err, "Failed to register taxi")
+        return
+    }
+
+    writeJSON(w, http.StatusCreated, created)
+}
+
+// GetAllRegisteredTaxis retrieves a page of registered taxis.
+func (rh *TaxiRegistryHandler) GetAllRegisteredTaxis(w http.ResponseWriter, r *http.Request) {
+    opts, err := parseListOptions(r.URL.Query())
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    taxis, next, err := rh.repo(r).GetAllRegisteredTaxis(opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query taxis")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: taxis, NextCursor: next})
+}
+
+// GetRegisteredTaxi retrieves a single registered taxi by ID.
+func (rh *TaxiRegistryHandler) GetRegisteredTaxi(w http.ResponseWriter, r *http.Request) {
+    taxi, err := rh.repo(r).GetRegisteredTaxi(mux.Vars(r)["id"])
+    if err != nil {
+        writeError(w, r, err, "Failed to query taxi")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, taxi)
+}
+
+// LoadRegisteredTaxi returns a registered taxi for the audit log.
+func (rh *TaxiRegistryHandler) LoadRegisteredTaxi(r *http.Request, id string) (interface{}, error) {
+    return rh.repo(r).GetRegisteredTaxi(id)
+}
+
+// UpdateRegisteredTaxi handles updating the registry details of a taxi.
+func (rh *TaxiRegistryHandler) UpdateRegisteredTaxi(w http.ResponseWriter, r *http.Request) {
+    taxiID := mux.Vars(r)["id"]
+
+    var taxi models.Taxi
+    if !decodeBody(w, r, &taxi) || !checkOperator(w, r, taxi.OperatorID) {
+        return
+    }
+
+    // The path names the taxi; a taxi_id in the body may only repeat it
+    if taxi.TaxiID != "" && taxi.TaxiID != taxiID {
+        writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, []validation.FieldError{
+            {Field: "taxi_id", Code: "mismatch", Message: "does not match the taxi in the path"},
+        })
+        return
+    }
+    taxi.TaxiID = taxiID
+    if !validate(w, r, &taxi) {
+        return
+    }
+
+    updated, err := rh.repo(r).UpdateRegisteredTaxi(taxi)
+    if err != nil {
+        writeError(w, r, err, "Failed to update taxi")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteRegisteredTaxi handles removing a taxi from the registry.
+func (rh *TaxiRegistryHandler) DeleteRegisteredTaxi(w http.ResponseWriter, r *http.Request) {
+    if err := rh.repo(r).DeleteRegisteredTaxi(mux.Vars(r)["id"]); err != nil {
+        writeError(w, r, err, "Failed to delete taxi")
+        return
+    }
+
+    writeMessage(w, http.StatusOK, "Taxi deregistered.")
+}
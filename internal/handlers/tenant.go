@@ -0,0 +1,29 @@
+// internal/handlers/tenant.go
+package handlers
+
+import (
+    "net/http"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/validation"
+)
+
+// operatorOf returns the operator the caller of a request belongs to. It is
+// empty for platform admins, whose repositories are unscoped.
+func operatorOf(r *http.Request) string {
+    if principal := principalOf(r); principal != nil {
+        return principal.Operator
+    }
+    return ""
+}
+
+// checkOperator rejects a payload naming another operator than the caller.
+// Platform admins may name any operator.
+func checkOperator(w http.ResponseWriter, r *http.Request, operatorID string) bool {
+    if caller := operatorOf(r); caller != "" && operatorID != "" && operatorID != caller {
+        writeFieldErrors(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, []validation.FieldError{
+            {Field: "operator_id", Code: "mismatch", Message: "does not match the calling operator"},
+        })
+        return false
+    }
+    return true
+}
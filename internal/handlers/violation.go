@@ -0,0 +1,179 @@
+// internal/handlers/violation.go
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/repository"
+    "github.com/gorilla/mux"
+)
+
+// ViolationHandler handles HTTP requests for rule violations.
+type ViolationHandler struct {
+    Repo   *repository.ViolationRepository
+    Places *repository.PlaceRepository
+}
+
+// repo returns the violation repository scoped to the calling operator.
+func (vh *ViolationHandler) repo(r *http.Request) *repository.ViolationRepository {
+    return vh.Repo.ForOperator(operatorOf(r))
+}
+
+// violationID parses the violation ID in the path, writing a problem when it
+// is invalid.
+func violationID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+    id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+    if err != nil || id <= 0 {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid violation ID")
+        return 0, false
+    }
+    return id, true
+}
+
+// GetViolations retrieves a page of violations, optionally filtered by
+// status=, rule=, place_id=, taxi_id= and since= (RFC 3339).
+func (vh *ViolationHandler) GetViolations(w http.ResponseWriter, r *http.Request) {
+    params := r.URL.Query()
+
+    opts, err := parseListOptions(params)
+    if err != nil {
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+        return
+    }
+
+    filter := repository.ViolationFilter{Status: params.Get("status"), Rule: params.Get("rule"), TaxiID: params.Get("taxi_id")}
+    switch filter.Status {
+    case "", models.ViolationOpen, models.ViolationClosed, models.ViolationDisputed, models.ViolationResolved:
+    default:
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid status, expected open, closed, disputed or resolved")
+        return
+    }
+    if v := params.Get("place_id"); v != "" {
+        placeID, err := strconv.Atoi(v)
+        if err != nil || placeID <= 0 {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid place_id")
+            return
+        }
+        filter.PlaceID = placeID
+    }
+    if v := params.Get("since"); v != "" {
+        since, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid since")
+            return
+        }
+        filter.Since = &since
+    }
+
+    violations, next, err := vh.repo(r).GetViolations(filter, opts)
+    if err != nil {
+        writeError(w, r, err, "Failed to query violations")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, listResponse{Data: violations, NextCursor: next})
+}
+
+// GetViolation retrieves a single violation with its evidence.
+func (vh *ViolationHandler) GetViolation(w http.ResponseWriter, r *http.Request) {
+    id, ok := violationID(w, r)
+    if !ok {
+        return
+    }
+
+    violation, err := vh.repo(r).GetViolation(id)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve violation")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, violation)
+}
+
+// LoadViolation returns a violation for the audit log.
+func (vh *ViolationHandler) LoadViolation(r *http.Request, id string) (interface{}, error) {
+    violationID, err := strconv.ParseInt(id, 10, 64)
+    if err != nil {
+        return nil, err
+    }
+    return vh.repo(r).GetViolation(violationID)
+}
+
+// DisputeViolation marks a violation as disputed. Drivers may only dispute
+// the violations of their own taxi.
+func (vh *ViolationHandler) DisputeViolation(w http.ResponseWriter, r *http.Request) {
+    id, ok := violationID(w, r)
+    if !ok {
+        return
+    }
+
+    var dispute models.ViolationDispute
+    if !decodeJSON(w, r, &dispute) {
+        return
+    }
+
+    violation, err := vh.repo(r).GetViolation(id)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve violation")
+        return
+    }
+    if !checkTaxiAccess(w, r, violation.TaxiID) {
+        return
+    }
+
+    if err := vh.repo(r).DisputeViolation(id, actorOf(r), dispute.Reason); err != nil {
+        writeError(w, r, err, "Failed to dispute violation")
+        return
+    }
+
+    writeMessage(w, http.StatusOK, "Violation disputed.")
+}
+
+// ResolveViolation records whether a closed or disputed violation is upheld
+// or dismissed.
+func (vh *ViolationHandler) ResolveViolation(w http.ResponseWriter, r *http.Request) {
+    id, ok := violationID(w, r)
+    if !ok {
+        return
+    }
+
+    var resolution models.ViolationResolution
+    if !decodeJSON(w, r, &resolution) {
+        return
+    }
+
+    if err := vh.repo(r).ResolveViolation(id, actorOf(r), resolution.Resolution, resolution.Note); err != nil {
+        writeError(w, r, err, "Failed to resolve violation")
+        return
+    }
+
+    writeMessage(w, http.StatusOK, "Violation resolved.")
+}
+
+// ExportViolation writes a violation as a GeoJSON FeatureCollection of its
+// place, as it was when the violation was detected, and the position trail
+// of the taxi, for enforcement reporting.
+func (vh *ViolationHandler) ExportViolation(w http.ResponseWriter, r *http.Request) {
+    id, ok := violationID(w, r)
+    if !ok {
+        return
+    }
+
+    violation, err := vh.repo(r).GetViolation(id)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve violation")
+        return
+    }
+    version, err := vh.Places.ForOperator(operatorOf(r)).GetPlaceAt(violation.PlaceID, violation.DetectedAt)
+    if err != nil {
+        writeError(w, r, err, "Failed to retrieve violation place")
+        return
+    }
+
+    w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="violation-%d.geojson"`, violation.ID))
+    writeGeoJSON(w, http.StatusOK, models.NewFeatureCollection(violationFeatures(*violation, version.Place())))
+}
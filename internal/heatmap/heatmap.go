@@ -0,0 +1,117 @@
+// internal/heatmap/heatmap.go
+package heatmap
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// ErrTooManyCells is returned when the positions fall into more cells than
+// the heatmap may hold; a coarser precision or a smaller area helps.
+var ErrTooManyCells = errors.New("too many cells")
+
+// Binner assigns a coordinate to a cell, returning the cell key and bounds.
+type Binner interface {
+    Cell(lat, lon float64) (string, geo.BBox)
+}
+
+// Geohash bins coordinates into geohash cells of Precision characters.
+type Geohash struct {
+    Precision int
+}
+
+// Cell implements Binner.
+func (g Geohash) Cell(lat, lon float64) (string, geo.BBox) {
+    hash := geo.Geohash(lat, lon, g.Precision)
+    return hash, geo.GeohashBounds(hash)
+}
+
+// Grid bins coordinates into cells about CellM metres on each side. Rows are
+// CellM high everywhere but at the poles, where they end. Each row is cut
+// into as many equal columns as fit CellM wide at its centre latitude, so
+// cells stay close to square away from the equator and never cross the
+// antimeridian; rows around a pole too short for two make up a single cell.
+type Grid struct {
+    CellM float64
+}
+
+// Cell implements Binner. Keys are "row:column", rows counted from the
+// equator and columns from the antimeridian.
+func (g Grid) Cell(lat, lon float64) (string, geo.BBox) {
+    kmPerDegree := geo.EarthRadiusKm * math.Pi / 180
+    height := g.CellM / 1000 / kmPerDegree
+    row := math.Floor(lat / height)
+
+    minLat, maxLat := math.Max(row*height, -90), math.Min((row+1)*height, 90)
+    columns := math.Max(1, math.Floor(360*math.Cos((minLat+maxLat)/2*math.Pi/180)/height))
+    width := 360 / columns
+    col := math.Min(math.Floor((lon+180)/width), columns-1)
+
+    box := geo.BBox{
+        MinLon: -180 + col*width,
+        MinLat: minLat,
+        MaxLon: -180 + (col+1)*width,
+        MaxLat: maxLat,
+    }
+    return fmt.Sprintf("%d:%d", int64(row), int64(col)), box
+}
+
+// cell accumulates the positions of one cell.
+type cell struct {
+    bounds geo.BBox
+    count  int
+    taxis  map[string]bool
+}
+
+// Heatmap counts positions per cell.
+type Heatmap struct {
+    binner   Binner
+    maxCells int
+    cells    map[string]*cell
+}
+
+// New returns an empty heatmap holding at most maxCells cells, or any number
+// when maxCells is 0.
+func New(binner Binner, maxCells int) *Heatmap {
+    return &Heatmap{binner: binner, maxCells: maxCells, cells: map[string]*cell{}}
+}
+
+// Add counts a position of a taxi in its cell.
+func (h *Heatmap) Add(taxiID string, lat, lon float64) error {
+    key, bounds := h.binner.Cell(lat, lon)
+    c, ok := h.cells[key]
+    if !ok {
+        if h.maxCells > 0 && len(h.cells) >= h.maxCells {
+            return ErrTooManyCells
+        }
+        c = &cell{bounds: bounds, taxis: map[string]bool{}}
+        h.cells[key] = c
+    }
+    c.count++
+    c.taxis[taxiID] = true
+    return nil
+}
+
+// Cells returns the cells holding at least minCount positions, densest
+// first and then by key.
+func (h *Heatmap) Cells(minCount int) []models.HeatmapCell {
+    cells := []models.HeatmapCell{}
+    for key, c := range h.cells {
+        if c.count < minCount {
+            continue
+        }
+        cells = append(cells, models.HeatmapCell{Cell: key, Bounds: c.bounds, Count: c.count, Taxis: len(c.taxis)})
+    }
+    sort.Slice(cells, func(i, j int) bool {
+        if cells[i].Count != cells[j].Count {
+            return cells[i].Count > cells[j].Count
+        }
+        return cells[i].Cell < cells[j].Cell
+    })
+    return cells
+}
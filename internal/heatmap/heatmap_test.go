@@ -0,0 +1,199 @@
+// internal/heatmap/heatmap_test.go
+package heatmap
+
+import (
+    "math"
+    "testing"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+)
+
+// metres returns the height and the width at its centre of a box.
+func metres(box geo.BBox) (height, width float64) {
+    mPerDegree := geo.EarthRadiusKm * math.Pi / 180 * 1000
+    centre := (box.MinLat + box.MaxLat) / 2
+    return (box.MaxLat - box.MinLat) * mPerDegree, (box.MaxLon - box.MinLon) * mPerDegree * math.Cos(centre*math.Pi/180)
+}
+
+func TestGridCells(t *testing.T) {
+    grid := Grid{CellM: 500}
+    tests := []struct {
+        name     string
+        lat, lon float64
+        key      string // checked when set
+    }{
+        {"antimeridian", 0, -180, "0:0"},
+        {"just south of the equator", -0.000001, -179.999999, "-1:0"},
+        {"other side of the antimeridian", 0, 180, ""},
+        {"origin", 0, 0, ""},
+        {"southern and western hemispheres", -6.2088, -106.8456, ""},
+        {"eastern hemisphere", -33.8688, 151.2093, ""},
+        {"near the north pole", 89.999, 45, ""},
+        {"near the south pole", -89.999, -135, ""},
+        {"north pole", 90, 0, ""},
+        {"south pole", -90, 0, ""},
+        {"very near the north pole", 89.99999, 10, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            key, box := grid.Cell(tt.lat, tt.lon)
+            if tt.key != "" && key != tt.key {
+                t.Errorf("key = %q, want %q", key, tt.key)
+            }
+            if !box.Contains(tt.lon, tt.lat) {
+                t.Errorf("cell %s %+v does not contain %v, %v", key, box, tt.lat, tt.lon)
+            }
+            for _, v := range []float64{box.MinLat, box.MaxLat, box.MinLon, box.MaxLon} {
+                if math.IsNaN(v) || math.IsInf(v, 0) {
+                    t.Fatalf("cell %s has invalid bounds %+v", key, box)
+                }
+            }
+
+            if box.MinLat < -90 || box.MaxLat > 90 || box.MinLon < -180 || box.MaxLon > 180.000001 {
+                t.Errorf("cell %s %+v is off the globe", key, box)
+            }
+
+            // Only the rows that reach a pole are cut short
+            height, width := metres(box)
+            if height > grid.CellM+0.01 || (math.Abs(tt.lat) < 89 && math.Abs(height-grid.CellM) > 0.01) {
+                t.Errorf("cell is %.2f m high, want %v", height, grid.CellM)
+            }
+            // Columns are at least CellM wide at the centre latitude of their
+            // row, and barely wider away from the poles, unless the whole row
+            // is one cell
+            if box.MaxLon-box.MinLon < 360 && (width < grid.CellM-0.01 || width >= 2*grid.CellM) {
+                t.Errorf("cell is %.2f m wide, want about %v", width, grid.CellM)
+            }
+            if math.Abs(tt.lat) < 89 && math.Abs(width-grid.CellM) > 0.01*grid.CellM {
+                t.Errorf("cell is %.2f m wide, want %v", width, grid.CellM)
+            }
+        })
+    }
+}
+
+// The rows reaching the poles end there, and each is still cut into whole
+// columns around the globe.
+func TestGridPoles(t *testing.T) {
+    for _, cellM := range []float64{100, 500, 1000, 5000, 50000} {
+        for _, lat := range []float64{90, -90} {
+            grid := Grid{CellM: cellM}
+            _, box := grid.Cell(lat, 0)
+            if box.MaxLat > 90 || box.MinLat < -90 || box.MinLon < -180 || box.MaxLon > 180.000001 {
+                t.Errorf("%v m cell at %v is off the globe: %+v", cellM, lat, box)
+            }
+            columns := 360 / (box.MaxLon - box.MinLon)
+            if math.Abs(columns-math.Round(columns)) > 1e-6 {
+                t.Errorf("%v m cell at %v does not tile its row: %+v", cellM, lat, box)
+            }
+        }
+    }
+}
+
+// The same coordinate always lands in the same cell, and its neighbour one
+// cell away in another.
+func TestGridNeighbours(t *testing.T) {
+    grid := Grid{CellM: 1000}
+    for _, p := range [][2]float64{{-6.2, 106.8}, {-6.2, -106.8}, {60.1, -150.3}, {-75.5, 20.2}} {
+        key, box := grid.Cell(p[0], p[1])
+        if again, _ := grid.Cell(p[0], p[1]); again != key {
+            t.Errorf("%v binned to %q and %q", p, key, again)
+        }
+        north, _ := grid.Cell(box.MaxLat+(box.MaxLat-box.MinLat)/2, p[1])
+        east, _ := grid.Cell(p[0], box.MaxLon+(box.MaxLon-box.MinLon)/2)
+        if north == key || east == key || north == east {
+            t.Errorf("%v: cell %q, north %q, east %q", p, key, north, east)
+        }
+    }
+}
+
+func TestHeatmapCap(t *testing.T) {
+    h := New(Geohash{Precision: 5}, 2)
+    if err := h.Add("T1", -6.2, 106.8); err != nil {
+        t.Fatal(err)
+    }
+    if err := h.Add("T2", 51.5, -0.12); err != nil {
+        t.Fatal(err)
+    }
+    if err := h.Add("T3", 40.7, -74); err != ErrTooManyCells {
+        t.Fatalf("got %v, want ErrTooManyCells", err)
+    }
+    // Cells already held keep counting past the cap
+    if err := h.Add("T3", -6.2, 106.8); err != nil {
+        t.Fatalf("adding to an existing cell: %v", err)
+    }
+    if cells := h.Cells(0); len(cells) != 2 {
+        t.Errorf("got %d cells, want 2", len(cells))
+    }
+
+    unlimited := New(Geohash{Precision: 5}, 0)
+    for i := 0; i < 100; i++ {
+        if err := unlimited.Add("T1", float64(i)-50, float64(i)); err != nil {
+            t.Fatalf("unlimited heatmap: %v", err)
+        }
+    }
+    if cells := unlimited.Cells(0); len(cells) != 100 {
+        t.Errorf("got %d cells, want 100", len(cells))
+    }
+}
+
+func TestHeatmapCells(t *testing.T) {
+    h := New(Geohash{Precision: 6}, 0)
+    positions := []struct {
+        taxi     string
+        lat, lon float64
+    }{
+        {"T1", -6.2000, 106.8000},
+        {"T1", -6.2001, 106.8001},
+        {"T2", -6.2000, 106.8000},
+        {"T3", 51.5, -0.12},
+        {"T3", 51.5, -0.12},
+        {"T4", 40.7, -74},
+    }
+    for _, p := range positions {
+        if err := h.Add(p.taxi, p.lat, p.lon); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    cells := h.Cells(0)
+    want := []struct {
+        cell         string
+        count, taxis int
+    }{
+        {geo.Geohash(-6.2, 106.8, 6), 3, 2},
+        {geo.Geohash(51.5, -0.12, 6), 2, 1},
+        {geo.Geohash(40.7, -74, 6), 1, 1},
+    }
+    if len(cells) != len(want) {
+        t.Fatalf("got %d cells, want %d", len(cells), len(want))
+    }
+    for i, w := range want {
+        c := cells[i]
+        if c.Cell != w.cell || c.Count != w.count || c.Taxis != w.taxis {
+            t.Errorf("cell %d = %+v, want %+v", i, c, w)
+        }
+        if c.Bounds != geo.GeohashBounds(c.Cell) {
+            t.Errorf("cell %s has bounds %+v", c.Cell, c.Bounds)
+        }
+    }
+
+    if dense := h.Cells(2); len(dense) != 2 {
+        t.Errorf("got %d cells with at least 2 positions, want 2", len(dense))
+    }
+}
+
+// Cells of equal count are ordered by key.
+func TestHeatmapCellsTieBreak(t *testing.T) {
+    h := New(Grid{CellM: 1000}, 0)
+    for _, p := range [][2]float64{{10, 10}, {-10, -10}, {0, 0}} {
+        if err := h.Add("T1", p[0], p[1]); err != nil {
+            t.Fatal(err)
+        }
+    }
+    cells := h.Cells(0)
+    for i := 1; i < len(cells); i++ {
+        if cells[i-1].Cell >= cells[i].Cell {
+            t.Errorf("cells out of order: %q before %q", cells[i-1].Cell, cells[i].Cell)
+        }
+    }
+}
@@ -0,0 +1,76 @@
+// internal/importer/geojson.go
+package importer
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+type geoJSONFeature struct {
+    Type     string `json:"type"`
+    Geometry *struct {
+        Type        string          `json:"type"`
+        Coordinates json.RawMessage `json:"coordinates"`
+    } `json:"geometry"`
+    Properties map[string]interface{} `json:"properties"`
+}
+
+// parseGeoJSON reads a FeatureCollection, or a single Feature, of Polygon or
+// MultiPolygon features.
+func parseGeoJSON(r io.Reader, nameProperty string) ([]Record, error) {
+    var doc struct {
+        Type     string           `json:"type"`
+        Features []geoJSONFeature `json:"features"`
+        geoJSONFeature
+    }
+    dec := json.NewDecoder(r)
+    dec.UseNumber()
+    if err := dec.Decode(&doc); err != nil {
+        return nil, fmt.Errorf("invalid GeoJSON: %v", err)
+    }
+
+    features := doc.Features
+    switch doc.Type {
+    case "FeatureCollection":
+    case "Feature":
+        features = []geoJSONFeature{doc.geoJSONFeature}
+    default:
+        return nil, fmt.Errorf("expected a FeatureCollection or Feature, got %q", doc.Type)
+    }
+
+    records := make([]Record, 0, len(features))
+    for _, f := range features {
+        var rec Record
+        if name, ok := f.Properties[nameProperty]; ok && name != nil {
+            rec.Place.PlaceName = fmt.Sprint(name)
+        }
+        rec.Place.Polygon, rec.Err = geoJSONPolygon(f)
+        records = append(records, rec)
+    }
+    return records, nil
+}
+
+func geoJSONPolygon(f geoJSONFeature) (models.GeoJSONPolygon, error) {
+    polygon := models.GeoJSONPolygon{Type: "Polygon"}
+    if f.Geometry == nil {
+        return polygon, fmt.Errorf("feature has no geometry")
+    }
+
+    switch f.Geometry.Type {
+    case "Polygon":
+        if err := json.Unmarshal(f.Geometry.Coordinates, &polygon.Coordinates); err != nil {
+            return polygon, fmt.Errorf("invalid polygon coordinates: %v", err)
+        }
+    case "MultiPolygon":
+        polygon.Type = "MultiPolygon"
+        if err := json.Unmarshal(f.Geometry.Coordinates, &polygon.Polygons); err != nil {
+            return polygon, fmt.Errorf("invalid multipolygon coordinates: %v", err)
+        }
+    default:
+        return polygon, fmt.Errorf("unsupported geometry type %q", f.Geometry.Type)
+    }
+    return polygon, nil
+}
@@ -0,0 +1,94 @@
+// internal/importer/importer.go
+package importer
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// Supported import formats.
+const (
+    FormatGeoJSON = "geojson"
+    FormatKML     = "kml"
+    FormatWKT     = "wkt" // CSV with a name column and a WKT geometry column
+)
+
+// DefaultNameProperty is the property, KML field or CSV column used as PlaceName.
+const DefaultNameProperty = "name"
+
+// Record is one place read from an import file. Err is set when the record
+// cannot be imported; Place is still filled in as far as it was parsed.
+type Record struct {
+    Index int
+    Place models.Place
+    Err   error
+}
+
+// Parse reads places from r in the given format. nameProperty selects the
+// feature property that becomes PlaceName. Every polygon is validated; a
+// malformed file fails as a whole, a malformed record only fails itself.
+func Parse(format string, r io.Reader, nameProperty string) ([]Record, error) {
+    if nameProperty == "" {
+        nameProperty = DefaultNameProperty
+    }
+
+    var records []Record
+    var err error
+    switch format {
+    case FormatGeoJSON:
+        records, err = parseGeoJSON(r, nameProperty)
+    case FormatKML:
+        records, err = parseKML(r, nameProperty)
+    case FormatWKT:
+        records, err = parseCSV(r, nameProperty)
+    default:
+        return nil, fmt.Errorf("unsupported format %q", format)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    for i := range records {
+        rec := &records[i]
+        rec.Index = i
+        if rec.Err != nil {
+            continue
+        }
+        if strings.TrimSpace(rec.Place.PlaceName) == "" {
+            rec.Err = fmt.Errorf("missing %q", nameProperty)
+            continue
+        }
+        if err := rec.Place.Polygon.Validate(); err != nil {
+            rec.Err = err
+        }
+    }
+    return records, nil
+}
+
+// DetectFormat guesses the import format from a file name or content type.
+func DetectFormat(name, contentType string) string {
+    lower := strings.ToLower(name + " " + contentType)
+    switch {
+    case strings.Contains(lower, "kml"):
+        return FormatKML
+    case strings.Contains(lower, "json"):
+        return FormatGeoJSON
+    case strings.Contains(lower, "csv"), strings.Contains(lower, "wkt"):
+        return FormatWKT
+    }
+    return ""
+}
+
+// numbers converts float coordinates to the json.Number form used by models.GeoJSONPolygon.
+func numbers(values ...float64) []json.Number {
+    out := make([]json.Number, len(values))
+    for i, v := range values {
+        out[i] = json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+    }
+    return out
+}
@@ -0,0 +1,167 @@
+// internal/importer/importer_test.go
+package importer
+
+import (
+    "strings"
+    "testing"
+)
+
+const (
+    square   = `[[106.80,-6.20],[106.81,-6.20],[106.81,-6.19],[106.80,-6.19],[106.80,-6.20]]`
+    openRing = `[[106.80,-6.20],[106.81,-6.20],[106.81,-6.19],[106.80,-6.19]]`
+    bowTie   = `[[0,0],[2,2],[2,0],[0,1],[0,0]]`
+
+    kmlSquare = `106.80,-6.20 106.81,-6.20 106.81,-6.19 106.80,-6.19 106.80,-6.20`
+    wktSquare = `(106.80 -6.20, 106.81 -6.20, 106.81 -6.19, 106.80 -6.19, 106.80 -6.20)`
+)
+
+func featureCollection(name, geometry string) string {
+    props := `{}`
+    if name != "" {
+        props = `{"name":"` + name + `"}`
+    }
+    return `{"type":"FeatureCollection","features":[{"type":"Feature","properties":` + props + `,"geometry":` + geometry + `}]}`
+}
+
+func kmlPlacemarkDoc(name, geometry string) string {
+    return `<?xml version="1.0"?><kml><Document><Folder><Placemark><name>` + name + `</name>` + geometry +
+        `</Placemark></Folder></Document></kml>`
+}
+
+func kmlPolygonOf(coordinates string) string {
+    return `<Polygon><outerBoundaryIs><LinearRing><coordinates>` + coordinates + `</coordinates></LinearRing></outerBoundaryIs></Polygon>`
+}
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        name   string
+        format string
+        input  string
+        place  string // the name read, when the record is valid
+        kind   string // the geometry type read, when the record is valid
+        parts  int
+        err    string // the record error, empty when it is valid
+    }{
+        {"geojson polygon", FormatGeoJSON,
+            featureCollection("Bandara", `{"type":"Polygon","coordinates":[`+square+`]}`), "Bandara", "Polygon", 1, ""},
+        {"geojson multipolygon", FormatGeoJSON,
+            featureCollection("Stasiun", `{"type":"MultiPolygon","coordinates":[[`+square+`],[`+square+`]]}`), "Stasiun", "MultiPolygon", 2, ""},
+        {"geojson single feature", FormatGeoJSON,
+            `{"type":"Feature","properties":{"name":"Mall"},"geometry":{"type":"Polygon","coordinates":[` + square + `]}}`, "Mall", "Polygon", 1, ""},
+        {"geojson missing name", FormatGeoJSON,
+            featureCollection("", `{"type":"Polygon","coordinates":[`+square+`]}`), "", "", 0, `missing "name"`},
+        {"geojson open ring", FormatGeoJSON,
+            featureCollection("Bandara", `{"type":"Polygon","coordinates":[`+openRing+`]}`), "", "", 0, "ring 0 is not closed"},
+        {"geojson self-intersecting ring", FormatGeoJSON,
+            featureCollection("Bandara", `{"type":"Polygon","coordinates":[`+bowTie+`]}`), "", "", 0, "intersects itself"},
+        {"geojson coordinate out of range", FormatGeoJSON,
+            featureCollection("Bandara", `{"type":"Polygon","coordinates":[[[0,0],[200,0],[1,1],[0,0]]]}`), "", "", 0, "out of range"},
+        {"geojson point", FormatGeoJSON,
+            featureCollection("Bandara", `{"type":"Point","coordinates":[0,0]}`), "", "", 0, `unsupported geometry type "Point"`},
+        {"geojson no geometry", FormatGeoJSON,
+            `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Bandara"}}]}`, "", "", 0, "feature has no geometry"},
+
+        {"kml polygon", FormatKML, kmlPlacemarkDoc("Bandara", kmlPolygonOf(kmlSquare)), "Bandara", "Polygon", 1, ""},
+        {"kml multigeometry", FormatKML,
+            kmlPlacemarkDoc("Stasiun", `<MultiGeometry>`+kmlPolygonOf(kmlSquare)+kmlPolygonOf(kmlSquare)+`</MultiGeometry>`), "Stasiun", "MultiPolygon", 2, ""},
+        {"kml multigeometry of one polygon", FormatKML,
+            kmlPlacemarkDoc("Mall", `<MultiGeometry>`+kmlPolygonOf(kmlSquare)+`</MultiGeometry>`), "Mall", "Polygon", 1, ""},
+        {"kml name from extended data", FormatKML,
+            `<kml><Placemark><name>ignored</name><ExtendedData><Data name="name"><value>Terminal</value></Data></ExtendedData>` +
+                kmlPolygonOf(kmlSquare) + `</Placemark></kml>`, "Terminal", "Polygon", 1, ""},
+        {"kml missing name", FormatKML, kmlPlacemarkDoc(" ", kmlPolygonOf(kmlSquare)), "", "", 0, `missing "name"`},
+        {"kml open ring", FormatKML,
+            kmlPlacemarkDoc("Bandara", kmlPolygonOf(`106.80,-6.20 106.81,-6.20 106.81,-6.19 106.80,-6.19`)), "", "", 0, "ring 0 is not closed"},
+        {"kml bad coordinate", FormatKML,
+            kmlPlacemarkDoc("Bandara", kmlPolygonOf(`106.80,-6.20 east,-6.20 106.81,-6.19 106.80,-6.20`)), "", "", 0, `invalid KML coordinate "east,-6.20"`},
+        {"kml no polygon", FormatKML,
+            kmlPlacemarkDoc("Bandara", `<Point><coordinates>106.80,-6.20</coordinates></Point>`), "", "", 0, "placemark has no polygon"},
+
+        {"wkt polygon", FormatWKT, "name,wkt\nBandara,\"POLYGON (" + wktSquare + ")\"\n", "Bandara", "Polygon", 1, ""},
+        {"wkt multipolygon", FormatWKT,
+            "name,geometry\nStasiun,\"MULTIPOLYGON ((" + wktSquare + "), (" + wktSquare + "))\"\n", "Stasiun", "MultiPolygon", 2, ""},
+        {"wkt missing name", FormatWKT, "name,wkt\n,\"POLYGON (" + wktSquare + ")\"\n", "", "", 0, `missing "name"`},
+        {"wkt open ring", FormatWKT,
+            "name,wkt\nBandara,\"POLYGON ((106.80 -6.20, 106.81 -6.20, 106.81 -6.19, 106.80 -6.19))\"\n", "", "", 0, "ring 0 is not closed"},
+        {"wkt self-intersecting ring", FormatWKT,
+            "name,wkt\nBandara,\"POLYGON ((0 0, 2 2, 2 0, 0 1, 0 0))\"\n", "", "", 0, "intersects itself"},
+        {"wkt bad coordinate", FormatWKT,
+            "name,wkt\nBandara,\"POLYGON ((106.80 -6.20, east -6.20, 106.81 -6.19, 106.80 -6.20))\"\n", "", "", 0, "invalid WKT position"},
+        {"wkt point", FormatWKT, "name,wkt\nBandara,POINT (106.80 -6.20)\n", "", "", 0, "unsupported WKT geometry"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            records, err := Parse(tt.format, strings.NewReader(tt.input), "")
+            if err != nil {
+                t.Fatalf("Parse: %v", err)
+            }
+            if len(records) != 1 {
+                t.Fatalf("got %d records, want 1", len(records))
+            }
+            rec := records[0]
+
+            if tt.err != "" {
+                if rec.Err == nil || !strings.Contains(rec.Err.Error(), tt.err) {
+                    t.Errorf("got error %v, want %q", rec.Err, tt.err)
+                }
+                return
+            }
+            if rec.Err != nil {
+                t.Fatalf("unexpected error: %v", rec.Err)
+            }
+            if rec.Place.PlaceName != tt.place {
+                t.Errorf("name = %q, want %q", rec.Place.PlaceName, tt.place)
+            }
+            if rec.Place.Polygon.Type != tt.kind || len(rec.Place.Polygon.Parts()) != tt.parts {
+                t.Errorf("got a %s of %d parts, want a %s of %d", rec.Place.Polygon.Type, len(rec.Place.Polygon.Parts()), tt.kind, tt.parts)
+            }
+        })
+    }
+}
+
+// A malformed file fails as a whole.
+func TestParseInvalidFile(t *testing.T) {
+    tests := []struct {
+        name   string
+        format string
+        input  string
+    }{
+        {"geojson syntax", FormatGeoJSON, `{"type":"FeatureCollection","features":[`},
+        {"geojson geometry alone", FormatGeoJSON, `{"type":"Polygon","coordinates":[` + square + `]}`},
+        {"kml syntax", FormatKML, `<kml><Placemark>`},
+        {"kml without placemarks", FormatKML, `<kml><Document></Document></kml>`},
+        {"csv without name column", FormatWKT, "title,wkt\nBandara,\"POLYGON (" + wktSquare + ")\"\n"},
+        {"csv without geometry column", FormatWKT, "name,shape\nBandara,\"POLYGON (" + wktSquare + ")\"\n"},
+        {"unknown format", "shapefile", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if records, err := Parse(tt.format, strings.NewReader(tt.input), ""); err == nil {
+                t.Errorf("got %d records, want an error", len(records))
+            }
+        })
+    }
+}
+
+// One bad record does not stop the others, and records keep their position.
+func TestParseMixedRecords(t *testing.T) {
+    input := "name,wkt\n" +
+        "Bandara,\"POLYGON (" + wktSquare + ")\"\n" +
+        ",\"POLYGON (" + wktSquare + ")\"\n" +
+        "Stasiun,\"POLYGON (" + wktSquare + ")\"\n"
+    records, err := Parse(FormatWKT, strings.NewReader(input), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(records) != 3 {
+        t.Fatalf("got %d records, want 3", len(records))
+    }
+    for i, rec := range records {
+        if rec.Index != i {
+            t.Errorf("record %d has index %d", i, rec.Index)
+        }
+        if (rec.Err != nil) != (i == 1) {
+            t.Errorf("record %d error = %v", i, rec.Err)
+        }
+    }
+}
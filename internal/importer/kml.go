@@ -0,0 +1,128 @@
+// internal/importer/kml.go
+package importer
+
+import (
+    "encoding/json"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+type kmlPlacemark struct {
+    Name         string `xml:"name"`
+    ExtendedData struct {
+        Data []struct {
+            Name  string `xml:"name,attr"`
+            Value string `xml:"value"`
+        } `xml:"Data"`
+        SimpleData []struct {
+            Name  string `xml:"name,attr"`
+            Value string `xml:",chardata"`
+        } `xml:"SchemaData>SimpleData"`
+    } `xml:"ExtendedData"`
+    Polygons []kmlPolygon `xml:"Polygon"`
+    Multi    []kmlPolygon `xml:"MultiGeometry>Polygon"`
+}
+
+type kmlPolygon struct {
+    Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+    Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+}
+
+// parseKML reads every Placemark in the document, at any folder depth. The
+// name comes from ExtendedData when it has nameProperty, otherwise from <name>.
+func parseKML(r io.Reader, nameProperty string) ([]Record, error) {
+    dec := xml.NewDecoder(r)
+
+    var records []Record
+    for {
+        tok, err := dec.Token()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("invalid KML: %v", err)
+        }
+
+        start, ok := tok.(xml.StartElement)
+        if !ok || start.Name.Local != "Placemark" {
+            continue
+        }
+
+        var pm kmlPlacemark
+        if err := dec.DecodeElement(&pm, &start); err != nil {
+            return nil, fmt.Errorf("invalid KML placemark: %v", err)
+        }
+        records = append(records, kmlRecord(pm, nameProperty))
+    }
+
+    if len(records) == 0 {
+        return nil, fmt.Errorf("KML contains no placemarks")
+    }
+    return records, nil
+}
+
+func kmlRecord(pm kmlPlacemark, nameProperty string) Record {
+    rec := Record{Place: models.Place{PlaceName: strings.TrimSpace(pm.Name)}}
+    for _, d := range pm.ExtendedData.Data {
+        if d.Name == nameProperty {
+            rec.Place.PlaceName = strings.TrimSpace(d.Value)
+        }
+    }
+    for _, d := range pm.ExtendedData.SimpleData {
+        if d.Name == nameProperty {
+            rec.Place.PlaceName = strings.TrimSpace(d.Value)
+        }
+    }
+
+    // A single polygon is a Polygon, several, as in a MultiGeometry, are the
+    // parts of a MultiPolygon
+    polygons := append(pm.Polygons, pm.Multi...)
+    if len(polygons) == 0 {
+        rec.Err = fmt.Errorf("placemark has no polygon")
+        return rec
+    }
+
+    var parts [][][][]json.Number
+    for _, polygon := range polygons {
+        var rings [][][]json.Number
+        for _, text := range append([]string{polygon.Outer}, polygon.Inner...) {
+            ring, err := kmlRing(text)
+            if err != nil {
+                rec.Err = err
+                return rec
+            }
+            rings = append(rings, ring)
+        }
+        parts = append(parts, rings)
+    }
+
+    if len(parts) == 1 {
+        rec.Place.Polygon = models.GeoJSONPolygon{Type: "Polygon", Coordinates: parts[0]}
+    } else {
+        rec.Place.Polygon = models.GeoJSONPolygon{Type: "MultiPolygon", Polygons: parts}
+    }
+    return rec
+}
+
+// kmlRing parses a KML coordinates string of "lon,lat[,alt]" tuples.
+func kmlRing(text string) ([][]json.Number, error) {
+    var ring [][]json.Number
+    for _, tuple := range strings.Fields(text) {
+        parts := strings.Split(tuple, ",")
+        if len(parts) < 2 {
+            return nil, fmt.Errorf("invalid KML coordinate %q", tuple)
+        }
+        lon, err1 := strconv.ParseFloat(parts[0], 64)
+        lat, err2 := strconv.ParseFloat(parts[1], 64)
+        if err1 != nil || err2 != nil {
+            return nil, fmt.Errorf("invalid KML coordinate %q", tuple)
+        }
+        ring = append(ring, numbers(lon, lat))
+    }
+    return ring, nil
+}
@@ -0,0 +1,153 @@
+// internal/importer/wkt.go
+package importer
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// wktColumns are the CSV header names accepted for the geometry column.
+var wktColumns = []string{"wkt", "geometry", "geom"}
+
+// parseCSV reads a CSV file with a header row, a name column and a WKT
+// POLYGON or MULTIPOLYGON column.
+func parseCSV(r io.Reader, nameProperty string) ([]Record, error) {
+    reader := csv.NewReader(r)
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, fmt.Errorf("invalid CSV header: %v", err)
+    }
+
+    nameCol, geomCol := -1, -1
+    for i, col := range header {
+        col = strings.ToLower(strings.TrimSpace(col))
+        if col == strings.ToLower(nameProperty) {
+            nameCol = i
+        }
+        for _, candidate := range wktColumns {
+            if col == candidate {
+                geomCol = i
+            }
+        }
+    }
+    if nameCol < 0 {
+        return nil, fmt.Errorf("CSV has no %q column", nameProperty)
+    }
+    if geomCol < 0 {
+        return nil, fmt.Errorf("CSV has no geometry column (one of %s)", strings.Join(wktColumns, ", "))
+    }
+
+    var records []Record
+    for {
+        row, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("invalid CSV: %v", err)
+        }
+
+        var rec Record
+        rec.Place.PlaceName = strings.TrimSpace(row[nameCol])
+        rec.Place.Polygon, rec.Err = parseWKT(row[geomCol])
+        records = append(records, rec)
+    }
+    return records, nil
+}
+
+// parseWKT parses a WKT POLYGON or MULTIPOLYGON.
+func parseWKT(text string) (models.GeoJSONPolygon, error) {
+    polygon := models.GeoJSONPolygon{Type: "Polygon"}
+    text = strings.TrimSpace(text)
+    upper := strings.ToUpper(text)
+
+    switch {
+    case strings.HasPrefix(upper, "MULTIPOLYGON"):
+        polygon.Type = "MultiPolygon"
+        parts, ok := unwrap(text[len("MULTIPOLYGON"):])
+        if !ok {
+            return polygon, fmt.Errorf("invalid WKT multipolygon")
+        }
+        for _, part := range splitTopLevel(parts) {
+            rings, err := wktRings(part)
+            if err != nil {
+                return polygon, err
+            }
+            polygon.Polygons = append(polygon.Polygons, rings)
+        }
+        return polygon, nil
+    case strings.HasPrefix(upper, "POLYGON"):
+        rings, err := wktRings(text[len("POLYGON"):])
+        polygon.Coordinates = rings
+        return polygon, err
+    }
+    return polygon, fmt.Errorf("unsupported WKT geometry")
+}
+
+// wktRings parses the parenthesised ring list of one WKT polygon.
+func wktRings(text string) ([][][]json.Number, error) {
+    body, ok := unwrap(text)
+    if !ok {
+        return nil, fmt.Errorf("invalid WKT polygon")
+    }
+
+    var rings [][][]json.Number
+    for _, ringText := range splitTopLevel(body) {
+        positions, ok := unwrap(ringText)
+        if !ok {
+            return nil, fmt.Errorf("invalid WKT ring %q", strings.TrimSpace(ringText))
+        }
+        var ring [][]json.Number
+        for _, pos := range strings.Split(positions, ",") {
+            fields := strings.Fields(pos)
+            if len(fields) < 2 {
+                return nil, fmt.Errorf("invalid WKT position %q", pos)
+            }
+            lon, err1 := strconv.ParseFloat(fields[0], 64)
+            lat, err2 := strconv.ParseFloat(fields[1], 64)
+            if err1 != nil || err2 != nil {
+                return nil, fmt.Errorf("invalid WKT position %q", pos)
+            }
+            ring = append(ring, numbers(lon, lat))
+        }
+        rings = append(rings, ring)
+    }
+    return rings, nil
+}
+
+// splitTopLevel splits s at the commas outside any parentheses.
+func splitTopLevel(s string) []string {
+    var parts []string
+    depth, start := 0, 0
+    for i, c := range s {
+        switch c {
+        case '(':
+            depth++
+        case ')':
+            depth--
+        case ',':
+            if depth == 0 {
+                parts = append(parts, s[start:i])
+                start = i + 1
+            }
+        }
+    }
+    return append(parts, s[start:])
+}
+
+// unwrap strips one pair of enclosing parentheses.
+func unwrap(s string) (string, bool) {
+    s = strings.TrimSpace(s)
+    if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+        return "", false
+    }
+    return s[1 : len(s)-1], true
+}
@@ -0,0 +1,34 @@
+// internal/models/audit.go
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Audit actions.
+const (
+    AuditCreate  = "create"
+    AuditUpdate  = "update"
+    AuditDelete  = "delete"
+    AuditImport  = "import"
+    AuditRestore = "restore"
+    AuditPurge   = "purge"
+    AuditDispute = "dispute"
+    AuditResolve = "resolve"
+)
+
+// AuditEntry records one change made through the API.
+type AuditEntry struct {
+    ID         int64           `json:"id"`
+    CreatedAt  time.Time       `json:"created_at"`
+    Actor      string          `json:"actor"`
+    ActorRole  string          `json:"actor_role"`
+    OperatorID string          `json:"operator_id,omitempty"`
+    RequestID  string          `json:"request_id,omitempty"`
+    Action     string          `json:"action"`
+    Entity     string          `json:"entity"`
+    EntityID   string          `json:"entity_id,omitempty"`
+    Before     json.RawMessage `json:"before"`
+    After      json.RawMessage `json:"after"`
+}
@@ -0,0 +1,41 @@
+// internal/models/dwell.go
+package models
+
+import "time"
+
+// DwellSession is one stay of a taxi inside a place. An open session has no
+// EndedAt.
+type DwellSession struct {
+    ID           int64      `json:"id"`
+    TaxiID       string     `json:"taxi_id"`
+    PlaceID      int        `json:"place_id"`
+    PlaceVersion *int       `json:"place_version,omitempty"`
+    StartedAt    time.Time  `json:"started_at"`
+    EndedAt      *time.Time `json:"ended_at"`
+}
+
+// Duration returns how long the session lasted, or has lasted until now.
+func (s DwellSession) Duration(now time.Time) time.Duration {
+    if s.EndedAt != nil {
+        return s.EndedAt.Sub(s.StartedAt)
+    }
+    return now.Sub(s.StartedAt)
+}
+
+// DwellStat summarises the durations of the dwell sessions in one group, in
+// seconds. Percentiles interpolate linearly between the nearest sessions.
+type DwellStat struct {
+    Group      string  `json:"group"`
+    Count      int     `json:"count"`
+    AvgSeconds float64 `json:"avg_seconds"`
+    P50Seconds float64 `json:"p50_seconds"`
+    P90Seconds float64 `json:"p90_seconds"`
+    P99Seconds float64 `json:"p99_seconds"`
+    MaxSeconds float64 `json:"max_seconds"`
+}
+
+// DwellReport lists dwell statistics grouped by place, taxi or hour of day.
+type DwellReport struct {
+    GroupBy string      `json:"group_by"`
+    Stats   []DwellStat `json:"stats"`
+}
@@ -0,0 +1,30 @@
+// internal/models/event.go
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Event types.
+const (
+    EventTaxiOnline  = "taxi.online"
+    EventTaxiStale   = "taxi.stale"
+    EventTaxiOffline = "taxi.offline"
+    EventViolation   = "place.violation"
+
+    EventViolationClosed = "place.violation_closed"
+    EventRebalance       = "taxi.rebalance"
+)
+
+// Event records something the system noticed about a taxi or place.
+type Event struct {
+    ID           int64           `json:"id"`
+    CreatedAt    time.Time       `json:"created_at"`
+    Type         string          `json:"type"`
+    OperatorID   string          `json:"operator_id,omitempty"`
+    TaxiID       string          `json:"taxi_id,omitempty"`
+    PlaceID      *int            `json:"place_id,omitempty"`
+    PlaceVersion *int            `json:"place_version,omitempty"` // in effect when recorded
+    Data         json.RawMessage `json:"data"`
+}
@@ -0,0 +1,41 @@
+// internal/models/forecast.go
+package models
+
+import "time"
+
+// ForecastPoint is the occupancy expected during the interval starting at
+// Start, with a range the actual value fell into about 80% of the time
+// during backtesting.
+type ForecastPoint struct {
+    Start    time.Time `json:"start"`
+    Expected float64   `json:"expected"`
+    Low      float64   `json:"low"`
+    High     float64   `json:"high"`
+}
+
+// ForecastAccuracy measures one-step-ahead forecasts replayed over the most
+// recent history. BaselineMAE is the error of the plain seasonal averages, and
+// Skill how much smoothing improves on them (1 is perfect, 0 no better).
+type ForecastAccuracy struct {
+    Points      int      `json:"points"`
+    MAE         float64  `json:"mae"`
+    RMSE        float64  `json:"rmse"`
+    MAPE        *float64 `json:"mape,omitempty"` // percent, over intervals that were occupied
+    BaselineMAE float64  `json:"baseline_mae"`
+    Skill       *float64 `json:"skill,omitempty"`
+}
+
+// OccupancyForecast is the expected occupancy of a place for the coming
+// intervals, learned from its occupancy history.
+type OccupancyForecast struct {
+    PlaceID       int              `json:"place_id"`
+    Step          string           `json:"step"`
+    Timezone      string           `json:"timezone"`
+    HistoryFrom   time.Time        `json:"history_from"`
+    HistoryTo     time.Time        `json:"history_to"`
+    HistoryPoints int              `json:"history_points"`
+    Alpha         float64          `json:"alpha"`
+    Damping       float64          `json:"damping"`
+    Points        []ForecastPoint  `json:"points"`
+    Backtest      ForecastAccuracy `json:"backtest"`
+}
@@ -0,0 +1,32 @@
+// internal/models/geojson.go
+package models
+
+// Geometry is a GeoJSON geometry object.
+type Geometry struct {
+    Type        string      `json:"type"`
+    Coordinates interface{} `json:"coordinates"`
+}
+
+// Feature is a GeoJSON feature.
+type Feature struct {
+    Type       string                 `json:"type"`
+    ID         interface{}            `json:"id,omitempty"`
+    Geometry   *Geometry              `json:"geometry"`
+    Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection. NextCursor is a foreign
+// member carrying the pagination cursor of list endpoints.
+type FeatureCollection struct {
+    Type       string    `json:"type"`
+    Features   []Feature `json:"features"`
+    NextCursor *string   `json:"next_cursor,omitempty"`
+}
+
+// NewFeatureCollection wraps features in a FeatureCollection.
+func NewFeatureCollection(features []Feature) FeatureCollection {
+    if features == nil {
+        features = []Feature{}
+    }
+    return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
@@ -0,0 +1,13 @@
+// internal/models/heatmap.go
+package models
+
+import "github.com/SangBejoo/parking-space-monitor/internal/geo"
+
+// HeatmapCell is one cell of a taxi density heatmap: how many positions fell
+// inside it and how many distinct taxis reported them.
+type HeatmapCell struct {
+    Cell   string   `json:"cell"`
+    Bounds geo.BBox `json:"-"`
+    Count  int      `json:"count"`
+    Taxis  int      `json:"taxis"`
+}
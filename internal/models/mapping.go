@@ -1,12 +1,71 @@
-
 // internal/models/mapping.go
 
 package models
 
+import "time"
+
+// Mapping statuses. A mapping made for a booking is offered to a taxi, which
+// accepts it and then picks the passenger up; until pickup it can be
+// cancelled. Mappings made directly by a dispatcher start out accepted.
+const (
+    MappingOffered   = "offered"
+    MappingAccepted  = "accepted"
+    MappingPickedUp  = "picked_up"
+    MappingCancelled = "cancelled"
+)
+
+// Reasons a mapping was cancelled.
+const (
+    CancelRejected   = "rejected"
+    CancelExpired    = "expired"
+    CancelDispatcher = "dispatcher"
+)
+
 // Mapping represents the association between a taxi and a place.
 type Mapping struct {
-    ID        int    `json:"id"`
-    PlaceID   int    `json:"place_id"`
-    TaxiID    int    `json:"taxi_id"`
-    PlaceName string `json:"place_name,omitempty"` // For response purposes
-}
\ No newline at end of file
+    ID          int    `json:"id"`
+    PlaceID     int    `json:"place_id" validate:"required,min=1"`
+    TaxiID      string `json:"taxi_id" validate:"required,id"`
+    PlaceName   string `json:"place_name,omitempty"`   // For response purposes
+    PlateNumber string `json:"plate_number,omitempty"` // For response purposes
+
+    // Status and the fields below are set by the server.
+    Status       string     `json:"status,omitempty"`
+    BookingRef   string     `json:"booking_ref,omitempty"`
+    PickupNote   string     `json:"pickup_note,omitempty"`
+    OfferedAt    *time.Time `json:"offered_at,omitempty"`
+    ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+    AcceptedAt   *time.Time `json:"accepted_at,omitempty"`
+    PickedUpAt   *time.Time `json:"picked_up_at,omitempty"`
+    CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+    CancelReason string     `json:"cancel_reason,omitempty"`
+
+    Transitions []MappingTransition `json:"transitions,omitempty"`
+}
+
+// MappingTransition records one status change of a mapping. From is empty
+// for the status a mapping was created with.
+type MappingTransition struct {
+    From      string    `json:"from,omitempty"`
+    To        string    `json:"to"`
+    Reason    string    `json:"reason,omitempty"`
+    Actor     string    `json:"actor,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// AssignmentRequest is the body of a request for a taxi from a place.
+type AssignmentRequest struct {
+    PlaceID    int    `json:"place_id" validate:"required,min=1"`
+    BookingRef string `json:"booking_ref" validate:"required,maxlen=255"`
+    PickupNote string `json:"pickup_note" validate:"maxlen=1000"`
+
+    // TimeoutSeconds is how long the driver has to accept the offer.
+    TimeoutSeconds int `json:"timeout_seconds" validate:"min=0,max=600"`
+}
+
+// AssignmentOutcome is the result of an offer ending without a pickup: the
+// cancelled mapping and the offer made to the next taxi in the queue, if any.
+type AssignmentOutcome struct {
+    Cancelled Mapping  `json:"cancelled"`
+    Reoffered *Mapping `json:"reoffered"`
+}
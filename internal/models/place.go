@@ -6,11 +6,71 @@ import (
     "encoding/json"
     "errors"
     "fmt"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
 )
 
+// GeoJSONPolygon is the area of a place: a GeoJSON Polygon, or a
+// MultiPolygon of several parts. Coordinates holds the rings of a Polygon and
+// Polygons the rings of each part of a MultiPolygon.
 type GeoJSONPolygon struct {
+    Type        string
+    Coordinates [][][]json.Number
+    Polygons    [][][][]json.Number
+}
+
+// geoJSONGeometry is the encoded form of a GeoJSONPolygon, whose coordinates
+// nest deeper for a MultiPolygon.
+type geoJSONGeometry struct {
     Type        string          `json:"type"`
-    Coordinates [][][]json.Number `json:"coordinates"`
+    Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// UnmarshalJSON decodes a Polygon or MultiPolygon geometry.
+func (p *GeoJSONPolygon) UnmarshalJSON(b []byte) error {
+    var g geoJSONGeometry
+    if err := json.Unmarshal(b, &g); err != nil {
+        return err
+    }
+
+    decoded := GeoJSONPolygon{Type: g.Type}
+    if len(g.Coordinates) > 0 && string(g.Coordinates) != "null" {
+        var err error
+        if g.Type == "MultiPolygon" {
+            err = json.Unmarshal(g.Coordinates, &decoded.Polygons)
+        } else {
+            err = json.Unmarshal(g.Coordinates, &decoded.Coordinates)
+        }
+        if err != nil {
+            return fmt.Errorf("invalid %s coordinates: %v", g.Type, err)
+        }
+    }
+    *p = decoded
+    return nil
+}
+
+// MarshalJSON encodes the geometry, as a Polygon unless it is a MultiPolygon.
+func (p GeoJSONPolygon) MarshalJSON() ([]byte, error) {
+    if p.Type == "MultiPolygon" {
+        return json.Marshal(struct {
+            Type        string              `json:"type"`
+            Coordinates [][][][]json.Number `json:"coordinates"`
+        }{"MultiPolygon", p.Polygons})
+    }
+    return json.Marshal(struct {
+        Type        string            `json:"type"`
+        Coordinates [][][]json.Number `json:"coordinates"`
+    }{"Polygon", p.Coordinates})
+}
+
+// GeometryCoordinates returns the coordinates of the geometry as encoded in
+// GeoJSON.
+func (p GeoJSONPolygon) GeometryCoordinates() interface{} {
+    if p.Type == "MultiPolygon" {
+        return p.Polygons
+    }
+    return p.Coordinates
 }
 
 // Scan implements sql.Scanner interface
@@ -24,22 +84,14 @@ func (p *GeoJSONPolygon) Scan(value interface{}) error {
         return fmt.Errorf("expected []byte, got %T", value)
     }
 
-    var geoJSON struct {
-        Type        string          `json:"type"`
-        Coordinates [][][]json.Number `json:"coordinates"`
-    }
-    
-    if err := json.Unmarshal(b, &geoJSON); err == nil {
-        p.Type = geoJSON.Type
-        p.Coordinates = geoJSON.Coordinates
+    if err := json.Unmarshal(b, p); err == nil {
         return nil
     }
 
     // Try to unmarshal as array if GeoJSON fails
     var coords [][][]json.Number
     if err := json.Unmarshal(b, &coords); err == nil {
-        p.Type = "Polygon"
-        p.Coordinates = coords
+        *p = GeoJSONPolygon{Type: "Polygon", Coordinates: coords}
         return nil
     }
 
@@ -48,17 +100,235 @@ func (p *GeoJSONPolygon) Scan(value interface{}) error {
 
 // Value implements driver.Valuer interface
 func (p GeoJSONPolygon) Value() (driver.Value, error) {
-    return json.Marshal(struct {
-        Type        string          `json:"type"`
-        Coordinates [][][]json.Number `json:"coordinates"`
-    }{
-        Type:        "Polygon",
-        Coordinates: p.Coordinates,
-    })
+    return p.MarshalJSON()
+}
+
+// Parts returns the rings of each polygon of the geometry: the one polygon of
+// a Polygon, or every part of a MultiPolygon.
+func (p GeoJSONPolygon) Parts() [][][][]json.Number {
+    if p.Type == "MultiPolygon" {
+        return p.Polygons
+    }
+    if len(p.Coordinates) == 0 {
+        return nil
+    }
+    return [][][][]json.Number{p.Coordinates}
+}
+
+// OuterRing converts the exterior ring of the first polygon to points.
+func (p GeoJSONPolygon) OuterRing() ([]geo.Point, error) {
+    rings, err := p.OuterRings()
+    if err != nil {
+        return nil, err
+    }
+    return rings[0], nil
+}
+
+// OuterRings converts the exterior ring of every polygon to points.
+func (p GeoJSONPolygon) OuterRings() ([][]geo.Point, error) {
+    parts := p.Parts()
+    if len(parts) == 0 {
+        return nil, errors.New("polygon has no coordinates")
+    }
+
+    rings := make([][]geo.Point, 0, len(parts))
+    for _, part := range parts {
+        if len(part) == 0 {
+            return nil, errors.New("polygon has no rings")
+        }
+        var ring []geo.Point
+        for _, coord := range part[0] {
+            if len(coord) < 2 {
+                continue
+            }
+            lon, err1 := coord[0].Float64()
+            lat, err2 := coord[1].Float64()
+            if err1 != nil || err2 != nil {
+                return nil, fmt.Errorf("invalid coordinate %v", coord)
+            }
+            ring = append(ring, geo.Point{X: lon, Y: lat})
+        }
+        rings = append(rings, ring)
+    }
+    return rings, nil
+}
+
+// Validate checks that the geometry is a well-formed GeoJSON Polygon or
+// MultiPolygon: every ring is closed, has at least four positions within
+// coordinate ranges, and each exterior ring encloses an area without
+// crossing itself.
+func (p GeoJSONPolygon) Validate() error {
+    switch p.Type {
+    case "", "Polygon":
+        if len(p.Coordinates) == 0 {
+            return errors.New("polygon has no rings")
+        }
+        return validatePolygon(p.Coordinates, "")
+    case "MultiPolygon":
+        if len(p.Polygons) == 0 {
+            return errors.New("multipolygon has no polygons")
+        }
+        for i, rings := range p.Polygons {
+            if len(rings) == 0 {
+                return fmt.Errorf("polygon %d has no rings", i)
+            }
+            if err := validatePolygon(rings, fmt.Sprintf("polygon %d ", i)); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+    return fmt.Errorf("unsupported geometry type %q", p.Type)
+}
+
+// validatePolygon checks the rings of one polygon; prefix names the polygon
+// in errors.
+func validatePolygon(rings [][][]json.Number, prefix string) error {
+    for i, ring := range rings {
+        if len(ring) < 4 {
+            return fmt.Errorf("%sring %d has %d positions, need at least 4", prefix, i, len(ring))
+        }
+        for _, coord := range ring {
+            if len(coord) < 2 {
+                return fmt.Errorf("%sring %d has a position with fewer than 2 values", prefix, i)
+            }
+            lon, err1 := coord[0].Float64()
+            lat, err2 := coord[1].Float64()
+            if err1 != nil || err2 != nil {
+                return fmt.Errorf("%sring %d has a non-numeric position", prefix, i)
+            }
+            if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+                return fmt.Errorf("%sring %d has position (%v, %v) out of range", prefix, i, lon, lat)
+            }
+        }
+        firstLon, _ := ring[0][0].Float64()
+        firstLat, _ := ring[0][1].Float64()
+        lastLon, _ := ring[len(ring)-1][0].Float64()
+        lastLat, _ := ring[len(ring)-1][1].Float64()
+        if firstLon != lastLon || firstLat != lastLat {
+            return fmt.Errorf("%sring %d is not closed", prefix, i)
+        }
+    }
+
+    outer, err := GeoJSONPolygon{Coordinates: rings}.OuterRing()
+    if err != nil {
+        return err
+    }
+    if geo.RingArea(outer) == 0 {
+        return fmt.Errorf("%sexterior ring has no area", prefix)
+    }
+    if geo.RingSelfIntersects(outer) {
+        return fmt.Errorf("%sexterior ring intersects itself", prefix)
+    }
+    return nil
 }
 
 type Place struct {
     PlaceID   int            `json:"place_id"`
+    PlaceName string         `json:"place_name" validate:"required,maxlen=255"`
+    Polygon   GeoJSONPolygon `json:"polygon" validate:"required"`
+
+    // OperatorID makes the place private to one operator; empty means shared.
+    OperatorID string `json:"operator_id,omitempty" validate:"id"`
+
+    // Version is the current version of the place, set by the server.
+    Version int `json:"version,omitempty"`
+
+    // Type is what kind of place this is; it defaults to a stand.
+    Type string `json:"type,omitempty" validate:"oneof=stand|no_parking|depot|holding_lot"`
+
+    // Rules configures what taxis may do inside the place.
+    Rules *PlaceRules `json:"rules,omitempty"`
+
+    // Schedule is when the place operates; unset means always.
+    Schedule *PlaceSchedule `json:"schedule,omitempty"`
+
+    // Capacity is how many taxis the place holds and demand needs waiting
+    // there, for rebalancing; unset leaves the place out.
+    Capacity *PlaceCapacity `json:"capacity,omitempty"`
+
+    // OpenState is whether the place operates now, set by the server.
+    OpenState *PlaceOpenState `json:"open_state,omitempty"`
+
+    // DeletedAt is set while the place is soft-deleted.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// PlaceVersion is the name and polygon a place had during [ValidFrom, ValidTo).
+// The current version has no ValidTo.
+type PlaceVersion struct {
+    PlaceID   int            `json:"place_id"`
+    Version   int            `json:"version"`
     PlaceName string         `json:"place_name"`
     Polygon   GeoJSONPolygon `json:"polygon"`
-}
\ No newline at end of file
+    ValidFrom time.Time      `json:"valid_from"`
+    ValidTo   *time.Time     `json:"valid_to"`
+}
+
+// Place returns the place as it was in this version.
+func (v PlaceVersion) Place() Place {
+    return Place{PlaceID: v.PlaceID, PlaceName: v.PlaceName, Polygon: v.Polygon, Version: v.Version}
+}
+
+// OccupancyCount counts the taxis inside a place, in total and per operator.
+type OccupancyCount struct {
+    Total      int            `json:"total"`
+    ByOperator map[string]int `json:"by_operator"`
+}
+
+// PlaceOccupancy is the occupancy of one place.
+type PlaceOccupancy struct {
+    PlaceID int `json:"place_id"`
+    OccupancyCount
+}
+
+// OccupancyReport lists the occupancy of every visible place together with
+// the sum over all of them.
+type OccupancyReport struct {
+    Places []PlaceOccupancy `json:"places"`
+    Total  OccupancyCount   `json:"total"`
+}
+
+// OccupancyPoint summarises the occupancy samples of a place taken during one
+// bucket starting at Start.
+type OccupancyPoint struct {
+    Start   time.Time `json:"start"`
+    Samples int       `json:"samples"`
+    Min     int       `json:"min"`
+    Max     int       `json:"max"`
+    Avg     float64   `json:"avg"`
+}
+
+// OccupancyHistory is the occupancy of a place over [From, To) in buckets of
+// Step. Buckets without samples are left out.
+type OccupancyHistory struct {
+    PlaceID int              `json:"place_id"`
+    Step    string           `json:"step"`
+    From    time.Time        `json:"from"`
+    To      time.Time        `json:"to"`
+    Points  []OccupancyPoint `json:"points"`
+}
+
+// OccupancyCompaction reports what an occupancy compaction run did.
+type OccupancyCompaction struct {
+    RolledUp map[string]int `json:"rolled_up"`
+    Removed  map[string]int `json:"removed"`
+}
+
+// ImportItem describes what a bulk import did, or would do, with one record.
+type ImportItem struct {
+    Index     int    `json:"index"`
+    PlaceName string `json:"place_name"`
+    PlaceID   int    `json:"place_id,omitempty"`
+    Reason    string `json:"reason,omitempty"`
+}
+
+// ImportResult summarises a bulk place import.
+type ImportResult struct {
+    DryRun    bool         `json:"dry_run"`
+    Committed bool         `json:"committed"`
+    Created   []ImportItem `json:"created"`
+    Updated   []ImportItem `json:"updated"`
+    Conflicts []ImportItem `json:"conflicts"`
+    Invalid   []ImportItem `json:"invalid"`
+}
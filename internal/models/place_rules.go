@@ -0,0 +1,166 @@
+// internal/models/place_rules.go
+package models
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Place types.
+const (
+    PlaceTypeStand      = "stand"
+    PlaceTypeNoParking  = "no_parking"
+    PlaceTypeDepot      = "depot"
+    PlaceTypeHoldingLot = "holding_lot"
+)
+
+// Queues reports whether taxis inside a place of this type wait in a queue.
+// Places without a type are stands.
+func PlaceTypeQueues(placeType string) bool {
+    return placeType == "" || placeType == PlaceTypeStand || placeType == PlaceTypeHoldingLot
+}
+
+// Rules a taxi inside a place can break.
+const (
+    RuleMaxDwell        = "max_dwell"
+    RuleFleetNotAllowed = "fleet_not_allowed"
+    RuleOutsideHours    = "outside_hours"
+)
+
+// PlaceRules configures what taxis may do inside a place.
+type PlaceRules struct {
+    // MaxDwellMinutes is how long a taxi may stay. Unset means no limit,
+    // except in no-parking zones, where a stay beyond NoParkingGrace breaks
+    // the rule.
+    MaxDwellMinutes *int `json:"max_dwell_minutes,omitempty"`
+
+    // AllowedFleets lists the fleets whose taxis may use the place. Empty
+    // allows every fleet.
+    AllowedFleets []string `json:"allowed_fleets,omitempty"`
+
+    // OperatingHours is when taxis may be inside the place. Unset means always.
+    OperatingHours *OperatingHours `json:"operating_hours,omitempty"`
+}
+
+// Scan implements sql.Scanner interface
+func (r *PlaceRules) Scan(value interface{}) error {
+    b, ok := value.([]byte)
+    if !ok {
+        return fmt.Errorf("expected []byte, got %T", value)
+    }
+    return json.Unmarshal(b, r)
+}
+
+// Value implements driver.Valuer interface
+func (r PlaceRules) Value() (driver.Value, error) {
+    return json.Marshal(r)
+}
+
+// Validate checks the dwell limit and operating hours.
+func (r PlaceRules) Validate() error {
+    if r.MaxDwellMinutes != nil && *r.MaxDwellMinutes < 0 {
+        return errors.New("max_dwell_minutes must not be negative")
+    }
+    for _, fleet := range r.AllowedFleets {
+        if fleet == "" {
+            return errors.New("allowed_fleets must not contain empty names")
+        }
+    }
+    if r.OperatingHours != nil {
+        if err := r.OperatingHours.Validate(); err != nil {
+            return fmt.Errorf("operating_hours: %v", err)
+        }
+    }
+    return nil
+}
+
+// NoParkingGrace is how long a taxi may stop in a no-parking zone without a
+// dwell limit before it is in violation, so passing through is not one.
+const NoParkingGrace = 2 * time.Minute
+
+// Breach is a rule broken by a taxi inside a place.
+type Breach struct {
+    Rule    string
+    Message string
+}
+
+// Check returns the rules of a place of placeType broken by a taxi of fleet
+// that has been inside for dwell at now.
+func (r PlaceRules) Check(placeType, fleet string, dwell time.Duration, now time.Time) []Breach {
+    var breaches []Breach
+
+    switch {
+    case r.MaxDwellMinutes != nil && dwell > time.Duration(*r.MaxDwellMinutes)*time.Minute:
+        breaches = append(breaches, Breach{RuleMaxDwell, fmt.Sprintf("stayed %s, limit is %d minutes", dwell.Round(time.Second), *r.MaxDwellMinutes)})
+    case r.MaxDwellMinutes == nil && placeType == PlaceTypeNoParking && dwell > NoParkingGrace:
+        breaches = append(breaches, Breach{RuleMaxDwell, fmt.Sprintf("stopped %s in a no-parking zone", dwell.Round(time.Second))})
+    }
+
+    if len(r.AllowedFleets) > 0 {
+        allowed := false
+        for _, f := range r.AllowedFleets {
+            allowed = allowed || f == fleet
+        }
+        if !allowed {
+            breaches = append(breaches, Breach{RuleFleetNotAllowed, fmt.Sprintf("fleet %q may not use this place", fleet)})
+        }
+    }
+
+    if r.OperatingHours != nil && !r.OperatingHours.IsOpen(now) {
+        breaches = append(breaches, Breach{RuleOutsideHours, fmt.Sprintf("present outside operating hours %s-%s", r.OperatingHours.Open, r.OperatingHours.Close)})
+    }
+
+    return breaches
+}
+
+// OperatingHours is a daily opening window in a time zone. A window whose
+// Close is before Open runs past midnight.
+type OperatingHours struct {
+    Open     string `json:"open"`               // HH:MM
+    Close    string `json:"close"`              // HH:MM
+    Timezone string `json:"timezone,omitempty"` // IANA name, UTC by default
+}
+
+// Validate checks the times and the time zone.
+func (h OperatingHours) Validate() error {
+    if _, err := clockMinutes(h.Open); err != nil {
+        return fmt.Errorf("open: %v", err)
+    }
+    if _, err := clockMinutes(h.Close); err != nil {
+        return fmt.Errorf("close: %v", err)
+    }
+    if _, err := time.LoadLocation(h.Timezone); err != nil {
+        return fmt.Errorf("unknown timezone %q", h.Timezone)
+    }
+    return nil
+}
+
+// IsOpen reports whether t falls inside the window. Invalid hours are
+// always open.
+func (h OperatingHours) IsOpen(t time.Time) bool {
+    open, err1 := clockMinutes(h.Open)
+    close, err2 := clockMinutes(h.Close)
+    loc, err3 := time.LoadLocation(h.Timezone)
+    if err1 != nil || err2 != nil || err3 != nil || open == close {
+        return true
+    }
+
+    local := t.In(loc)
+    now := local.Hour()*60 + local.Minute()
+    if open < close {
+        return now >= open && now < close
+    }
+    return now >= open || now < close
+}
+
+// clockMinutes parses HH:MM into minutes after midnight.
+func clockMinutes(s string) (int, error) {
+    t, err := time.Parse("15:04", s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+    }
+    return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,252 @@
+// internal/models/place_schedule.go
+package models
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// PlaceSchedule is when a place operates: windows repeating every week, in a
+// time zone, overridden on exception dates such as holidays or event days.
+// Outside its schedule a place is closed and the scheduler treats it as
+// inactive. A place without a schedule is always open.
+//
+// This differs from the operating hours of PlaceRules, which say when taxis
+// may be inside a place that is active regardless.
+type PlaceSchedule struct {
+    Timezone   string              `json:"timezone,omitempty"` // IANA name, UTC by default
+    Weekly     []WeeklyWindow      `json:"weekly"`
+    Exceptions []ScheduleException `json:"exceptions,omitempty"`
+}
+
+// WeeklyWindow opens a place on the given days of the week. A window whose
+// Close is not after Open runs past midnight into the next day.
+type WeeklyWindow struct {
+    Days  []string `json:"days"`  // mon, tue, wed, thu, fri, sat, sun
+    Open  string   `json:"open"`  // HH:MM
+    Close string   `json:"close"` // HH:MM
+}
+
+// ScheduleException replaces the weekly windows on one date: the place is
+// either closed all day or open only during Windows.
+type ScheduleException struct {
+    Date    string       `json:"date"` // YYYY-MM-DD
+    Closed  bool         `json:"closed,omitempty"`
+    Windows []TimeWindow `json:"windows,omitempty"`
+    Note    string       `json:"note,omitempty"`
+}
+
+// TimeWindow is an opening window within one day. Like weekly windows, a
+// window whose Close is not after Open runs past midnight.
+type TimeWindow struct {
+    Open  string `json:"open"`  // HH:MM
+    Close string `json:"close"` // HH:MM
+}
+
+// PlaceOpenState is whether a place is open at a time and when that changes.
+// NextTransition is nil when the place stays as it is for the coming year.
+type PlaceOpenState struct {
+    Open           bool       `json:"open"`
+    NextTransition *time.Time `json:"next_transition,omitempty"`
+}
+
+// State returns whether the place operates at t and when that next changes.
+// A place without a schedule is always open.
+func (p Place) State(t time.Time) PlaceOpenState {
+    if p.Schedule == nil {
+        return PlaceOpenState{Open: true}
+    }
+    return p.Schedule.State(t)
+}
+
+// IsOpen reports whether the place operates at t.
+func (p Place) IsOpen(t time.Time) bool {
+    return p.Schedule == nil || p.Schedule.IsOpen(t)
+}
+
+// weekdays maps the day names of weekly windows to weekdays.
+var weekdays = map[string]time.Weekday{
+    "sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+    "thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// scheduleDate is the layout of exception dates.
+const scheduleDate = "2006-01-02"
+
+// Scan implements sql.Scanner interface
+func (s *PlaceSchedule) Scan(value interface{}) error {
+    b, ok := value.([]byte)
+    if !ok {
+        return fmt.Errorf("expected []byte, got %T", value)
+    }
+    return json.Unmarshal(b, s)
+}
+
+// Value implements driver.Valuer interface
+func (s PlaceSchedule) Value() (driver.Value, error) {
+    return json.Marshal(s)
+}
+
+// Validate checks the time zone, days, times and exception dates.
+func (s PlaceSchedule) Validate() error {
+    if _, err := time.LoadLocation(s.Timezone); err != nil {
+        return fmt.Errorf("unknown timezone %q", s.Timezone)
+    }
+    for i, w := range s.Weekly {
+        if len(w.Days) == 0 {
+            return fmt.Errorf("weekly[%d]: days are required", i)
+        }
+        for _, day := range w.Days {
+            if _, ok := weekdays[strings.ToLower(day)]; !ok {
+                return fmt.Errorf("weekly[%d]: unknown day %q", i, day)
+            }
+        }
+        if err := (TimeWindow{w.Open, w.Close}).validate(); err != nil {
+            return fmt.Errorf("weekly[%d]: %v", i, err)
+        }
+    }
+
+    seen := make(map[string]bool, len(s.Exceptions))
+    for i, e := range s.Exceptions {
+        if _, err := time.Parse(scheduleDate, e.Date); err != nil {
+            return fmt.Errorf("exceptions[%d]: invalid date %q, expected YYYY-MM-DD", i, e.Date)
+        }
+        if seen[e.Date] {
+            return fmt.Errorf("exceptions[%d]: date %s is listed twice", i, e.Date)
+        }
+        seen[e.Date] = true
+        if e.Closed && len(e.Windows) > 0 {
+            return fmt.Errorf("exceptions[%d]: a closed date cannot have windows", i)
+        }
+        if !e.Closed && len(e.Windows) == 0 {
+            return fmt.Errorf("exceptions[%d]: either closed or windows is required", i)
+        }
+        for _, w := range e.Windows {
+            if err := w.validate(); err != nil {
+                return fmt.Errorf("exceptions[%d]: %v", i, err)
+            }
+        }
+    }
+    return nil
+}
+
+// validate checks the times of a window.
+func (w TimeWindow) validate() error {
+    if _, err := clockMinutes(w.Open); err != nil {
+        return fmt.Errorf("open: %v", err)
+    }
+    if _, err := clockMinutes(w.Close); err != nil {
+        return fmt.Errorf("close: %v", err)
+    }
+    return nil
+}
+
+// IsOpen reports whether the place operates at t.
+func (s PlaceSchedule) IsOpen(t time.Time) bool {
+    return s.State(t).Open
+}
+
+// State returns whether the place operates at t and when that next changes,
+// looking up to a year ahead.
+func (s PlaceSchedule) State(t time.Time) PlaceOpenState {
+    loc, err := time.LoadLocation(s.Timezone)
+    if err != nil {
+        // An invalid schedule never closes a place
+        return PlaceOpenState{Open: true}
+    }
+
+    local := t.In(loc)
+
+    // Look a week ahead first, which finds the transition of any regular
+    // schedule, and only then as far as a year
+    var state PlaceOpenState
+    for _, days := range []int{8, 367} {
+        state = PlaceOpenState{}
+        var next time.Time
+        for _, iv := range s.intervals(local, days) {
+            if !iv.start.After(t) && iv.end.After(t) {
+                state.Open = true
+                next = iv.end
+                break
+            }
+            if iv.start.After(t) {
+                next = iv.start
+                break
+            }
+        }
+
+        // Windows of the days after the lookahead are unknown, so only a
+        // transition before its last day is certain
+        limit := time.Date(local.Year(), local.Month(), local.Day()+days-1, 0, 0, 0, 0, loc)
+        if !next.IsZero() && next.Before(limit) {
+            utc := next.UTC()
+            state.NextTransition = &utc
+            return state
+        }
+    }
+    return state
+}
+
+// interval is a span of time a place is open.
+type interval struct {
+    start, end time.Time
+}
+
+// intervals returns the merged open intervals of the days from the day
+// before local up to days after it, in order.
+func (s PlaceSchedule) intervals(local time.Time, days int) []interval {
+    exceptions := make(map[string]ScheduleException, len(s.Exceptions))
+    for _, e := range s.Exceptions {
+        exceptions[e.Date] = e
+    }
+
+    var all []interval
+    for d := -1; d < days; d++ {
+        date := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, local.Location())
+
+        var windows []TimeWindow
+        if e, ok := exceptions[date.Format(scheduleDate)]; ok {
+            windows = e.Windows
+        } else {
+            for _, w := range s.Weekly {
+                for _, day := range w.Days {
+                    if weekdays[strings.ToLower(day)] == date.Weekday() {
+                        windows = append(windows, TimeWindow{w.Open, w.Close})
+                        break
+                    }
+                }
+            }
+        }
+
+        for _, w := range windows {
+            open, err1 := clockMinutes(w.Open)
+            close, err2 := clockMinutes(w.Close)
+            if err1 != nil || err2 != nil {
+                continue
+            }
+            start := time.Date(date.Year(), date.Month(), date.Day(), open/60, open%60, 0, 0, date.Location())
+            end := time.Date(date.Year(), date.Month(), date.Day(), close/60, close%60, 0, 0, date.Location())
+            if !end.After(start) {
+                end = end.AddDate(0, 0, 1)
+            }
+            all = append(all, interval{start, end})
+        }
+    }
+
+    sort.Slice(all, func(i, j int) bool { return all[i].start.Before(all[j].start) })
+    var merged []interval
+    for _, iv := range all {
+        if n := len(merged); n > 0 && !iv.start.After(merged[n-1].end) {
+            if iv.end.After(merged[n-1].end) {
+                merged[n-1].end = iv.end
+            }
+            continue
+        }
+        merged = append(merged, iv)
+    }
+    return merged
+}
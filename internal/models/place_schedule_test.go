@@ -0,0 +1,244 @@
+// internal/models/place_schedule_test.go
+package models
+
+import (
+    "testing"
+    "time"
+)
+
+// 2026-03-02 is a Monday.
+
+func mustTime(t *testing.T, s string) time.Time {
+    t.Helper()
+    v, err := time.Parse(time.RFC3339, s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return v
+}
+
+var weekdaysOnly = PlaceSchedule{
+    Weekly: []WeeklyWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Open: "08:00", Close: "17:00"}},
+}
+
+func TestPlaceScheduleState(t *testing.T) {
+    tests := []struct {
+        name     string
+        schedule PlaceSchedule
+        at       string
+        open     bool
+        next     string // empty when there is no transition
+    }{
+        {"no windows", PlaceSchedule{}, "2026-03-02T10:00:00Z", false, ""},
+        {"every day all day", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Open: "00:00", Close: "00:00"},
+        }}, "2026-03-02T10:00:00Z", true, ""},
+
+        {"inside window", weekdaysOnly, "2026-03-02T10:00:00Z", true, "2026-03-02T17:00:00Z"},
+        {"at opening", weekdaysOnly, "2026-03-02T08:00:00Z", true, "2026-03-02T17:00:00Z"},
+        {"at closing", weekdaysOnly, "2026-03-02T17:00:00Z", false, "2026-03-03T08:00:00Z"},
+        {"before opening", weekdaysOnly, "2026-03-02T07:59:00Z", false, "2026-03-02T08:00:00Z"},
+        {"over the weekend", weekdaysOnly, "2026-03-06T18:00:00Z", false, "2026-03-09T08:00:00Z"},
+        {"day names ignore case", PlaceSchedule{Weekly: []WeeklyWindow{{Days: []string{"Mon"}, Open: "08:00", Close: "17:00"}}},
+            "2026-03-02T10:00:00Z", true, "2026-03-02T17:00:00Z"},
+
+        // Friday and Saturday nights, 22:00 to 06:00 the next morning
+        {"overnight before midnight", overnight, "2026-03-06T23:00:00Z", true, "2026-03-07T06:00:00Z"},
+        {"overnight after midnight", overnight, "2026-03-07T03:00:00Z", true, "2026-03-07T06:00:00Z"},
+        {"overnight into sunday", overnight, "2026-03-08T05:59:00Z", true, "2026-03-08T06:00:00Z"},
+        {"overnight between nights", overnight, "2026-03-07T12:00:00Z", false, "2026-03-07T22:00:00Z"},
+        {"overnight not from thursday", overnight, "2026-03-06T03:00:00Z", false, "2026-03-06T22:00:00Z"},
+        {"overnight next week", overnight, "2026-03-09T03:00:00Z", false, "2026-03-13T22:00:00Z"},
+
+        {"adjacent windows merge", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon"}, Open: "08:00", Close: "12:00"},
+            {Days: []string{"mon"}, Open: "12:00", Close: "18:00"},
+        }}, "2026-03-02T10:00:00Z", true, "2026-03-02T18:00:00Z"},
+        {"overlapping windows merge", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon"}, Open: "11:00", Close: "18:00"},
+            {Days: []string{"mon"}, Open: "08:00", Close: "13:00"},
+        }}, "2026-03-02T09:00:00Z", true, "2026-03-02T18:00:00Z"},
+        {"contained window merges", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon"}, Open: "08:00", Close: "18:00"},
+            {Days: []string{"mon"}, Open: "10:00", Close: "12:00"},
+        }}, "2026-03-02T11:00:00Z", true, "2026-03-02T18:00:00Z"},
+        {"overnight merges with next day", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon"}, Open: "20:00", Close: "02:00"},
+            {Days: []string{"tue"}, Open: "01:00", Close: "09:00"},
+        }}, "2026-03-02T21:00:00Z", true, "2026-03-03T09:00:00Z"},
+        {"gap between windows", PlaceSchedule{Weekly: []WeeklyWindow{
+            {Days: []string{"mon"}, Open: "08:00", Close: "12:00"},
+            {Days: []string{"mon"}, Open: "13:00", Close: "18:00"},
+        }}, "2026-03-02T12:30:00Z", false, "2026-03-02T13:00:00Z"},
+
+        {"closed exception", withException(weekdaysOnly, ScheduleException{Date: "2026-03-03", Closed: true}),
+            "2026-03-03T10:00:00Z", false, "2026-03-04T08:00:00Z"},
+        {"closed exception skipped from the day before", withException(weekdaysOnly, ScheduleException{Date: "2026-03-03", Closed: true}),
+            "2026-03-02T18:00:00Z", false, "2026-03-04T08:00:00Z"},
+        {"exception windows replace weekly", withException(weekdaysOnly,
+            ScheduleException{Date: "2026-03-03", Windows: []TimeWindow{{Open: "10:00", Close: "12:00"}}}),
+            "2026-03-03T09:00:00Z", false, "2026-03-03T10:00:00Z"},
+        {"inside exception window", withException(weekdaysOnly,
+            ScheduleException{Date: "2026-03-03", Windows: []TimeWindow{{Open: "10:00", Close: "12:00"}}}),
+            "2026-03-03T11:00:00Z", true, "2026-03-03T12:00:00Z"},
+        {"exception opens a weekend", withException(weekdaysOnly,
+            ScheduleException{Date: "2026-03-07", Windows: []TimeWindow{{Open: "09:00", Close: "13:00"}}}),
+            "2026-03-06T18:00:00Z", false, "2026-03-07T09:00:00Z"},
+        // Closing Saturday leaves Friday night's window running into it
+        {"closed exception keeps overnight from the day before", withException(overnight,
+            ScheduleException{Date: "2026-03-07", Closed: true}),
+            "2026-03-07T03:00:00Z", true, "2026-03-07T06:00:00Z"},
+        {"overnight exception window", withException(PlaceSchedule{},
+            ScheduleException{Date: "2026-03-02", Windows: []TimeWindow{{Open: "23:00", Close: "01:00"}}}),
+            "2026-03-03T00:30:00Z", true, "2026-03-03T01:00:00Z"},
+
+        {"time zone", PlaceSchedule{Timezone: "Asia/Jakarta", Weekly: weekdaysOnly.Weekly},
+            "2026-03-02T00:30:00Z", false, "2026-03-02T01:00:00Z"},
+        {"time zone weekday", PlaceSchedule{Timezone: "Asia/Jakarta", Weekly: weekdaysOnly.Weekly},
+            "2026-03-06T20:00:00Z", false, "2026-03-09T01:00:00Z"},
+
+        // Berlin springs forward on 2026-03-29 at 02:00 CET and falls back on
+        // 2026-10-25 at 03:00 CEST
+        {"spring forward opening", sundays("01:00", "04:00"), "2026-03-28T23:30:00Z", false, "2026-03-29T00:00:00Z"},
+        {"spring forward shortens window", sundays("01:00", "04:00"), "2026-03-29T00:30:00Z", true, "2026-03-29T02:00:00Z"},
+        {"fall back lengthens window", sundays("01:00", "04:00"), "2026-10-25T00:30:00Z", true, "2026-10-25T03:00:00Z"},
+        {"fall back keeps wall clock opening", sundays("08:00", "17:00"), "2026-10-24T16:00:00Z", false, "2026-10-25T07:00:00Z"},
+        {"summer opening", sundays("08:00", "17:00"), "2026-10-17T16:00:00Z", false, "2026-10-18T06:00:00Z"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := tt.schedule.Validate(); err != nil {
+                t.Fatalf("invalid schedule: %v", err)
+            }
+            state := tt.schedule.State(mustTime(t, tt.at))
+            if state.Open != tt.open {
+                t.Errorf("open = %v, want %v", state.Open, tt.open)
+            }
+            checkTransition(t, state, tt.next)
+        })
+    }
+}
+
+// overnight opens on Friday and Saturday nights.
+var overnight = PlaceSchedule{
+    Weekly: []WeeklyWindow{{Days: []string{"fri", "sat"}, Open: "22:00", Close: "06:00"}},
+}
+
+func sundays(open, close string) PlaceSchedule {
+    return PlaceSchedule{Timezone: "Europe/Berlin", Weekly: []WeeklyWindow{{Days: []string{"sun"}, Open: open, Close: close}}}
+}
+
+func withException(s PlaceSchedule, e ScheduleException) PlaceSchedule {
+    s.Exceptions = append(append([]ScheduleException(nil), s.Exceptions...), e)
+    return s
+}
+
+func checkTransition(t *testing.T, state PlaceOpenState, want string) {
+    t.Helper()
+    switch {
+    case want == "" && state.NextTransition != nil:
+        t.Errorf("next transition = %v, want none", state.NextTransition)
+    case want != "" && state.NextTransition == nil:
+        t.Errorf("no next transition, want %s", want)
+    case want != "" && !state.NextTransition.Equal(mustTime(t, want)):
+        t.Errorf("next transition = %v, want %s", state.NextTransition, want)
+    case state.NextTransition != nil && state.NextTransition.Location() != time.UTC:
+        t.Errorf("next transition %v is not UTC", state.NextTransition)
+    }
+}
+
+// The first pass looks 8 days ahead and the second 367, of which a
+// transition is only certain before the last day.
+func TestPlaceScheduleLookahead(t *testing.T) {
+    now := mustTime(t, "2026-03-02T10:00:00Z")
+    day := func(days int) string { return now.AddDate(0, 0, days).Format(scheduleDate) }
+    at := func(days int, clock string) string { return day(days) + "T" + clock + ":00Z" }
+
+    allDay := []WeeklyWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Open: "00:00", Close: "00:00"}}
+
+    tests := []struct {
+        name     string
+        schedule PlaceSchedule
+        open     bool
+        next     string
+    }{
+        {"weekly within first pass", PlaceSchedule{Weekly: []WeeklyWindow{{Days: []string{"sun"}, Open: "09:00", Close: "10:00"}}},
+            false, at(6, "09:00")},
+        {"opening beyond a week", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: day(10), Windows: []TimeWindow{{Open: "09:00", Close: "17:00"}}},
+        }}, false, at(10, "09:00")},
+        {"opening months ahead", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: day(200), Windows: []TimeWindow{{Open: "09:00", Close: "17:00"}}},
+        }}, false, at(200, "09:00")},
+        {"opening on the last certain day", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: day(365), Windows: []TimeWindow{{Open: "09:00", Close: "17:00"}}},
+        }}, false, at(365, "09:00")},
+        {"opening past the lookahead", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: day(366), Windows: []TimeWindow{{Open: "09:00", Close: "17:00"}}},
+        }}, false, ""},
+        {"closing months ahead", PlaceSchedule{Weekly: allDay, Exceptions: []ScheduleException{
+            {Date: day(200), Closed: true},
+        }}, true, at(200, "00:00")},
+        {"closing past the lookahead", PlaceSchedule{Weekly: allDay, Exceptions: []ScheduleException{
+            {Date: day(400), Closed: true},
+        }}, true, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := tt.schedule.Validate(); err != nil {
+                t.Fatalf("invalid schedule: %v", err)
+            }
+            state := tt.schedule.State(now)
+            if state.Open != tt.open {
+                t.Errorf("open = %v, want %v", state.Open, tt.open)
+            }
+            checkTransition(t, state, tt.next)
+        })
+    }
+}
+
+func TestPlaceWithoutScheduleIsOpen(t *testing.T) {
+    now := mustTime(t, "2026-03-02T10:00:00Z")
+    state := Place{}.State(now)
+    if !state.Open || state.NextTransition != nil {
+        t.Errorf("got %+v, want open without transition", state)
+    }
+    if !(PlaceSchedule{Timezone: "Mars/Olympus_Mons"}).IsOpen(now) {
+        t.Error("a schedule with an invalid time zone must not close a place")
+    }
+}
+
+func TestPlaceScheduleValidate(t *testing.T) {
+    tests := []struct {
+        name     string
+        schedule PlaceSchedule
+        ok       bool
+    }{
+        {"empty", PlaceSchedule{}, true},
+        {"weekly", weekdaysOnly, true},
+        {"unknown timezone", PlaceSchedule{Timezone: "Mars/Olympus_Mons"}, false},
+        {"no days", PlaceSchedule{Weekly: []WeeklyWindow{{Open: "08:00", Close: "17:00"}}}, false},
+        {"unknown day", PlaceSchedule{Weekly: []WeeklyWindow{{Days: []string{"monday"}, Open: "08:00", Close: "17:00"}}}, false},
+        {"bad time", PlaceSchedule{Weekly: []WeeklyWindow{{Days: []string{"mon"}, Open: "8am", Close: "17:00"}}}, false},
+        {"hour 24", PlaceSchedule{Weekly: []WeeklyWindow{{Days: []string{"mon"}, Open: "08:00", Close: "24:00"}}}, false},
+        {"bad date", PlaceSchedule{Exceptions: []ScheduleException{{Date: "2026-02-30", Closed: true}}}, false},
+        {"duplicate date", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: "2026-12-25", Closed: true}, {Date: "2026-12-25", Closed: true},
+        }}, false},
+        {"closed with windows", PlaceSchedule{Exceptions: []ScheduleException{
+            {Date: "2026-12-25", Closed: true, Windows: []TimeWindow{{Open: "10:00", Close: "12:00"}}},
+        }}, false},
+        {"neither closed nor windows", PlaceSchedule{Exceptions: []ScheduleException{{Date: "2026-12-25"}}}, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := tt.schedule.Validate()
+            if tt.ok && err != nil {
+                t.Errorf("unexpected error: %v", err)
+            }
+            if !tt.ok && err == nil {
+                t.Error("expected an error")
+            }
+        })
+    }
+}
@@ -0,0 +1,142 @@
+// internal/models/place_test.go
+package models
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+)
+
+const (
+    squareA = `[[[106.80,-6.20],[106.81,-6.20],[106.81,-6.19],[106.80,-6.19],[106.80,-6.20]]]`
+    squareB = `[[[106.90,-6.20],[106.91,-6.20],[106.91,-6.19],[106.90,-6.19],[106.90,-6.20]]]`
+)
+
+func TestGeoJSONPolygonRoundTrip(t *testing.T) {
+    tests := []struct {
+        name  string
+        input string
+        parts int
+    }{
+        {"polygon", `{"type":"Polygon","coordinates":` + squareA + `}`, 1},
+        {"multipolygon", `{"type":"MultiPolygon","coordinates":[` + squareA + `,` + squareB + `]}`, 2},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var p GeoJSONPolygon
+            if err := json.Unmarshal([]byte(tt.input), &p); err != nil {
+                t.Fatal(err)
+            }
+            if err := p.Validate(); err != nil {
+                t.Fatalf("Validate: %v", err)
+            }
+            if len(p.Parts()) != tt.parts {
+                t.Errorf("got %d parts, want %d", len(p.Parts()), tt.parts)
+            }
+
+            // Stored and returned as it was given
+            value, err := p.Value()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if string(value.([]byte)) != tt.input {
+                t.Errorf("Value = %s, want %s", value, tt.input)
+            }
+            var scanned GeoJSONPolygon
+            if err := scanned.Scan(value); err != nil {
+                t.Fatal(err)
+            }
+            out, _ := json.Marshal(scanned)
+            if string(out) != tt.input {
+                t.Errorf("scanned back as %s, want %s", out, tt.input)
+            }
+        })
+    }
+}
+
+// Polygons stored as a bare ring array before they were typed read back as
+// Polygons.
+func TestGeoJSONPolygonScanArray(t *testing.T) {
+    var p GeoJSONPolygon
+    if err := p.Scan([]byte(squareA)); err != nil {
+        t.Fatal(err)
+    }
+    if p.Type != "Polygon" || len(p.Coordinates) != 1 || len(p.Coordinates[0]) != 5 {
+        t.Errorf("got %+v", p)
+    }
+}
+
+func TestGeoJSONPolygonValidate(t *testing.T) {
+    tests := []struct {
+        name  string
+        input string
+        err   string // empty when valid
+    }{
+        {"polygon", `{"type":"Polygon","coordinates":` + squareA + `}`, ""},
+        {"untyped polygon", `{"coordinates":` + squareA + `}`, ""},
+        {"no rings", `{"type":"Polygon","coordinates":[]}`, "polygon has no rings"},
+        {"open ring", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, "ring 0 is not closed"},
+        {"too few positions", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, "ring 0 has 3 positions"},
+        {"out of range", `{"type":"Polygon","coordinates":[[[0,0],[181,0],[1,1],[0,0]]]}`, "out of range"},
+        {"bow tie", `{"type":"Polygon","coordinates":[[[0,0],[2,2],[2,0],[0,1],[0,0]]]}`, "intersects itself"},
+        {"multipolygon", `{"type":"MultiPolygon","coordinates":[` + squareA + `,` + squareB + `]}`, ""},
+        {"empty multipolygon", `{"type":"MultiPolygon","coordinates":[]}`, "multipolygon has no polygons"},
+        {"multipolygon with an empty part", `{"type":"MultiPolygon","coordinates":[` + squareA + `,[]]}`, "polygon 1 has no rings"},
+        {"multipolygon with an open part", `{"type":"MultiPolygon","coordinates":[` + squareA + `,[[[0,0],[1,0],[1,1],[0,1]]]]}`, "polygon 1 ring 0 is not closed"},
+        {"point", `{"type":"Point","coordinates":[0,0]}`, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var p GeoJSONPolygon
+            if err := json.Unmarshal([]byte(tt.input), &p); err != nil {
+                // A point's coordinates cannot be read as rings
+                if tt.name == "point" {
+                    return
+                }
+                t.Fatal(err)
+            }
+            err := p.Validate()
+            if tt.err == "" && err != nil {
+                t.Errorf("unexpected error: %v", err)
+            }
+            if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+                t.Errorf("got error %v, want %q", err, tt.err)
+            }
+        })
+    }
+}
+
+// A taxi is inside a MultiPolygon place when it is inside any of its parts.
+func TestGeoJSONPolygonOuterRings(t *testing.T) {
+    var p GeoJSONPolygon
+    if err := json.Unmarshal([]byte(`{"type":"MultiPolygon","coordinates":[`+squareA+`,`+squareB+`]}`), &p); err != nil {
+        t.Fatal(err)
+    }
+    rings, err := p.OuterRings()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(rings) != 2 {
+        t.Fatalf("got %d rings, want 2", len(rings))
+    }
+
+    for _, tt := range []struct {
+        lon, lat float64
+        inside   bool
+    }{
+        {106.805, -6.195, true},
+        {106.905, -6.195, true},
+        {106.855, -6.195, false},
+    } {
+        if got := geo.PointInAnyPolygon(tt.lon, tt.lat, rings); got != tt.inside {
+            t.Errorf("(%v, %v) inside = %v, want %v", tt.lon, tt.lat, got, tt.inside)
+        }
+    }
+
+    // Both parts are the same size, so the centre lies between them
+    if c := geo.CentroidOf(rings); c.X < 106.854 || c.X > 106.856 || c.Y < -6.196 || c.Y > -6.194 {
+        t.Errorf("centroid %+v, want about (106.855, -6.195)", c)
+    }
+}
@@ -0,0 +1,13 @@
+// internal/models/purge.go
+package models
+
+import "time"
+
+// PurgeResult counts the soft-deleted rows, and the positions of the purged
+// taxis, that a purge removed for good.
+type PurgeResult struct {
+    Cutoff        time.Time `json:"cutoff"`
+    Places        int       `json:"places"`
+    TaxiLocations int       `json:"taxi_locations"`
+    Positions     int       `json:"positions"`
+}
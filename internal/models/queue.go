@@ -0,0 +1,13 @@
+// internal/models/queue.go
+package models
+
+import "time"
+
+// QueueEntry represents a taxi waiting in a place's FIFO queue.
+type QueueEntry struct {
+    PlaceID      int       `json:"place_id"`
+    PlaceVersion int       `json:"place_version"`
+    TaxiID       string    `json:"taxi_id"`
+    Position     int       `json:"position"`
+    EnteredAt    time.Time `json:"entered_at"`
+}
@@ -0,0 +1,70 @@
+// internal/models/recommendation.go
+package models
+
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Reasons a taxi is recommended to move.
+const (
+    RebalanceOverCapacity = "over_capacity"
+    RebalanceOverTarget   = "over_target"
+)
+
+// PlaceCapacity says how many taxis a place holds and how many demand needs
+// waiting there. Places with more waiting taxis than their target, or than
+// their spaces when they have no target, give taxis to places below target.
+type PlaceCapacity struct {
+    // Spaces is how many taxis fit. Unset means no physical limit.
+    Spaces *int `json:"spaces,omitempty"`
+
+    // Target is how many taxis should be waiting. Unset means the place only
+    // gives away taxis beyond its spaces and never asks for more.
+    Target *int `json:"target,omitempty"`
+}
+
+// Scan implements sql.Scanner interface
+func (c *PlaceCapacity) Scan(value interface{}) error {
+    b, ok := value.([]byte)
+    if !ok {
+        return fmt.Errorf("expected []byte, got %T", value)
+    }
+    return json.Unmarshal(b, c)
+}
+
+// Value implements driver.Valuer interface
+func (c PlaceCapacity) Value() (driver.Value, error) {
+    return json.Marshal(c)
+}
+
+// Validate checks that the numbers are not negative and the target fits.
+func (c PlaceCapacity) Validate() error {
+    if c.Spaces != nil && *c.Spaces < 0 {
+        return errors.New("spaces must not be negative")
+    }
+    if c.Target != nil && *c.Target < 0 {
+        return errors.New("target must not be negative")
+    }
+    if c.Spaces != nil && c.Target != nil && *c.Target > *c.Spaces {
+        return errors.New("target must not exceed spaces")
+    }
+    return nil
+}
+
+// Recommendation proposes that a waiting taxi moves from an overflowing
+// place to one below its target. Rank orders the recommendations of a run by
+// distance, shortest first.
+type Recommendation struct {
+    ID          int64     `json:"id"`
+    Rank        int       `json:"rank"`
+    TaxiID      string    `json:"taxi_id"`
+    FromPlaceID int       `json:"from_place_id"`
+    ToPlaceID   int       `json:"to_place_id"`
+    DistanceKm  float64   `json:"distance_km"`
+    Reason      string    `json:"reason"`
+    CreatedAt   time.Time `json:"created_at"`
+}
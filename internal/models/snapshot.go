@@ -0,0 +1,27 @@
+// internal/models/snapshot.go
+package models
+
+import "time"
+
+// TaxiDuration records which place a taxi is currently dwelling in, as kept
+// in the taxi_durations table by the scheduler.
+type TaxiDuration struct {
+    TaxiID       string    `json:"taxi_id"`
+    PlaceID      *int      `json:"place_id"`
+    PlaceVersion *int      `json:"place_version,omitempty"` // version of the place the taxi entered
+    UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Snapshot is a point-in-time copy of the monitored state.
+type Snapshot struct {
+    Version   int            `json:"version"`
+    CreatedAt time.Time      `json:"created_at"`
+    Places    []Place        `json:"places"`
+    Registry  []Taxi         `json:"registry"`
+    Taxis     []TaxiLocation `json:"taxis"`
+    Mappings  []Mapping      `json:"mappings"`
+    Durations []TaxiDuration `json:"durations"`
+
+    // DwellSessions holds every stay, open or ended.
+    DwellSessions []DwellSession `json:"dwell_sessions"`
+}
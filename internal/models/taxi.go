@@ -1,17 +1,75 @@
 // internal/models/taxi.go
 package models
 
-// Taxi represents a taxi entity.
+import "time"
+
+// Taxi statuses in the registry.
+const (
+    TaxiStatusActive    = "active"
+    TaxiStatusInactive  = "inactive"
+    TaxiStatusSuspended = "suspended"
+)
+
+// Taxi represents a registered taxi. Its TaxiID is the identity used by
+// locations, mappings, queues and dwell records.
 type Taxi struct {
-    ID        int     `json:"id"`
-    NomorTaxi string  `json:"nomor_taxi"`
-    Longitude float64 `json:"longitude"`
-    Latitude  float64 `json:"latitude"`
+    TaxiID       string    `json:"taxi_id" validate:"required,id"`
+    PlateNumber  string    `json:"plate_number" validate:"required,maxlen=32"`
+    Fleet        string    `json:"fleet,omitempty" validate:"maxlen=255"`
+    VehicleClass string    `json:"vehicle_class,omitempty" validate:"maxlen=64"`
+    Status       string    `json:"status,omitempty" validate:"oneof=active|inactive|suspended"`
+    OperatorID   string    `json:"operator_id,omitempty" validate:"id"`
+    CreatedAt    time.Time `json:"created_at"`
+    UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Presence statuses of a taxi, derived from when its location was last reported.
+const (
+    TaxiOnline  = "online"
+    TaxiStale   = "stale"
+    TaxiOffline = "offline"
+)
+
+// Presence holds the thresholds after which a taxi that stopped reporting
+// its location counts as stale and then offline.
+type Presence struct {
+    StaleAfter   time.Duration
+    OfflineAfter time.Duration
+}
+
+// DefaultPresence marks taxis stale after 5 minutes and offline after 30.
+var DefaultPresence = Presence{StaleAfter: 5 * time.Minute, OfflineAfter: 30 * time.Minute}
+
+// Status returns the presence status of a taxi last seen at lastSeen.
+func (p Presence) Status(lastSeen, now time.Time) string {
+    age := now.Sub(lastSeen)
+    switch {
+    case age >= p.OfflineAfter:
+        return TaxiOffline
+    case age >= p.StaleAfter:
+        return TaxiStale
+    }
+    return TaxiOnline
 }
 
 // TaxiLocation represents a taxi's location.
 type TaxiLocation struct {
-    TaxiID    string  `json:"taxi_id"`
-    Longitude float64 `json:"longitude"`
-    Latitude  float64 `json:"latitude"`
-}
\ No newline at end of file
+    TaxiID    string  `json:"taxi_id" validate:"required,id"`
+    Longitude float64 `json:"longitude" validate:"lon"`
+    Latitude  float64 `json:"latitude" validate:"lat"`
+
+    // UpdatedAt is when the location was last reported and Status the
+    // presence derived from it; both are set by the server.
+    UpdatedAt time.Time `json:"updated_at"`
+    Status    string    `json:"status,omitempty"`
+
+    // DeletedAt is set while the location is soft-deleted.
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// NearbyTaxi represents a taxi ranked by its distance from a requested point.
+type NearbyTaxi struct {
+    TaxiLocation
+    PlaceID    *int    `json:"place_id,omitempty"`
+    DistanceKm float64 `json:"distance_km"`
+}
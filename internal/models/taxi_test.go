@@ -0,0 +1,37 @@
+// internal/models/taxi_test.go
+package models
+
+import (
+    "testing"
+    "time"
+)
+
+func TestPresenceStatus(t *testing.T) {
+    jakarta := time.FixedZone("WIB", 7*60*60)
+    // now is 10:00 in Jakarta, 03:00 UTC
+    now := time.Date(2026, 3, 2, 10, 0, 0, 0, jakarta)
+    // lastSeen reads back from the database as a UTC wall clock
+    utc := func(hour, minute int) time.Time { return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC) }
+
+    tests := []struct {
+        name     string
+        lastSeen time.Time
+        want     string
+    }{
+        {"just now", utc(3, 0), TaxiOnline},
+        {"under stale", utc(2, 56), TaxiOnline},
+        {"at stale", utc(2, 55), TaxiStale},
+        {"under offline", utc(2, 31), TaxiStale},
+        {"at offline", utc(2, 30), TaxiOffline},
+        {"same wall clock as now", utc(10, 0), TaxiOnline},
+        {"Jakarta wall clock seven hours ago", utc(3, 0).Add(-7 * time.Hour), TaxiOffline},
+        {"same instant in Jakarta", now, TaxiOnline},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := DefaultPresence.Status(tt.lastSeen, now); got != tt.want {
+                t.Errorf("Status(%v, %v) = %q, want %q", tt.lastSeen, now, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,79 @@
+// internal/models/violation.go
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Violation statuses. A violation is open while the taxi is still inside the
+// place and closed once it leaves. It can be disputed before or after that,
+// and is resolved once it has closed.
+const (
+    ViolationOpen     = "open"
+    ViolationClosed   = "closed"
+    ViolationDisputed = "disputed"
+    ViolationResolved = "resolved"
+)
+
+// Violation resolutions.
+const (
+    ResolutionUpheld    = "upheld"
+    ResolutionDismissed = "dismissed"
+)
+
+// Violation records a taxi breaking a rule of the place it was in, once per
+// dwell session and rule.
+type Violation struct {
+    ID             int64      `json:"id"`
+    TaxiID         string     `json:"taxi_id"`
+    PlaceID        int        `json:"place_id"`
+    PlaceVersion   *int       `json:"place_version,omitempty"` // in effect when detected
+    DwellSessionID *int64     `json:"dwell_session_id,omitempty"`
+    Rule           string     `json:"rule"`
+    Message        string     `json:"message"`
+    DetectedAt     time.Time  `json:"detected_at"`
+    Status         string     `json:"status"`
+    ClosedAt       *time.Time `json:"closed_at,omitempty"`
+
+    // Evidence is the position trail of the taxi during its stay. It is
+    // fixed when the stay ends, setting ClosedAt, and live until then.
+    Evidence []TrailPoint `json:"evidence"`
+
+    DisputeReason  string     `json:"dispute_reason,omitempty"`
+    DisputedBy     string     `json:"disputed_by,omitempty"`
+    DisputedAt     *time.Time `json:"disputed_at,omitempty"`
+    Resolution     string     `json:"resolution,omitempty"`
+    ResolutionNote string     `json:"resolution_note,omitempty"`
+    ResolvedBy     string     `json:"resolved_by,omitempty"`
+    ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+    UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TrailPoint is one reported position of a taxi.
+type TrailPoint struct {
+    Longitude  float64   `json:"longitude"`
+    Latitude   float64   `json:"latitude"`
+    RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ParseTrail decodes an evidence trail stored as JSON.
+func ParseTrail(b []byte) ([]TrailPoint, error) {
+    trail := []TrailPoint{}
+    if len(b) == 0 {
+        return trail, nil
+    }
+    err := json.Unmarshal(b, &trail)
+    return trail, err
+}
+
+// ViolationDispute is the body of a request to dispute a violation.
+type ViolationDispute struct {
+    Reason string `json:"reason" validate:"required,maxlen=2000"`
+}
+
+// ViolationResolution is the body of a request to resolve a violation.
+type ViolationResolution struct {
+    Resolution string `json:"resolution" validate:"required,oneof=upheld|dismissed"`
+    Note       string `json:"note" validate:"maxlen=2000"`
+}
@@ -0,0 +1,127 @@
+// internal/rebalance/rebalance.go
+package rebalance
+
+import (
+    "math"
+    "sort"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// Taxi is where a waiting taxi is and which operator it belongs to.
+type Taxi struct {
+    Longitude  float64
+    Latitude   float64
+    OperatorID string
+}
+
+// receiver is a place below its target.
+type receiver struct {
+    place   models.Place
+    centre  geo.Point
+    deficit int
+}
+
+// move is a taxi that could go to a receiver.
+type move struct {
+    taxiID     string
+    from       int
+    reason     string
+    to         *receiver
+    distanceKm float64
+}
+
+// Recommend proposes moves from places with more waiting taxis than they
+// need to places with fewer than their target.
+//
+// Only places that queue taxis and have a capacity take part, and callers
+// pass only the places that are open. A place needs its target, or its
+// spaces when it has no target; the taxis beyond that, the last to arrive,
+// may move. A place below its target asks for the difference. A taxi only
+// moves to shared places and those of its own operator.
+//
+// Moves are chosen greedily, the shortest haversine distance from the taxi
+// to the centre of the receiving place first, so each taxi gets at most one
+// recommendation and no place is sent more taxis than it asked for. The
+// result is ranked by that distance.
+func Recommend(places []models.Place, queues map[int][]models.QueueEntry, taxis map[string]Taxi) []models.Recommendation {
+    var receivers []*receiver
+    var moves []move
+    for _, place := range places {
+        if place.Capacity == nil || !models.PlaceTypeQueues(place.Type) {
+            continue
+        }
+        waiting := len(queues[place.PlaceID])
+        capacity := place.Capacity
+
+        if capacity.Target != nil && waiting < *capacity.Target {
+            rings, err := place.Polygon.OuterRings()
+            if err != nil || len(rings[0]) == 0 {
+                continue
+            }
+            receivers = append(receivers, &receiver{place: place, centre: geo.CentroidOf(rings), deficit: *capacity.Target - waiting})
+            continue
+        }
+
+        needed := capacity.Target
+        if needed == nil {
+            needed = capacity.Spaces
+        }
+        if needed == nil || waiting <= *needed {
+            continue
+        }
+        reason := models.RebalanceOverTarget
+        if capacity.Spaces != nil && waiting > *capacity.Spaces {
+            reason = models.RebalanceOverCapacity
+        }
+        for _, entry := range queues[place.PlaceID][*needed:] {
+            moves = append(moves, move{taxiID: entry.TaxiID, from: place.PlaceID, reason: reason})
+        }
+    }
+
+    var candidates []move
+    for _, m := range moves {
+        taxi, ok := taxis[m.taxiID]
+        if !ok {
+            continue
+        }
+        for _, r := range receivers {
+            if r.place.OperatorID != "" && r.place.OperatorID != taxi.OperatorID {
+                continue
+            }
+            m.to = r
+            m.distanceKm = geo.HaversineKm(taxi.Latitude, taxi.Longitude, r.centre.Y, r.centre.X)
+            candidates = append(candidates, m)
+        }
+    }
+    sort.SliceStable(candidates, func(i, j int) bool {
+        a, b := candidates[i], candidates[j]
+        if a.distanceKm != b.distanceKm {
+            return a.distanceKm < b.distanceKm
+        }
+        if a.taxiID != b.taxiID {
+            return a.taxiID < b.taxiID
+        }
+        return a.to.place.PlaceID < b.to.place.PlaceID
+    })
+
+    recommendations := []models.Recommendation{}
+    moved := map[string]bool{}
+    for _, c := range candidates {
+        if moved[c.taxiID] || c.to.deficit == 0 {
+            continue
+        }
+        moved[c.taxiID] = true
+        c.to.deficit--
+        recommendations = append(recommendations, models.Recommendation{
+            Rank:        len(recommendations) + 1,
+            TaxiID:      c.taxiID,
+            FromPlaceID: c.from,
+            ToPlaceID:   c.to.place.PlaceID,
+            DistanceKm:  math.Round(c.distanceKm*1000) / 1000,
+            Reason:      c.reason,
+        })
+    }
+    return recommendations
+}
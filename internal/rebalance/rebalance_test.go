@@ -0,0 +1,316 @@
+// internal/rebalance/rebalance_test.go
+package rebalance
+
+import (
+    "encoding/json"
+    "fmt"
+    "math/rand"
+    "strconv"
+    "testing"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+func intp(v int) *int {
+    return &v
+}
+
+// square returns a square place about 200 m across centred on lat, lon.
+func square(lat, lon float64) models.GeoJSONPolygon {
+    const d = 0.001
+    corner := func(lat, lon float64) []json.Number {
+        return []json.Number{json.Number(strconv.FormatFloat(lon, 'f', -1, 64)), json.Number(strconv.FormatFloat(lat, 'f', -1, 64))}
+    }
+    return models.GeoJSONPolygon{Type: "Polygon", Coordinates: [][][]json.Number{{
+        corner(lat-d, lon-d), corner(lat-d, lon+d), corner(lat+d, lon+d), corner(lat+d, lon-d), corner(lat-d, lon-d),
+    }}}
+}
+
+func place(id int, lat, lon float64, operator string, spaces, target *int) models.Place {
+    return models.Place{
+        PlaceID:    id,
+        Type:       models.PlaceTypeStand,
+        Polygon:    square(lat, lon),
+        OperatorID: operator,
+        Capacity:   &models.PlaceCapacity{Spaces: spaces, Target: target},
+    }
+}
+
+// scenario builds queues and taxi positions; each queued taxi waits at the
+// place it is queued for.
+type scenario struct {
+    places []models.Place
+    queues map[int][]models.QueueEntry
+    taxis  map[string]Taxi
+}
+
+func newScenario(places ...models.Place) *scenario {
+    return &scenario{places: places, queues: map[int][]models.QueueEntry{}, taxis: map[string]Taxi{}}
+}
+
+// queue adds taxis of an operator to the end of a place's queue.
+func (s *scenario) queue(placeID int, operator string, taxiIDs ...string) *scenario {
+    var at Taxi
+    for _, p := range s.places {
+        if p.PlaceID == placeID {
+            ring, _ := p.Polygon.OuterRing()
+            at = Taxi{Longitude: (ring[0].X + ring[2].X) / 2, Latitude: (ring[0].Y + ring[2].Y) / 2}
+        }
+    }
+    for _, id := range taxiIDs {
+        s.queues[placeID] = append(s.queues[placeID], models.QueueEntry{PlaceID: placeID, TaxiID: id, Position: len(s.queues[placeID]) + 1})
+        s.taxis[id] = Taxi{Longitude: at.Longitude, Latitude: at.Latitude, OperatorID: operator}
+    }
+    return s
+}
+
+func (s *scenario) recommend() []models.Recommendation {
+    return Recommend(s.places, s.queues, s.taxis)
+}
+
+// moves summarises recommendations as "taxi:from>to" in rank order.
+func moves(recs []models.Recommendation) []string {
+    var out []string
+    for _, r := range recs {
+        out = append(out, fmt.Sprintf("%s:%d>%d", r.TaxiID, r.FromPlaceID, r.ToPlaceID))
+    }
+    return out
+}
+
+func equalMoves(t *testing.T, got []models.Recommendation, want ...string) {
+    t.Helper()
+    g := moves(got)
+    if fmt.Sprint(g) != fmt.Sprint(want) {
+        t.Errorf("got moves %v, want %v", g, want)
+    }
+}
+
+func TestRecommendMovesLatestArrivalsToNearestPlace(t *testing.T) {
+    s := newScenario(
+        place(1, -6.200, 106.800, "", intp(10), intp(2)), // 5 waiting, 3 to spare
+        place(2, -6.210, 106.800, "", intp(10), intp(2)), // about 1.1 km away, needs 2
+        place(3, -6.250, 106.800, "", intp(10), intp(5)), // about 5.6 km away, needs 1
+    ).queue(1, "acme", "T1", "T2", "T3", "T4", "T5").queue(3, "acme", "T6", "T7", "T8", "T9")
+
+    recs := s.recommend()
+    equalMoves(t, recs, "T3:1>2", "T4:1>2", "T5:1>3")
+    for i, r := range recs {
+        if r.Rank != i+1 {
+            t.Errorf("recommendation %d has rank %d", i, r.Rank)
+        }
+        if r.Reason != models.RebalanceOverTarget {
+            t.Errorf("%s: reason %q, want %q", r.TaxiID, r.Reason, models.RebalanceOverTarget)
+        }
+    }
+    if d := recs[0].DistanceKm; d < 1.0 || d > 1.2 {
+        t.Errorf("distance %v km, want about 1.1", d)
+    }
+}
+
+// A receiver is sent no more than its deficit however many taxis are spare.
+func TestRecommendFillsOnlyTheDeficit(t *testing.T) {
+    s := newScenario(
+        place(1, -6.200, 106.800, "", nil, intp(0)),
+        place(2, -6.210, 106.800, "", nil, intp(3)),
+    ).queue(1, "acme", "T1", "T2", "T3", "T4", "T5").queue(2, "acme", "T6")
+
+    equalMoves(t, s.recommend(), "T1:1>2", "T2:1>2")
+}
+
+// Every spare taxi can reach both receivers; each is recommended once.
+func TestRecommendMovesEachTaxiOnce(t *testing.T) {
+    s := newScenario(
+        place(1, -6.200, 106.800, "", intp(2), nil),
+        place(2, -6.210, 106.800, "", nil, intp(5)),
+        place(3, -6.190, 106.800, "", nil, intp(5)),
+    ).queue(1, "acme", "T1", "T2", "T3", "T4")
+
+    recs := s.recommend()
+    if len(recs) != 2 {
+        t.Fatalf("got moves %v, want two", moves(recs))
+    }
+    if recs[0].TaxiID == recs[1].TaxiID {
+        t.Errorf("taxi %s recommended twice", recs[0].TaxiID)
+    }
+}
+
+func TestRecommendRespectsPrivatePlaces(t *testing.T) {
+    s := newScenario(
+        place(1, -6.200, 106.800, "", intp(1), nil),
+        place(2, -6.201, 106.800, "acme", nil, intp(2)), // nearest, only for acme
+        place(3, -6.220, 106.800, "", nil, intp(2)),
+    ).queue(1, "beta", "B1").queue(1, "acme", "A1").queue(1, "beta", "B2")
+
+    // B1 is the first to arrive and stays
+    recs := s.recommend()
+    equalMoves(t, recs, "A1:1>2", "B2:1>3")
+    for _, r := range recs {
+        if r.ToPlaceID == 2 && s.taxis[r.TaxiID].OperatorID != "acme" {
+            t.Errorf("taxi %s of %s sent to a place of acme", r.TaxiID, s.taxis[r.TaxiID].OperatorID)
+        }
+    }
+
+    // With only the private place asking, other operators' taxis stay put
+    s.places = s.places[:2]
+    equalMoves(t, s.recommend(), "A1:1>2")
+}
+
+func TestRecommendReasons(t *testing.T) {
+    receiver := place(9, -6.300, 106.800, "", nil, intp(100))
+    tests := []struct {
+        name     string
+        capacity models.PlaceCapacity
+        waiting  int
+        moved    int
+        reason   string
+    }{
+        {"over target within spaces", models.PlaceCapacity{Spaces: intp(4), Target: intp(2)}, 3, 1, models.RebalanceOverTarget},
+        {"at spaces", models.PlaceCapacity{Spaces: intp(4), Target: intp(2)}, 4, 2, models.RebalanceOverTarget},
+        {"over spaces", models.PlaceCapacity{Spaces: intp(4), Target: intp(2)}, 5, 3, models.RebalanceOverCapacity},
+        {"over target without spaces", models.PlaceCapacity{Target: intp(2)}, 6, 4, models.RebalanceOverTarget},
+        {"over spaces without target", models.PlaceCapacity{Spaces: intp(3)}, 4, 1, models.RebalanceOverCapacity},
+        {"at spaces without target", models.PlaceCapacity{Spaces: intp(3)}, 3, 0, ""},
+        {"at target", models.PlaceCapacity{Spaces: intp(4), Target: intp(2)}, 2, 0, ""},
+        {"no limits", models.PlaceCapacity{}, 50, 0, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            donor := place(1, -6.200, 106.800, "", tt.capacity.Spaces, tt.capacity.Target)
+            s := newScenario(donor, receiver)
+            for i := 0; i < tt.waiting; i++ {
+                s.queue(1, "acme", fmt.Sprintf("T%02d", i))
+            }
+
+            recs := s.recommend()
+            if len(recs) != tt.moved {
+                t.Fatalf("got moves %v, want %d", moves(recs), tt.moved)
+            }
+            for _, r := range recs {
+                if r.Reason != tt.reason {
+                    t.Errorf("%s: reason %q, want %q", r.TaxiID, r.Reason, tt.reason)
+                }
+            }
+        })
+    }
+}
+
+func TestRecommendSkipsPlacesNotTakingPart(t *testing.T) {
+    depot := place(2, -6.210, 106.800, "", nil, intp(5))
+    depot.Type = models.PlaceTypeDepot
+    unlimited := place(3, -6.220, 106.800, "", nil, nil)
+    unlimited.Capacity = nil
+    broken := place(4, -6.230, 106.800, "", nil, intp(5))
+    broken.Polygon = models.GeoJSONPolygon{Type: "Polygon"}
+
+    s := newScenario(place(1, -6.200, 106.800, "", intp(1), nil), depot, unlimited, broken).
+        queue(1, "acme", "T1", "T2")
+    if recs := s.recommend(); len(recs) != 0 {
+        t.Errorf("got moves %v, want none", moves(recs))
+    }
+
+    // A spare taxi without a known position is not recommended either
+    s = newScenario(place(1, -6.200, 106.800, "", intp(1), nil), place(2, -6.210, 106.800, "", nil, intp(5))).
+        queue(1, "acme", "T1", "T2", "T3")
+    delete(s.taxis, "T2")
+    equalMoves(t, s.recommend(), "T3:1>2")
+}
+
+// Random networks keep every promise of Recommend.
+func TestRecommendInvariants(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    operators := []string{"acme", "beta", "gamma"}
+
+    for run := 0; run < 200; run++ {
+        s := newScenario()
+        for id := 1; id <= 2+rng.Intn(10); id++ {
+            operator := ""
+            if rng.Intn(3) == 0 {
+                operator = operators[rng.Intn(len(operators))]
+            }
+            var spaces, target *int
+            if rng.Intn(4) > 0 {
+                spaces = intp(rng.Intn(8))
+            }
+            if rng.Intn(4) > 0 {
+                target = intp(rng.Intn(6))
+                if spaces != nil && *target > *spaces {
+                    target = spaces
+                }
+            }
+            s.places = append(s.places, place(id, -6.2+rng.Float64()*0.2, 106.8+rng.Float64()*0.2, operator, spaces, target))
+        }
+        taxi := 0
+        for _, p := range s.places {
+            for i := rng.Intn(10); i > 0; i-- {
+                taxi++
+                s.queue(p.PlaceID, operators[rng.Intn(len(operators))], fmt.Sprintf("T%d", taxi))
+            }
+        }
+
+        recs := s.recommend()
+        checkInvariants(t, run, s, recs)
+    }
+}
+
+func checkInvariants(t *testing.T, run int, s *scenario, recs []models.Recommendation) {
+    t.Helper()
+    places := map[int]models.Place{}
+    for _, p := range s.places {
+        places[p.PlaceID] = p
+    }
+
+    moved := map[string]bool{}
+    sent := map[int]int{}
+    given := map[int]int{}
+    for i, r := range recs {
+        if moved[r.TaxiID] {
+            t.Fatalf("run %d: taxi %s moved twice", run, r.TaxiID)
+        }
+        moved[r.TaxiID] = true
+        sent[r.ToPlaceID]++
+        given[r.FromPlaceID]++
+
+        if r.Rank != i+1 {
+            t.Fatalf("run %d: recommendation %d has rank %d", run, i, r.Rank)
+        }
+        if i > 0 && recs[i-1].DistanceKm > r.DistanceKm {
+            t.Fatalf("run %d: recommendations not ranked by distance", run)
+        }
+        to := places[r.ToPlaceID]
+        if to.OperatorID != "" && to.OperatorID != s.taxis[r.TaxiID].OperatorID {
+            t.Fatalf("run %d: taxi %s of %s sent to place %d of %s", run, r.TaxiID, s.taxis[r.TaxiID].OperatorID, to.PlaceID, to.OperatorID)
+        }
+
+        // Only the taxis beyond what the place needs, the last to arrive, move
+        from := places[r.FromPlaceID]
+        needed := from.Capacity.Target
+        if needed == nil {
+            needed = from.Capacity.Spaces
+        }
+        queue := s.queues[r.FromPlaceID]
+        pos := -1
+        for j, e := range queue {
+            if e.TaxiID == r.TaxiID {
+                pos = j
+            }
+        }
+        if needed == nil || pos < *needed {
+            t.Fatalf("run %d: taxi %s at position %d of place %d moved", run, r.TaxiID, pos, from.PlaceID)
+        }
+    }
+
+    for id, n := range sent {
+        p := places[id]
+        if p.Capacity.Target == nil {
+            t.Fatalf("run %d: place %d without target was sent taxis", run, id)
+        }
+        if deficit := *p.Capacity.Target - len(s.queues[id]); n > deficit {
+            t.Fatalf("run %d: place %d sent %d taxis for a deficit of %d", run, id, n, deficit)
+        }
+    }
+    for id := range given {
+        if sent[id] > 0 {
+            t.Fatalf("run %d: place %d both gave and received taxis", run, id)
+        }
+    }
+}
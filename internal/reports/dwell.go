@@ -0,0 +1,14 @@
+// internal/reports/dwell.go
+package reports
+
+// Dwell report groupings.
+const (
+    GroupByPlace = "place"
+    GroupByTaxi  = "taxi"
+    GroupByHour  = "hour"
+)
+
+// ValidGroupBy reports whether groupBy is a known dwell report grouping.
+func ValidGroupBy(groupBy string) bool {
+    return groupBy == GroupByPlace || groupBy == GroupByTaxi || groupBy == GroupByHour
+}
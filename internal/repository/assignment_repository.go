@@ -0,0 +1,428 @@
+// internal/repository/assignment_repository.go
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/lib/pq"
+)
+
+// DefaultOfferTimeout is how long a driver has to accept an offer when the
+// request does not say otherwise.
+const DefaultOfferTimeout = time.Minute
+
+// AssignmentRepository handles dispatch assignments: mappings made for a
+// booking that are offered to the taxi at the head of a place's queue and
+// move through their statuses from there.
+type AssignmentRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the assignments of
+// one operator's taxis.
+func (ar *AssignmentRepository) ForOperator(operatorID string) *AssignmentRepository {
+    scoped := *ar
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// assignmentColumns selects an assignment in the order scanAssignment reads it.
+const assignmentColumns = `
+    m.id, m.place_id, p.place_name, m.taxi_id, t.plate_number, m.status,
+    COALESCE(m.booking_ref, ''), COALESCE(m.pickup_note, ''), m.offered_at, m.expires_at,
+    m.accepted_at, m.picked_up_at, m.cancelled_at, COALESCE(m.cancel_reason, '')`
+
+// assignmentFrom joins the tables assignmentColumns reads.
+const assignmentFrom = `
+    FROM mapping m
+    JOIN places p ON m.place_id = p.place_id
+    JOIN taxis t ON m.taxi_id = t.taxi_id`
+
+// scanAssignment reads one row selected with assignmentColumns.
+func scanAssignment(row interface{ Scan(...interface{}) error }, m *models.Mapping, extra ...interface{}) error {
+    dest := []interface{}{&m.ID, &m.PlaceID, &m.PlaceName, &m.TaxiID, &m.PlateNumber, &m.Status,
+        &m.BookingRef, &m.PickupNote, &m.OfferedAt, &m.ExpiresAt,
+        &m.AcceptedAt, &m.PickedUpAt, &m.CancelledAt, &m.CancelReason}
+    return row.Scan(append(dest, extra...)...)
+}
+
+// Request offers a booking to the first of the operator's taxis waiting in a
+// place's queue. The taxi is dispatched from the queue while the offer is
+// open. It fails with ErrNotFound when no taxi is waiting.
+func (ar *AssignmentRepository) Request(req models.AssignmentRequest, actor string) (*models.Mapping, error) {
+    timeout := DefaultOfferTimeout
+    if req.TimeoutSeconds > 0 {
+        timeout = time.Duration(req.TimeoutSeconds) * time.Second
+    }
+
+    tx, err := ar.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin assignment: %v", err)
+    }
+    defer tx.Rollback()
+
+    id, err := offerNext(tx, req.PlaceID, req.BookingRef, req.PickupNote, timeout, actor, ar.Operator)
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit assignment: %v", err)
+    }
+    return ar.GetAssignment(id)
+}
+
+// offerNext dispatches the first waiting taxi of a place that holds no open
+// offer and was not offered the booking before, and offers it the booking.
+// It returns the ID of the new mapping, or ErrNotFound when no taxi is left.
+func offerNext(tx *sql.Tx, placeID int, bookingRef, note string, timeout time.Duration, actor, operator string) (int, error) {
+    var taxiID string
+    err := tx.QueryRow(`
+        UPDATE place_queue
+        SET dispatched_at = CURRENT_TIMESTAMP
+        WHERE taxi_id = (
+            SELECT q.taxi_id
+            FROM place_queue q
+            WHERE q.place_id = $1 AND q.dispatched_at IS NULL AND `+ownedTaxi("q.taxi_id", 3)+`
+              AND EXISTS (SELECT 1 FROM places WHERE place_id = $1 AND deleted_at IS NULL AND `+visiblePlace("operator_id", 3)+`)
+              AND NOT EXISTS (
+                  SELECT 1 FROM mapping m
+                  WHERE m.taxi_id = q.taxi_id AND (m.booking_ref = $2 OR m.status = 'offered'))
+            ORDER BY q.entered_at, q.taxi_id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING taxi_id`, placeID, bookingRef, operator).Scan(&taxiID)
+    if err != nil {
+        return 0, classify(err, "waiting taxi")
+    }
+
+    var id int
+    err = tx.QueryRow(`
+        INSERT INTO mapping (place_id, taxi_id, status, booking_ref, pickup_note, offered_at, expires_at, updated_at)
+        VALUES ($1, $2, 'offered', $3, NULLIF($4, ''), NOW(), NOW() + $5::integer * INTERVAL '1 second', NOW())
+        RETURNING id`,
+        placeID, taxiID, bookingRef, note, int(timeout/time.Second)).Scan(&id)
+    if err != nil {
+        return 0, fmt.Errorf("failed to offer assignment: %w", classify(err, "assignment"))
+    }
+    if err := recordTransition(tx, id, "", models.MappingOffered, "", actor); err != nil {
+        return 0, err
+    }
+    return id, nil
+}
+
+// recordTransition appends a status change to the history of a mapping.
+func recordTransition(tx execer, mappingID int, from, to, reason, actor string) error {
+    _, err := tx.Exec(`
+        INSERT INTO mapping_transitions (mapping_id, from_status, to_status, reason, actor)
+        VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), NULLIF($5, ''))`,
+        mappingID, from, to, reason, actor)
+    if err != nil {
+        return fmt.Errorf("failed to record mapping transition: %v", err)
+    }
+    return nil
+}
+
+// statusColumn is the column stamped when a mapping enters a status.
+var statusColumn = map[string]string{
+    models.MappingAccepted:  "accepted_at",
+    models.MappingPickedUp:  "picked_up_at",
+    models.MappingCancelled: "cancelled_at",
+}
+
+// moved describes a mapping that changed status.
+type moved struct {
+    from       string
+    taxiID     string
+    operator   string // of the taxi, which owns the booking
+    placeID    int
+    bookingRef string
+    note       string
+    timeout    time.Duration
+}
+
+// transition moves a mapping from one of the from statuses to to within a
+// transaction and records the change. Offers can only be accepted before they
+// expire. A mapping in another status fails with ErrConflict.
+func (ar *AssignmentRepository) transition(tx *sql.Tx, id int, from []string, to, reason, actor string) (*moved, error) {
+    var m moved
+    var timeout sql.NullFloat64
+    err := tx.QueryRow(`
+        UPDATE mapping m
+        SET status = $2, `+statusColumn[to]+` = NOW(), cancel_reason = NULLIF($3, ''), updated_at = NOW()
+        FROM mapping old
+        WHERE m.id = $1 AND old.id = m.id AND m.status = ANY($4)
+          AND (m.status <> 'offered' OR $2 <> 'accepted' OR m.expires_at > NOW())
+          AND `+ownedTaxi("m.taxi_id", 5)+`
+        RETURNING old.status, m.taxi_id, (SELECT operator_id FROM taxis WHERE taxi_id = m.taxi_id),
+            m.place_id, COALESCE(m.booking_ref, ''), COALESCE(m.pickup_note, ''),
+            EXTRACT(EPOCH FROM m.expires_at - m.offered_at)`,
+        id, to, reason, pq.Array(from), ar.Operator).
+        Scan(&m.from, &m.taxiID, &m.operator, &m.placeID, &m.bookingRef, &m.note, &timeout)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, ar.refused(id, to)
+    }
+    if err != nil {
+        return nil, classify(err, "assignment")
+    }
+    m.timeout = time.Duration(timeout.Float64 * float64(time.Second))
+
+    if err := recordTransition(tx, id, m.from, to, reason, actor); err != nil {
+        return nil, err
+    }
+    return &m, nil
+}
+
+// refused explains why a mapping could not enter a status: it does not exist,
+// its offer expired, or it is in a status the change does not start from.
+func (ar *AssignmentRepository) refused(id int, to string) error {
+    m, err := ar.GetAssignment(id)
+    if err != nil {
+        return err
+    }
+    if m.Status == models.MappingOffered && m.ExpiresAt != nil && !m.ExpiresAt.After(time.Now()) {
+        return fmt.Errorf("offer %d expired at %s: %w", id, m.ExpiresAt.Format(time.RFC3339), ErrConflict)
+    }
+    return fmt.Errorf("assignment %d is %s and cannot become %s: %w", id, m.Status, to, ErrConflict)
+}
+
+// Accept accepts an open offer on behalf of its taxi.
+func (ar *AssignmentRepository) Accept(id int, actor string) (*models.Mapping, error) {
+    return ar.change(id, []string{models.MappingOffered}, models.MappingAccepted, "", actor)
+}
+
+// PickUp records that the taxi of an accepted assignment picked the
+// passenger up.
+func (ar *AssignmentRepository) PickUp(id int, actor string) (*models.Mapping, error) {
+    return ar.change(id, []string{models.MappingAccepted}, models.MappingPickedUp, "", actor)
+}
+
+// change moves a mapping to a status that has no side effects and returns it.
+func (ar *AssignmentRepository) change(id int, from []string, to, reason, actor string) (*models.Mapping, error) {
+    tx, err := ar.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin assignment change: %v", err)
+    }
+    defer tx.Rollback()
+
+    if _, err := ar.transition(tx, id, from, to, reason, actor); err != nil {
+        return nil, err
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit assignment change: %v", err)
+    }
+    return ar.GetAssignment(id)
+}
+
+// Reject turns down an open offer on behalf of its taxi, which returns to its
+// place in the queue. The booking is offered to the next waiting taxi.
+func (ar *AssignmentRepository) Reject(id int, actor string) (*models.AssignmentOutcome, error) {
+    return ar.cancel(id, []string{models.MappingOffered}, models.CancelRejected, actor, true)
+}
+
+// Cancel withdraws an offered or accepted assignment. Its taxi returns to
+// its place in the queue; the booking is not offered again.
+func (ar *AssignmentRepository) Cancel(id int, actor string) (*models.AssignmentOutcome, error) {
+    return ar.cancel(id, []string{models.MappingOffered, models.MappingAccepted}, models.CancelDispatcher, actor, false)
+}
+
+// cancel cancels a mapping, puts its taxi back in the queue it was
+// dispatched from and, with reoffer, offers the booking to the next taxi of
+// the same operator. The booking belongs to that operator even when the
+// repository is unscoped, as it is when the scheduler expires offers.
+func (ar *AssignmentRepository) cancel(id int, from []string, reason, actor string, reoffer bool) (*models.AssignmentOutcome, error) {
+    tx, err := ar.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin assignment cancellation: %v", err)
+    }
+    defer tx.Rollback()
+
+    m, err := ar.transition(tx, id, from, models.MappingCancelled, reason, actor)
+    if err != nil {
+        return nil, err
+    }
+
+    // The taxi keeps its original place in the queue if it is still there
+    _, err = tx.Exec(`UPDATE place_queue SET dispatched_at = NULL WHERE taxi_id = $1 AND place_id = $2`, m.taxiID, m.placeID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to requeue taxi: %v", err)
+    }
+
+    nextID := 0
+    if reoffer && m.bookingRef != "" {
+        timeout := m.timeout
+        if timeout <= 0 {
+            timeout = DefaultOfferTimeout
+        }
+        nextID, err = offerNext(tx, m.placeID, m.bookingRef, m.note, timeout, "", m.operator)
+        if errors.Is(err, ErrNotFound) {
+            nextID = 0
+        } else if err != nil {
+            return nil, err
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit assignment cancellation: %v", err)
+    }
+
+    outcome := &models.AssignmentOutcome{}
+    cancelled, err := ar.GetAssignment(id)
+    if err != nil {
+        return nil, err
+    }
+    outcome.Cancelled = *cancelled
+    if nextID > 0 {
+        if outcome.Reoffered, err = ar.GetAssignment(nextID); err != nil {
+            return nil, err
+        }
+    }
+    return outcome, nil
+}
+
+// ExpireOffers cancels the offers whose driver did not answer in time,
+// putting their taxis back in the queue and offering each booking to the
+// next waiting taxi. It returns what happened to every expired offer.
+func (ar *AssignmentRepository) ExpireOffers() ([]models.AssignmentOutcome, error) {
+    rows, err := ar.DB.Query(`SELECT id FROM mapping WHERE status = 'offered' AND expires_at <= NOW() AND `+ownedTaxi("taxi_id", 1)+` ORDER BY expires_at, id`, ar.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query expired offers: %v", err)
+    }
+    var ids []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan expired offer: %v", err)
+        }
+        ids = append(ids, id)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("expired offer iteration error: %v", err)
+    }
+
+    outcomes := []models.AssignmentOutcome{}
+    for _, id := range ids {
+        outcome, err := ar.cancel(id, []string{models.MappingOffered}, models.CancelExpired, "", true)
+        if errors.Is(err, ErrConflict) {
+            // Answered since it was listed
+            continue
+        }
+        if err != nil {
+            return outcomes, err
+        }
+        outcomes = append(outcomes, *outcome)
+    }
+    return outcomes, nil
+}
+
+// AssignmentFilter narrows down which assignments are returned. The zero
+// value matches every mapping.
+type AssignmentFilter struct {
+    Status     string
+    PlaceID    int
+    TaxiID     string
+    BookingRef string
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f AssignmentFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.Status != "" {
+        args = append(args, f.Status)
+        conditions = append(conditions, fmt.Sprintf("m.status = $%d", len(args)))
+    }
+    if f.PlaceID > 0 {
+        args = append(args, f.PlaceID)
+        conditions = append(conditions, fmt.Sprintf("m.place_id = $%d", len(args)))
+    }
+    if f.TaxiID != "" {
+        args = append(args, f.TaxiID)
+        conditions = append(conditions, fmt.Sprintf("m.taxi_id = $%d", len(args)))
+    }
+    if f.BookingRef != "" {
+        args = append(args, f.BookingRef)
+        conditions = append(conditions, fmt.Sprintf("m.booking_ref = $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// GetAssignments retrieves one page of the assignments matching the filter,
+// along with the cursor of the next page.
+func (ar *AssignmentRepository) GetAssignments(filter AssignmentFilter, opts ListOptions) ([]models.Mapping, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, ar.Operator)
+    conditions = append(conditions, ownedTaxi("m.taxi_id", len(args)))
+
+    q, err := opts.build(mappingSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `SELECT ` + assignmentColumns + `, ` + q.SortKey + assignmentFrom + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := ar.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query assignments: %v", err)
+    }
+    defer rows.Close()
+
+    assignments := []models.Mapping{}
+    var keys []cursor
+    for rows.Next() {
+        var m models.Mapping
+        var key string
+        if err := scanAssignment(rows, &m, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan assignment: %v", err)
+        }
+        assignments = append(assignments, m)
+        keys = append(keys, cursor{Key: key, ID: strconv.Itoa(m.ID)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("assignment iteration error: %v", err)
+    }
+
+    assignments, next := trimPage(assignments, keys, opts, mappingSort)
+    return assignments, next, nil
+}
+
+// GetAssignment retrieves a mapping with its status history.
+func (ar *AssignmentRepository) GetAssignment(id int) (*models.Mapping, error) {
+    var m models.Mapping
+    query := `SELECT ` + assignmentColumns + assignmentFrom + ` WHERE m.id = $1 AND ` + ownedTaxi("m.taxi_id", 2)
+    if err := scanAssignment(ar.DB.QueryRow(query, id, ar.Operator), &m); err != nil {
+        return nil, classify(err, "assignment")
+    }
+
+    rows, err := ar.DB.Query(`
+        SELECT COALESCE(from_status, ''), to_status, COALESCE(reason, ''), COALESCE(actor, ''), created_at
+        FROM mapping_transitions
+        WHERE mapping_id = $1
+        ORDER BY id`, id)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query mapping transitions: %v", err)
+    }
+    defer rows.Close()
+
+    m.Transitions = []models.MappingTransition{}
+    for rows.Next() {
+        var t models.MappingTransition
+        if err := rows.Scan(&t.From, &t.To, &t.Reason, &t.Actor, &t.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan mapping transition: %v", err)
+        }
+        m.Transitions = append(m.Transitions, t)
+    }
+    return &m, rows.Err()
+}
@@ -0,0 +1,142 @@
+// internal/repository/audit_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// AuditRepository stores and lists audit log entries.
+type AuditRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the entries
+// recorded for one operator.
+func (ar *AuditRepository) ForOperator(operatorID string) *AuditRepository {
+    scoped := *ar
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// Record appends an entry to the audit log. Entries are stamped with a UTC
+// time taken in Go, which is what since= is compared with.
+func (ar *AuditRepository) Record(entry models.AuditEntry) error {
+    _, err := ar.DB.Exec(`
+        INSERT INTO audit_log (actor, actor_role, operator_id, request_id, action, entity, entity_id, before, after, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, NULLIF($7, ''), $8, $9, $10::timestamp)`,
+        entry.Actor, entry.ActorRole, entry.OperatorID, entry.RequestID,
+        entry.Action, entry.Entity, entry.EntityID, nullJSON(entry.Before), nullJSON(entry.After), time.Now().UTC())
+    if err != nil {
+        return fmt.Errorf("failed to record audit entry: %v", err)
+    }
+    return nil
+}
+
+// AuditFilter narrows down which audit entries are returned. The zero value
+// matches every entry.
+type AuditFilter struct {
+    Entity   string
+    EntityID string
+    Since    *time.Time
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f AuditFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.Entity != "" {
+        args = append(args, f.Entity)
+        conditions = append(conditions, fmt.Sprintf("entity = $%d", len(args)))
+    }
+    if f.EntityID != "" {
+        args = append(args, f.EntityID)
+        conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+    }
+    if f.Since != nil {
+        args = append(args, f.Since.UTC())
+        conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// auditSort whitelists the columns audit lists can be sorted by.
+var auditSort = sortSpec{
+    Columns: map[string]string{
+        "id":         "id",
+        "created_at": "created_at",
+    },
+    Default:   "id",
+    ID:        "id",
+    UpdatedAt: "created_at",
+}
+
+// GetAuditLog retrieves one page of the audit entries matching the filter,
+// along with the cursor of the next page.
+func (ar *AuditRepository) GetAuditLog(filter AuditFilter, opts ListOptions) ([]models.AuditEntry, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, ar.Operator)
+    conditions = append(conditions, operatorIs("operator_id", len(args)))
+
+    q, err := opts.build(auditSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `
+        SELECT id, created_at, actor, actor_role, COALESCE(operator_id, ''), COALESCE(request_id, ''),
+            action, entity, COALESCE(entity_id, ''), before, after, ` + q.SortKey + `
+        FROM audit_log` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := ar.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query audit log: %v", err)
+    }
+    defer rows.Close()
+
+    entries := []models.AuditEntry{}
+    var keys []cursor
+    for rows.Next() {
+        var e models.AuditEntry
+        var before, after []byte
+        var key string
+        if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Actor, &e.ActorRole, &e.OperatorID, &e.RequestID,
+            &e.Action, &e.Entity, &e.EntityID, &before, &after, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan audit entry: %v", err)
+        }
+        e.Before, e.After = jsonOrNull(before), jsonOrNull(after)
+        entries = append(entries, e)
+        keys = append(keys, cursor{Key: key, ID: strconv.FormatInt(e.ID, 10)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("audit log iteration error: %v", err)
+    }
+
+    entries, next := trimPage(entries, keys, opts, auditSort)
+    return entries, next, nil
+}
+
+// nullJSON stores an empty document as SQL NULL.
+func nullJSON(b []byte) interface{} {
+    if len(b) == 0 {
+        return nil
+    }
+    return string(b)
+}
+
+// jsonOrNull reads SQL NULL back as a JSON null.
+func jsonOrNull(b []byte) []byte {
+    if b == nil {
+        return []byte("null")
+    }
+    return b
+}
@@ -0,0 +1,35 @@
+// internal/repository/audit_repository_test.go
+package repository
+
+import (
+    "testing"
+    "time"
+)
+
+// since= is compared with UTC timestamps, so its offset is applied before
+// binding.
+func TestSinceFiltersBindUTC(t *testing.T) {
+    since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+    want := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name       string
+        conditions func() ([]string, []interface{})
+        condition  string
+    }{
+        {"audit", AuditFilter{Entity: "place", Since: &since}.conditions, "created_at >= $2"},
+        {"violation", ViolationFilter{Rule: "max_dwell", Since: &since}.conditions, "v.detected_at >= $2"},
+        {"event", EventFilter{Type: "taxi.offline", Since: &since}.conditions, "created_at >= $2"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            conditions, args := tt.conditions()
+            if len(conditions) != 2 || conditions[1] != tt.condition {
+                t.Fatalf("got conditions %q, want %q last", conditions, tt.condition)
+            }
+            if got := args[1]; got != want {
+                t.Errorf("got arg %v, want %v", got, want)
+            }
+        })
+    }
+}
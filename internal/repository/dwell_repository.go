@@ -0,0 +1,200 @@
+// internal/repository/dwell_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/reports"
+)
+
+// DwellRepository reads dwell sessions and reports on them. Sessions are
+// written by MappingRepository as taxis enter and leave places, with UTC
+// times.
+type DwellRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the dwell sessions
+// of one operator's taxis.
+func (dr *DwellRepository) ForOperator(operatorID string) *DwellRepository {
+    scoped := *dr
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// DwellFilter narrows down which dwell sessions are reported on. The zero
+// value matches every closed session.
+type DwellFilter struct {
+    PlaceID int
+    TaxiID  string
+    From    *time.Time // sessions starting at or after From
+    To      *time.Time // sessions starting before To
+
+    // HourFrom and HourTo keep the sessions starting in [HourFrom, HourTo)
+    // hours of the day, wrapping past midnight when HourFrom > HourTo. Equal
+    // values match every hour.
+    HourFrom int
+    HourTo   int
+    // TimeZone is the IANA time zone hours of the day are taken in, for
+    // both HourFrom and HourTo and grouping by hour. Empty means UTC.
+    TimeZone string
+
+    // IncludeOpen also counts sessions still in progress, as lasting until now.
+    IncludeOpen bool
+    // OpenOnly keeps only the sessions still in progress.
+    OpenOnly bool
+}
+
+// conditions builds the SQL conditions for the filter on dwell_sessions s.
+func (f DwellFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.PlaceID > 0 {
+        args = append(args, f.PlaceID)
+        conditions = append(conditions, fmt.Sprintf("s.place_id = $%d", len(args)))
+    }
+    if f.TaxiID != "" {
+        args = append(args, f.TaxiID)
+        conditions = append(conditions, fmt.Sprintf("s.taxi_id = $%d", len(args)))
+    }
+    if f.From != nil {
+        args = append(args, f.From.UTC())
+        conditions = append(conditions, fmt.Sprintf("s.started_at >= $%d", len(args)))
+    }
+    if f.To != nil {
+        args = append(args, f.To.UTC())
+        conditions = append(conditions, fmt.Sprintf("s.started_at < $%d", len(args)))
+    }
+    if f.HourFrom != f.HourTo {
+        args = append(args, f.timeZone())
+        hour := startHour(len(args))
+        args = append(args, f.HourFrom, f.HourTo)
+        n := len(args)
+        join := "AND"
+        if f.HourFrom > f.HourTo {
+            join = "OR"
+        }
+        conditions = append(conditions, fmt.Sprintf("(%s >= $%d %s %s < $%d)", hour, n-1, join, hour, n))
+    }
+    switch {
+    case f.OpenOnly:
+        conditions = append(conditions, "s.ended_at IS NULL")
+    case !f.IncludeOpen:
+        conditions = append(conditions, "s.ended_at IS NOT NULL")
+    }
+
+    return conditions, args
+}
+
+// timeZone returns the time zone hours of the day are taken in.
+func (f DwellFilter) timeZone() string {
+    if f.TimeZone == "" {
+        return "UTC"
+    }
+    return f.TimeZone
+}
+
+// startHour is the hour of the day a session s starts in, in the time zone
+// bound to parameter n.
+func startHour(n int) string {
+    return fmt.Sprintf("EXTRACT(HOUR FROM s.started_at AT TIME ZONE 'UTC' AT TIME ZONE $%d)::integer", n)
+}
+
+// where returns the filter and tenant conditions as a WHERE clause.
+func (dr *DwellRepository) where(filter DwellFilter) (string, []interface{}) {
+    conditions, args := filter.conditions()
+    args = append(args, dr.Operator)
+    conditions = append(conditions, ownedTaxi("s.taxi_id", len(args)))
+    return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetDwellReport computes count, average, p50, p90, p99 and maximum dwell
+// time of the sessions matching the filter, grouped by place, taxi or hour of
+// day in the filter's time zone. Open sessions last until now.
+func (dr *DwellRepository) GetDwellReport(filter DwellFilter, groupBy string) (*models.DwellReport, error) {
+    where, args := dr.where(filter)
+    args = append(args, time.Now().UTC())
+    seconds := fmt.Sprintf("EXTRACT(EPOCH FROM COALESCE(s.ended_at, $%d::timestamp) - s.started_at)::double precision", len(args))
+
+    var group string
+    switch groupBy {
+    case reports.GroupByPlace:
+        group = "s.place_id"
+    case reports.GroupByTaxi:
+        group = "s.taxi_id"
+    case reports.GroupByHour:
+        args = append(args, filter.timeZone())
+        group = startHour(len(args))
+    default:
+        return nil, fmt.Errorf("unknown dwell report grouping %q", groupBy)
+    }
+
+    rows, err := dr.DB.Query(`
+        SELECT grp, COUNT(*), AVG(seconds),
+               percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds),
+               percentile_cont(0.9) WITHIN GROUP (ORDER BY seconds),
+               percentile_cont(0.99) WITHIN GROUP (ORDER BY seconds),
+               MAX(seconds)
+        FROM (
+            SELECT `+group+` AS grp,
+                   `+seconds+` AS seconds
+            FROM dwell_sessions s`+where+`
+        ) x
+        GROUP BY grp
+        ORDER BY grp`, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query dwell report: %v", err)
+    }
+    defer rows.Close()
+
+    report := &models.DwellReport{GroupBy: groupBy, Stats: []models.DwellStat{}}
+    for rows.Next() {
+        var stat models.DwellStat
+        if err := rows.Scan(&stat.Group, &stat.Count, &stat.AvgSeconds, &stat.P50Seconds, &stat.P90Seconds, &stat.P99Seconds, &stat.MaxSeconds); err != nil {
+            return nil, fmt.Errorf("failed to scan dwell stat: %v", err)
+        }
+        report.Stats = append(report.Stats, stat)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("dwell report iteration error: %v", err)
+    }
+    return report, nil
+}
+
+// GetDwellSessions lists the sessions matching the filter, oldest first.
+func (dr *DwellRepository) GetDwellSessions(filter DwellFilter) ([]models.DwellSession, error) {
+    where, args := dr.where(filter)
+    rows, err := dr.DB.Query(`
+        SELECT s.id, s.taxi_id, s.place_id, s.place_version, s.started_at, s.ended_at
+        FROM dwell_sessions s`+where+`
+        ORDER BY s.started_at, s.id`, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query dwell sessions: %v", err)
+    }
+    defer rows.Close()
+
+    sessions := []models.DwellSession{}
+    for rows.Next() {
+        var s models.DwellSession
+        var placeVersion sql.NullInt64
+        var endedAt sql.NullTime
+        if err := rows.Scan(&s.ID, &s.TaxiID, &s.PlaceID, &placeVersion, &s.StartedAt, &endedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan dwell session: %v", err)
+        }
+        if placeVersion.Valid {
+            version := int(placeVersion.Int64)
+            s.PlaceVersion = &version
+        }
+        if endedAt.Valid {
+            s.EndedAt = &endedAt.Time
+        }
+        sessions = append(sessions, s)
+    }
+    return sessions, rows.Err()
+}
@@ -0,0 +1,91 @@
+// internal/repository/dwell_repository_test.go
+package repository
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestDwellFilterHourConditions(t *testing.T) {
+    hour := func(n int) string {
+        return fmt.Sprintf("EXTRACT(HOUR FROM s.started_at AT TIME ZONE 'UTC' AT TIME ZONE $%d)::integer", n)
+    }
+    tests := []struct {
+        name      string
+        filter    DwellFilter
+        condition string // the hour condition, empty when there is none
+        args      []interface{}
+    }{
+        {"every hour", DwellFilter{}, "", nil},
+        {"equal hours", DwellFilter{HourFrom: 5, HourTo: 5}, "", nil},
+        {"within a day", DwellFilter{HourFrom: 6, HourTo: 9},
+            "(" + hour(1) + " >= $2 AND " + hour(1) + " < $3)", []interface{}{"UTC", 6, 9}},
+        {"past midnight", DwellFilter{HourFrom: 22, HourTo: 6},
+            "(" + hour(1) + " >= $2 OR " + hour(1) + " < $3)", []interface{}{"UTC", 22, 6}},
+        {"up to midnight", DwellFilter{HourFrom: 22, HourTo: 0},
+            "(" + hour(1) + " >= $2 OR " + hour(1) + " < $3)", []interface{}{"UTC", 22, 0}},
+        {"in a time zone", DwellFilter{HourFrom: 6, HourTo: 9, TimeZone: "Asia/Jakarta"},
+            "(" + hour(1) + " >= $2 AND " + hour(1) + " < $3)", []interface{}{"Asia/Jakarta", 6, 9}},
+        {"after other conditions", DwellFilter{PlaceID: 3, TaxiID: "T1", HourFrom: 22, HourTo: 6},
+            "(" + hour(3) + " >= $4 OR " + hour(3) + " < $5)", []interface{}{3, "T1", "UTC", 22, 6}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            conditions, args := tt.filter.conditions()
+
+            var hour string
+            for _, c := range conditions {
+                if strings.Contains(c, "EXTRACT(HOUR") {
+                    hour = c
+                }
+            }
+            if hour != tt.condition {
+                t.Errorf("got condition %q, want %q", hour, tt.condition)
+            }
+            if !reflect.DeepEqual(args, tt.args) {
+                t.Errorf("got args %v, want %v", args, tt.args)
+            }
+        })
+    }
+}
+
+// from= and to= keep their instant whatever offset they were given in, as
+// started_at is stored in UTC.
+func TestDwellFilterWindow(t *testing.T) {
+    from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+    to := time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+    conditions, args := DwellFilter{From: &from, To: &to, IncludeOpen: true}.conditions()
+
+    if got, want := strings.Join(conditions, " AND "), "s.started_at >= $1 AND s.started_at < $2"; got != want {
+        t.Errorf("got %q, want %q", got, want)
+    }
+    want := []interface{}{time.Date(2026, 2, 28, 17, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC)}
+    if !reflect.DeepEqual(args, want) {
+        t.Errorf("got args %v, want %v", args, want)
+    }
+}
+
+func TestDwellFilterOpenSessions(t *testing.T) {
+    tests := []struct {
+        name   string
+        filter DwellFilter
+        want   string
+    }{
+        {"closed only by default", DwellFilter{}, "s.ended_at IS NOT NULL"},
+        {"open included", DwellFilter{IncludeOpen: true}, ""},
+        {"open only", DwellFilter{OpenOnly: true}, "s.ended_at IS NULL"},
+        {"open only wins", DwellFilter{OpenOnly: true, IncludeOpen: true}, "s.ended_at IS NULL"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            conditions, _ := tt.filter.conditions()
+            got := strings.Join(conditions, " AND ")
+            if got != tt.want {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,50 @@
+// internal/repository/errors.go
+package repository
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+
+    "github.com/lib/pq"
+)
+
+// ErrNotFound is returned when the requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a write clashes with existing data, such as a
+// duplicate key or a row that is still referenced elsewhere.
+var ErrConflict = errors.New("conflict")
+
+// PostgreSQL error codes translated by classify.
+const (
+    pgForeignKeyViolation = "23503"
+    pgUniqueViolation     = "23505"
+)
+
+// classify wraps database errors in the repository's sentinel errors so
+// callers can test them with errors.Is. Other errors are returned unchanged.
+func classify(err error, entity string) error {
+    if err == nil {
+        return nil
+    }
+    if errors.Is(err, sql.ErrNoRows) {
+        return fmt.Errorf("%s %w", entity, ErrNotFound)
+    }
+
+    var pqErr *pq.Error
+    if errors.As(err, &pqErr) {
+        switch pqErr.Code {
+        case pgUniqueViolation:
+            return fmt.Errorf("%s already exists: %w", entity, ErrConflict)
+        case pgForeignKeyViolation:
+            return fmt.Errorf("%s is referenced by or references missing data (%s): %w", entity, pqErr.Constraint, ErrConflict)
+        }
+    }
+    return err
+}
+
+// notFound builds the ErrNotFound error for an entity.
+func notFound(entity string) error {
+    return fmt.Errorf("%s %w", entity, ErrNotFound)
+}
@@ -0,0 +1,134 @@
+// internal/repository/event_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// EventRepository stores and lists events.
+type EventRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the events of one
+// operator's taxis.
+func (er *EventRepository) ForOperator(operatorID string) *EventRepository {
+    scoped := *er
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// Record stores an event at a UTC time taken in Go. The event belongs to the
+// operator of its taxi, and references the version of its place in effect
+// now.
+func (er *EventRepository) Record(event models.Event) error {
+    _, err := er.DB.Exec(`
+        INSERT INTO events (type, operator_id, taxi_id, place_id, place_version, data, created_at)
+        VALUES ($1, (SELECT operator_id FROM taxis WHERE taxi_id = $2), NULLIF($2, ''), $3::integer,
+            (SELECT version FROM places WHERE place_id = $3::integer), $4, $5::timestamp)`,
+        event.Type, event.TaxiID, event.PlaceID, nullJSON(event.Data), time.Now().UTC())
+    if err != nil {
+        return fmt.Errorf("failed to record %s event: %v", event.Type, err)
+    }
+    return nil
+}
+
+// EventFilter narrows down which events are returned. The zero value matches
+// every event.
+type EventFilter struct {
+    Type   string
+    TaxiID string
+    Since  *time.Time
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f EventFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.Type != "" {
+        args = append(args, f.Type)
+        conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+    }
+    if f.TaxiID != "" {
+        args = append(args, f.TaxiID)
+        conditions = append(conditions, fmt.Sprintf("taxi_id = $%d", len(args)))
+    }
+    if f.Since != nil {
+        args = append(args, f.Since.UTC())
+        conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// eventSort whitelists the columns event lists can be sorted by.
+var eventSort = sortSpec{
+    Columns: map[string]string{
+        "id":         "id",
+        "created_at": "created_at",
+    },
+    Default:   "id",
+    ID:        "id",
+    UpdatedAt: "created_at",
+}
+
+// GetEvents retrieves one page of the events matching the filter, along with
+// the cursor of the next page.
+func (er *EventRepository) GetEvents(filter EventFilter, opts ListOptions) ([]models.Event, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, er.Operator)
+    conditions = append(conditions, operatorIs("operator_id", len(args)))
+
+    q, err := opts.build(eventSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `
+        SELECT id, created_at, type, COALESCE(operator_id, ''), COALESCE(taxi_id, ''), place_id, place_version, data, ` + q.SortKey + `
+        FROM events` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := er.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query events: %v", err)
+    }
+    defer rows.Close()
+
+    events := []models.Event{}
+    var keys []cursor
+    for rows.Next() {
+        var e models.Event
+        var placeID, placeVersion sql.NullInt64
+        var data []byte
+        var key string
+        if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Type, &e.OperatorID, &e.TaxiID, &placeID, &placeVersion, &data, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan event: %v", err)
+        }
+        if placeID.Valid {
+            id := int(placeID.Int64)
+            e.PlaceID = &id
+        }
+        if placeVersion.Valid {
+            version := int(placeVersion.Int64)
+            e.PlaceVersion = &version
+        }
+        e.Data = jsonOrNull(data)
+        events = append(events, e)
+        keys = append(keys, cursor{Key: key, ID: strconv.FormatInt(e.ID, 10)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("event iteration error: %v", err)
+    }
+
+    events, next := trimPage(events, keys, opts, eventSort)
+    return events, next, nil
+}
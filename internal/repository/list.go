@@ -0,0 +1,138 @@
+// internal/repository/list.go
+package repository
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// ErrInvalidSort is returned when a list is sorted by a column that is not whitelisted.
+var ErrInvalidSort = errors.New("invalid sort column")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or
+// was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListOptions controls pagination, ordering and incremental sync for list queries.
+// The zero value returns every row in the resource's default order.
+type ListOptions struct {
+    Limit        int    // 0 means no limit
+    Cursor       string // next_cursor of the previous page
+    Sort         string // whitelisted column name; empty means the resource default
+    Desc         bool
+    UpdatedSince *time.Time
+}
+
+// sortSpec describes the sortable columns of one resource.
+type sortSpec struct {
+    Columns   map[string]string // sort name -> SQL expression
+    Default   string
+    ID        string // SQL expression of the unique tiebreaker
+    UpdatedAt string // SQL expression of the modification time
+}
+
+// cursor is the decoded form of a pagination cursor. It pins the sort order so
+// a cursor cannot be replayed against a different one.
+type cursor struct {
+    Sort string `json:"s"`
+    Desc bool   `json:"d,omitempty"`
+    Key  string `json:"k"`
+    ID   string `json:"id"`
+}
+
+// pageQuery is the SQL produced for one page of a list.
+type pageQuery struct {
+    Where   string // including the leading WHERE, or empty
+    OrderBy string // including the leading ORDER BY
+    SortKey string // expression to select so the next cursor can be built
+    Args    []interface{}
+}
+
+// build combines resource-specific conditions with the keyset and
+// updated_since conditions of the options.
+func (o ListOptions) build(spec sortSpec, conditions []string, args []interface{}) (pageQuery, error) {
+    sortName := o.Sort
+    if sortName == "" {
+        sortName = spec.Default
+    }
+    sortExpr, ok := spec.Columns[sortName]
+    if !ok {
+        return pageQuery{}, ErrInvalidSort
+    }
+
+    // Modification times have no time zone and are stored as UTC
+    if o.UpdatedSince != nil {
+        args = append(args, o.UpdatedSince.UTC())
+        conditions = append(conditions, fmt.Sprintf("%s >= $%d", spec.UpdatedAt, len(args)))
+    }
+
+    if o.Cursor != "" {
+        c, err := decodeCursor(o.Cursor)
+        if err != nil || c.Sort != sortName || c.Desc != o.Desc {
+            return pageQuery{}, ErrInvalidCursor
+        }
+        op := ">"
+        if o.Desc {
+            op = "<"
+        }
+        args = append(args, c.Key, c.ID)
+        conditions = append(conditions, fmt.Sprintf("(%s, %s) %s ($%d, $%d)", sortExpr, spec.ID, op, len(args)-1, len(args)))
+    }
+
+    dir := "ASC"
+    if o.Desc {
+        dir = "DESC"
+    }
+
+    q := pageQuery{
+        OrderBy: fmt.Sprintf(" ORDER BY %s %s, %s %s", sortExpr, dir, spec.ID, dir),
+        SortKey: sortExpr,
+        Args:    args,
+    }
+    if len(conditions) > 0 {
+        q.Where = " WHERE " + strings.Join(conditions, " AND ")
+    }
+    return q, nil
+}
+
+// full reports whether enough rows were collected to know another page exists.
+func (o ListOptions) full(n int) bool {
+    return o.Limit > 0 && n > o.Limit
+}
+
+// trimPage cuts a result collected with one extra row down to the page size
+// and returns the cursor of the next page, or nil on the last page.
+func trimPage[T any](items []T, keys []cursor, o ListOptions, spec sortSpec) ([]T, *string) {
+    if !o.full(len(items)) {
+        return items, nil
+    }
+
+    last := keys[o.Limit-1]
+    last.Sort = o.Sort
+    if last.Sort == "" {
+        last.Sort = spec.Default
+    }
+    last.Desc = o.Desc
+
+    next := encodeCursor(last)
+    return items[:o.Limit], &next
+}
+
+func encodeCursor(c cursor) string {
+    b, _ := json.Marshal(c)
+    return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+    var c cursor
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return c, err
+    }
+    err = json.Unmarshal(b, &c)
+    return c, err
+}
@@ -0,0 +1,23 @@
+// internal/repository/list_test.go
+package repository
+
+import (
+    "testing"
+    "time"
+)
+
+func TestListOptionsUpdatedSince(t *testing.T) {
+    spec := sortSpec{Columns: map[string]string{"id": "id"}, Default: "id", ID: "id", UpdatedAt: "updated_at"}
+    since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+    q, err := ListOptions{UpdatedSince: &since}.build(spec, []string{"x = $1"}, []interface{}{"x"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := " WHERE x = $1 AND updated_at >= $2"; q.Where != want {
+        t.Errorf("got %q, want %q", q.Where, want)
+    }
+    if got, want := q.Args[1], time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC); got != want {
+        t.Errorf("got arg %v, want %v", got, want)
+    }
+}
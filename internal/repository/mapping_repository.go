@@ -5,82 +5,128 @@ package repository
 import (
     "database/sql"
     "fmt"
-   
+    "strconv"
+    "time"
+
     "github.com/SangBejoo/parking-space-monitor/internal/models"
 )
 
 // MappingRepository handles operations related to mappings.
 type MappingRepository struct {
-    DB *sql.DB
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the mappings of one
+// operator's taxis.
+func (mr *MappingRepository) ForOperator(operatorID string) *MappingRepository {
+    scoped := *mr
+    scoped.Operator = operatorID
+    return &scoped
 }
 
-// InsertMapping inserts a new mapping into the mapping table.
-func (mr *MappingRepository) InsertMapping(mapping models.Mapping) error {
+// InsertMapping inserts a new mapping into the mapping table and returns its
+// ID. The taxi must belong to the operator and the place must be visible to it.
+func (mr *MappingRepository) InsertMapping(mapping models.Mapping) (int, error) {
     query := `
         INSERT INTO mapping (place_id, taxi_id)
-        VALUES ($1, $2)
+        SELECT $1::integer, $2::text
+        WHERE ` + ownedTaxi("$2::text", 3) + `
+          AND EXISTS (SELECT 1 FROM places WHERE place_id = $1 AND deleted_at IS NULL AND ` + visiblePlace("operator_id", 3) + `)
+        RETURNING id
     `
-    _, err := mr.DB.Exec(query, mapping.PlaceID, mapping.TaxiID)
+    var id int
+    err := mr.DB.QueryRow(query, mapping.PlaceID, mapping.TaxiID, mr.Operator).Scan(&id)
     if err != nil {
-        return fmt.Errorf("failed to insert mapping: %w", err)
+        return 0, fmt.Errorf("failed to insert mapping: %w", classify(err, "taxi or place"))
     }
-    return nil
+    return id, nil
 }
 
-// GetAllMappings retrieves all mappings with place names and taxi numbers.
-func (mr *MappingRepository) GetAllMappings() ([]models.Mapping, error) {
+// mappingSort whitelists the columns mapping lists can be sorted by.
+var mappingSort = sortSpec{
+    Columns: map[string]string{
+        "id":         "m.id",
+        "place_id":   "m.place_id",
+        "taxi_id":    "m.taxi_id",
+        "updated_at": "m.updated_at",
+    },
+    Default:   "id",
+    ID:        "m.id",
+    UpdatedAt: "m.updated_at",
+}
+
+// GetAllMappings retrieves one page of mappings with place names and plate
+// numbers, along with the cursor of the next page.
+func (mr *MappingRepository) GetAllMappings(opts ListOptions) ([]models.Mapping, *string, error) {
+    q, err := opts.build(mappingSort, []string{ownedTaxi("m.taxi_id", 1)}, []interface{}{mr.Operator})
+    if err != nil {
+        return nil, nil, err
+    }
+
     query := `
-        SELECT m.id, p.place_name, t.nomor_taxi
+        SELECT m.id, m.place_id, p.place_name, m.taxi_id, t.plate_number, m.status, ` + q.SortKey + `
         FROM mapping m
         JOIN places p ON m.place_id = p.place_id
-        JOIN taxi t ON m.taxi_id = t.id
-    `
+        JOIN taxis t ON m.taxi_id = t.taxi_id` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
 
-    rows, err := mr.DB.Query(query)
+    rows, err := mr.DB.Query(query, q.Args...)
     if err != nil {
-        return nil, fmt.Errorf("failed to query mappings: %v", err)
+        return nil, nil, fmt.Errorf("failed to query mappings: %v", err)
     }
     defer rows.Close()
 
-    var mappings []models.Mapping
+    mappings := []models.Mapping{}
+    var keys []cursor
     for rows.Next() {
         var mapping models.Mapping
-        if err := rows.Scan(&mapping.ID, &mapping.PlaceName, &mapping.TaxiID); err != nil {
-            return nil, fmt.Errorf("failed to scan mapping: %v", err)
+        var key string
+        if err := rows.Scan(&mapping.ID, &mapping.PlaceID, &mapping.PlaceName, &mapping.TaxiID, &mapping.PlateNumber, &mapping.Status, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan mapping: %v", err)
         }
         mappings = append(mappings, mapping)
+        keys = append(keys, cursor{Key: key, ID: strconv.Itoa(mapping.ID)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("mapping iteration error: %v", err)
     }
 
-    return mappings, nil
+    mappings, next := trimPage(mappings, keys, opts, mappingSort)
+    return mappings, next, nil
 }
 
 // GetMappingByID retrieves a mapping by its ID.
 func (mr *MappingRepository) GetMappingByID(mappingID int) (*models.Mapping, error) {
     var mapping models.Mapping
     query := `
-        SELECT m.id, p.place_name, t.nomor_taxi
+        SELECT m.id, m.place_id, p.place_name, m.taxi_id, t.plate_number, m.status
         FROM mapping m
         JOIN places p ON m.place_id = p.place_id
-        JOIN taxi t ON m.taxi_id = t.id
-        WHERE m.id = $1
+        JOIN taxis t ON m.taxi_id = t.taxi_id
+        WHERE m.id = $1 AND ` + ownedTaxi("m.taxi_id", 2) + `
     `
-    err := mr.DB.QueryRow(query, mappingID).Scan(&mapping.ID, &mapping.PlaceName, &mapping.TaxiID)
+    err := mr.DB.QueryRow(query, mappingID, mr.Operator).Scan(&mapping.ID, &mapping.PlaceID, &mapping.PlaceName, &mapping.TaxiID, &mapping.PlateNumber, &mapping.Status)
     if err != nil {
-        return nil, err
+        return nil, classify(err, "mapping")
     }
     return &mapping, nil
 }
 
-// UpdateMapping updates an existing mapping.
+// UpdateMapping updates an existing mapping. Both the current and the new
+// taxi must belong to the operator.
 func (mr *MappingRepository) UpdateMapping(mapping models.Mapping) error {
     query := `
         UPDATE mapping
-        SET place_id = $1, taxi_id = $2
-        WHERE id = $3
+        SET place_id = $1, taxi_id = $2, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3 AND ` + ownedTaxi("taxi_id", 4) + ` AND ` + ownedTaxi("$2::text", 4) + `
+          AND EXISTS (SELECT 1 FROM places WHERE place_id = $1 AND deleted_at IS NULL AND ` + visiblePlace("operator_id", 4) + `)
     `
-    res, err := mr.DB.Exec(query, mapping.PlaceID, mapping.TaxiID, mapping.ID)
+    res, err := mr.DB.Exec(query, mapping.PlaceID, mapping.TaxiID, mapping.ID, mr.Operator)
     if err != nil {
-        return err
+        return classify(err, "mapping")
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -88,7 +134,7 @@ func (mr *MappingRepository) UpdateMapping(mapping models.Mapping) error {
         return err
     }
     if rowsAffected == 0 {
-        return fmt.Errorf("mapping not found")
+        return notFound("mapping")
     }
 
     return nil
@@ -96,9 +142,9 @@ func (mr *MappingRepository) UpdateMapping(mapping models.Mapping) error {
 
 // DeleteMapping deletes a mapping by its ID.
 func (mr *MappingRepository) DeleteMapping(mappingID int) error {
-    res, err := mr.DB.Exec("DELETE FROM mapping WHERE id = $1", mappingID)
+    res, err := mr.DB.Exec("DELETE FROM mapping WHERE id = $1 AND "+ownedTaxi("taxi_id", 2), mappingID, mr.Operator)
     if err != nil {
-        return err
+        return classify(err, "mapping")
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -106,22 +152,114 @@ func (mr *MappingRepository) DeleteMapping(mappingID int) error {
         return err
     }
     if rowsAffected == 0 {
-        return fmt.Errorf("mapping not found")
+        return notFound("mapping")
     }
 
     return nil
 }
 
-// UpdateTaxiDuration updates the duration a taxi has spent in a place.
+// UpdateTaxiDuration updates the duration a taxi has spent in a place,
+// recording the place version in effect. The row is created on the taxi's
+// first sighting. A taxi arriving at a new place ends its open dwell session
+// and starts one there.
+//
+// Dwell times are stamped with a UTC time taken in Go, as the columns have
+// no time zone and the session's need not be UTC.
 func (mr *MappingRepository) UpdateTaxiDuration(taxiID string, placeID int) error {
-    query := `UPDATE taxi_durations SET place_id = $1, updated_at = NOW() WHERE taxi_id = $2`
-    _, err := mr.DB.Exec(query, placeID, taxiID)
-    return err
+    now := time.Now().UTC()
+    tx, err := mr.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to begin taxi duration update: %v", err)
+    }
+    defer tx.Rollback()
+
+    query := `
+        INSERT INTO taxi_durations (taxi_id, place_id, place_version, updated_at)
+        SELECT $2::text, $1::integer, (SELECT version FROM places WHERE place_id = $1), $4::timestamp
+        WHERE ` + ownedTaxi("$2::text", 3) + `
+        ON CONFLICT (taxi_id) DO UPDATE
+        SET place_id = EXCLUDED.place_id,
+            place_version = EXCLUDED.place_version,
+            updated_at = EXCLUDED.updated_at
+    `
+    res, err := tx.Exec(query, placeID, taxiID, mr.Operator, now)
+    if err != nil {
+        return err
+    }
+    if n, err := res.RowsAffected(); err != nil || n == 0 {
+        return err
+    }
+
+    _, err = tx.Exec(`UPDATE dwell_sessions SET ended_at = $3::timestamp WHERE taxi_id = $1 AND ended_at IS NULL AND place_id <> $2`, taxiID, placeID, now)
+    if err != nil {
+        return fmt.Errorf("failed to end dwell session: %v", err)
+    }
+    _, err = tx.Exec(`
+        INSERT INTO dwell_sessions (taxi_id, place_id, place_version, started_at)
+        SELECT $1::text, place_id, version, $3::timestamp FROM places WHERE place_id = $2
+        ON CONFLICT (taxi_id) WHERE ended_at IS NULL DO NOTHING`, taxiID, placeID, now)
+    if err != nil {
+        return fmt.Errorf("failed to start dwell session: %v", err)
+    }
+
+    return tx.Commit()
 }
 
-// ResetTaxiDuration resets the duration a taxi has spent in any place.
+// ResetTaxiDuration resets the duration a taxi has spent in any place and
+// ends its open dwell session.
 func (mr *MappingRepository) ResetTaxiDuration(taxiID string) error {
-    query := `UPDATE taxi_durations SET place_id = NULL, updated_at = NOW() WHERE taxi_id = $1`
-    _, err := mr.DB.Exec(query, taxiID)
-    return err
-}
\ No newline at end of file
+    tx, err := mr.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to begin taxi duration reset: %v", err)
+    }
+    defer tx.Rollback()
+
+    if err := resetTaxiDuration(tx, taxiID, mr.Operator); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// resetTaxiDuration takes a taxi out of any place within a transaction.
+func resetTaxiDuration(tx execer, taxiID, operator string) error {
+    now := time.Now().UTC()
+    query := `UPDATE taxi_durations SET place_id = NULL, place_version = NULL, updated_at = $3::timestamp WHERE taxi_id = $1 AND ` + ownedTaxi("taxi_id", 2)
+    if _, err := tx.Exec(query, taxiID, operator, now); err != nil {
+        return err
+    }
+    query = `UPDATE dwell_sessions SET ended_at = $3::timestamp WHERE taxi_id = $1 AND ended_at IS NULL AND ` + ownedTaxi("taxi_id", 2)
+    if _, err := tx.Exec(query, taxiID, operator, now); err != nil {
+        return fmt.Errorf("failed to end dwell session: %v", err)
+    }
+    return nil
+}
+
+// GetAllTaxiDurations retrieves the current dwell state of every taxi.
+func (mr *MappingRepository) GetAllTaxiDurations() ([]models.TaxiDuration, error) {
+    query := "SELECT taxi_id, place_id, place_version, updated_at FROM taxi_durations WHERE " + ownedTaxi("taxi_id", 1) + " ORDER BY taxi_id"
+    rows, err := mr.DB.Query(query, mr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query taxi durations: %v", err)
+    }
+    defer rows.Close()
+
+    durations := []models.TaxiDuration{}
+    for rows.Next() {
+        var d models.TaxiDuration
+        var placeID, placeVersion sql.NullInt64
+        if err := rows.Scan(&d.TaxiID, &placeID, &placeVersion, &d.UpdatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan taxi duration: %v", err)
+        }
+        if placeID.Valid {
+            id := int(placeID.Int64)
+            d.PlaceID = &id
+        }
+        if placeVersion.Valid {
+            version := int(placeVersion.Int64)
+            d.PlaceVersion = &version
+        }
+        durations = append(durations, d)
+    }
+
+    return durations, rows.Err()
+}
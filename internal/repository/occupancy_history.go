@@ -0,0 +1,222 @@
+// internal/repository/occupancy_history.go
+package repository
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// Occupancy history resolutions, finest first. Each is also the date_trunc
+// field of its buckets.
+//
+// Bucket starts are UTC. Every clock reading is taken in Go and bound as a
+// UTC time rather than read from the database, whose session time zone need
+// not be UTC.
+var occupancyResolutions = []string{"minute", "hour", "day"}
+
+// OccupancyStep returns the bucket length of a resolution, or false for an
+// unknown one.
+func OccupancyStep(resolution string) (time.Duration, bool) {
+    switch resolution {
+    case "minute":
+        return time.Minute, true
+    case "hour":
+        return time.Hour, true
+    case "day":
+        return 24 * time.Hour, true
+    }
+    return 0, false
+}
+
+// OccupancyRetention says how long rolled-up buckets of each resolution are
+// kept. A zero duration keeps them forever.
+type OccupancyRetention struct {
+    Minute time.Duration
+    Hour   time.Duration
+    Day    time.Duration
+}
+
+// DefaultOccupancyRetention keeps minute buckets for a week, hour buckets for
+// 90 days and day buckets forever.
+var DefaultOccupancyRetention = OccupancyRetention{
+    Minute: 7 * 24 * time.Hour,
+    Hour:   90 * 24 * time.Hour,
+}
+
+func (r OccupancyRetention) of(resolution string) time.Duration {
+    switch resolution {
+    case "minute":
+        return r.Minute
+    case "hour":
+        return r.Hour
+    }
+    return r.Day
+}
+
+// RecordOccupancy adds the current number of taxis inside every live place as
+// a sample to the place's minute bucket. The scheduler calls it after each
+// run.
+func (pr *PlaceRepository) RecordOccupancy() error {
+    now := time.Now().UTC()
+    _, err := pr.DB.Exec(`
+        INSERT INTO occupancy_samples (place_id, resolution, bucket_start, samples, min_count, max_count, sum_count)
+        SELECT p.place_id, 'minute', date_trunc('minute', $2::timestamp), 1, COUNT(d.taxi_id), COUNT(d.taxi_id), COUNT(d.taxi_id)
+        FROM places p
+        LEFT JOIN taxi_durations d ON d.place_id = p.place_id
+        WHERE p.deleted_at IS NULL AND ` + visiblePlace("p.operator_id", 1) + `
+        GROUP BY p.place_id
+        ON CONFLICT (place_id, resolution, bucket_start) DO UPDATE
+        SET samples = occupancy_samples.samples + EXCLUDED.samples,
+            min_count = LEAST(occupancy_samples.min_count, EXCLUDED.min_count),
+            max_count = GREATEST(occupancy_samples.max_count, EXCLUDED.max_count),
+            sum_count = occupancy_samples.sum_count + EXCLUDED.sum_count`, pr.Operator, now)
+    if err != nil {
+        return fmt.Errorf("failed to record occupancy: %v", err)
+    }
+    return nil
+}
+
+// GetOccupancyHistory returns the occupancy of a visible place over
+// [from, to) in buckets of the given resolution. Finer buckets that have not
+// been rolled up yet are aggregated on the fly, so recent periods are complete
+// before compaction has run.
+func (pr *PlaceRepository) GetOccupancyHistory(placeID int, resolution string, from, to time.Time) (*models.OccupancyHistory, error) {
+    if _, ok := OccupancyStep(resolution); !ok {
+        return nil, fmt.Errorf("unknown occupancy resolution %q", resolution)
+    }
+
+    var exists bool
+    err := pr.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM places WHERE place_id = $1 AND "+visiblePlace("operator_id", 2)+")",
+        placeID, pr.Operator).Scan(&exists)
+    if err != nil {
+        return nil, fmt.Errorf("failed to check place: %v", err)
+    }
+    if !exists {
+        return nil, notFound("place")
+    }
+
+    rows, err := pr.DB.Query(`
+        SELECT date_trunc($2, bucket_start) AS start,
+               SUM(samples), MIN(min_count), MAX(max_count), SUM(sum_count)
+        FROM occupancy_samples
+        WHERE place_id = $1
+          AND (resolution = $2 OR (resolution = ANY (string_to_array($5, ',')) AND NOT rolled_up))
+          AND bucket_start >= date_trunc($2, $3::timestamp) AND bucket_start < $4::timestamp
+        GROUP BY 1
+        ORDER BY 1`,
+        placeID, resolution, from.UTC(), to.UTC(), finerResolutions(resolution))
+    if err != nil {
+        return nil, fmt.Errorf("failed to query occupancy history: %v", err)
+    }
+    defer rows.Close()
+
+    history := &models.OccupancyHistory{PlaceID: placeID, Step: resolution, From: from.UTC(), To: to.UTC(), Points: []models.OccupancyPoint{}}
+    for rows.Next() {
+        var point models.OccupancyPoint
+        var sum int64
+        if err := rows.Scan(&point.Start, &point.Samples, &point.Min, &point.Max, &sum); err != nil {
+            return nil, fmt.Errorf("failed to scan occupancy point: %v", err)
+        }
+        point.Start = point.Start.UTC()
+        if point.Samples > 0 {
+            point.Avg = float64(sum) / float64(point.Samples)
+        }
+        history.Points = append(history.Points, point)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("occupancy history iteration error: %v", err)
+    }
+    return history, nil
+}
+
+// finerResolutions lists the resolutions finer than resolution, comma-separated.
+func finerResolutions(resolution string) string {
+    finer := ""
+    for _, res := range occupancyResolutions {
+        if res == resolution {
+            break
+        }
+        if finer != "" {
+            finer += ","
+        }
+        finer += res
+    }
+    return finer
+}
+
+// CompactOccupancy rolls every closed minute bucket into its hour bucket and
+// every closed hour bucket into its day bucket, then removes rolled-up buckets
+// older than their retention. Buckets that have not been rolled up are never
+// removed. It runs in one transaction and may be repeated safely.
+func (pr *PlaceRepository) CompactOccupancy(retention OccupancyRetention) (*models.OccupancyCompaction, error) {
+    if pr.Operator != "" {
+        return nil, errors.New("occupancy compaction must run unscoped")
+    }
+
+    result := &models.OccupancyCompaction{RolledUp: map[string]int{}, Removed: map[string]int{}}
+    now := time.Now().UTC()
+
+    tx, err := pr.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin occupancy compaction: %v", err)
+    }
+    defer tx.Rollback()
+
+    for i := 0; i+1 < len(occupancyResolutions); i++ {
+        source, target := occupancyResolutions[i], occupancyResolutions[i+1]
+
+        // Only buckets inside a closed target bucket are rolled up; they
+        // receive no further samples
+        _, err := tx.Exec(`
+            INSERT INTO occupancy_samples (place_id, resolution, bucket_start, samples, min_count, max_count, sum_count)
+            SELECT place_id, $2::text, date_trunc($2, bucket_start), SUM(samples), MIN(min_count), MAX(max_count), SUM(sum_count)
+            FROM occupancy_samples
+            WHERE resolution = $1 AND NOT rolled_up AND bucket_start < date_trunc($2, $3::timestamp)
+            GROUP BY place_id, date_trunc($2, bucket_start)
+            ON CONFLICT (place_id, resolution, bucket_start) DO UPDATE
+            SET samples = occupancy_samples.samples + EXCLUDED.samples,
+                min_count = LEAST(occupancy_samples.min_count, EXCLUDED.min_count),
+                max_count = GREATEST(occupancy_samples.max_count, EXCLUDED.max_count),
+                sum_count = occupancy_samples.sum_count + EXCLUDED.sum_count`, source, target, now)
+        if err != nil {
+            return nil, fmt.Errorf("failed to roll up %s buckets: %v", source, err)
+        }
+
+        res, err := tx.Exec(`
+            UPDATE occupancy_samples SET rolled_up = TRUE
+            WHERE resolution = $1 AND NOT rolled_up AND bucket_start < date_trunc($2, $3::timestamp)`, source, target, now)
+        if err != nil {
+            return nil, fmt.Errorf("failed to mark %s buckets: %v", source, err)
+        }
+        if n, err := res.RowsAffected(); err == nil {
+            result.RolledUp[source] = int(n)
+        }
+    }
+
+    for _, res := range occupancyResolutions {
+        keep := retention.of(res)
+        if keep <= 0 {
+            continue
+        }
+        query := "DELETE FROM occupancy_samples WHERE resolution = $1 AND bucket_start < $2::timestamp"
+        if res != occupancyResolutions[len(occupancyResolutions)-1] {
+            // The coarsest buckets are never rolled up, so only they go unconditionally
+            query += " AND rolled_up"
+        }
+        deleted, err := tx.Exec(query, res, now.Add(-keep))
+        if err != nil {
+            return nil, fmt.Errorf("failed to remove %s buckets: %v", res, err)
+        }
+        if n, err := deleted.RowsAffected(); err == nil {
+            result.Removed[res] = int(n)
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit occupancy compaction: %v", err)
+    }
+    return result, nil
+}
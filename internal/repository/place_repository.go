@@ -6,31 +6,140 @@ import (
     "fmt"
     "log"
     "encoding/json"
+    "strconv"
+    "time"
 
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/importer"
     "github.com/SangBejoo/parking-space-monitor/internal/models"
 )
 
 // PlaceRepository handles CRUD operations for Place.
 type PlaceRepository struct {
-    DB *sql.DB
+    DB       *sql.DB
+    Operator string // empty means unscoped
 }
 
-// internal/repository/place_repository.go
-
-// internal/repository/place_repository.go
+// ForOperator returns a copy of the repository scoped to the shared places
+// and the private places of one operator.
+func (pr *PlaceRepository) ForOperator(operatorID string) *PlaceRepository {
+    scoped := *pr
+    scoped.Operator = operatorID
+    return &scoped
+}
 
+// CreatePlace creates a place as its first version. A scoped repository
+// always creates places private to its operator; unscoped, place.OperatorID
+// decides.
 func (pr *PlaceRepository) CreatePlace(place models.Place) (int, error) {
+    tx, err := pr.DB.Begin()
+    if err != nil {
+        return 0, fmt.Errorf("failed to begin place creation: %v", err)
+    }
+    defer tx.Rollback()
+
+    operatorID := pr.Operator
+    if operatorID == "" {
+        operatorID = place.OperatorID
+    }
+    if err := provisionOperator(tx, operatorID); err != nil {
+        return 0, err
+    }
+
     var placeID int
-    err := pr.DB.QueryRow(`INSERT INTO places (place_name, polygon) 
-        VALUES ($1, $2) RETURNING place_id`,
-        place.PlaceName, place.Polygon).Scan(&placeID)
+    err = tx.QueryRow(`INSERT INTO places (place_name, polygon, operator_id, place_type, rules, schedule, capacity) 
+        VALUES ($1, $2, COALESCE(NULLIF($3, ''), NULLIF($4, '')), COALESCE(NULLIF($5, ''), 'stand'), $6, $7, $8) RETURNING place_id`,
+        place.PlaceName, place.Polygon, pr.Operator, place.OperatorID, place.Type, place.Rules, place.Schedule, place.Capacity).Scan(&placeID)
     if err != nil {
+        return 0, classify(err, "place")
+    }
+    if err := startPlaceVersion(tx, placeID); err != nil {
         return 0, err
     }
+
+    if err := tx.Commit(); err != nil {
+        return 0, fmt.Errorf("failed to commit place creation: %v", err)
+    }
     return placeID, nil
 }
 
-func (pr *PlaceRepository) GetAllPlaces() ([]models.Place, error) {
+// PlaceFilter narrows down which places are returned. The zero value matches every place.
+type PlaceFilter struct {
+    BBox    *geo.BBox   // places whose polygon intersects the box
+    Near    *geo.LatLon // places whose polygon lies within RadiusM of this point
+    RadiusM float64
+    PlaceID int
+
+    // IncludeDeleted also returns soft-deleted places.
+    IncludeDeleted bool
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f PlaceFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+    if f.PlaceID > 0 {
+        args = append(args, f.PlaceID)
+        conditions = append(conditions, "place_id = $1")
+    }
+    if !f.IncludeDeleted {
+        conditions = append(conditions, "deleted_at IS NULL")
+    }
+    return conditions, args
+}
+
+// exact reports whether conditions alone decide the filter, so SQL can apply LIMIT.
+func (f PlaceFilter) exact() bool {
+    return f.BBox == nil && f.Near == nil
+}
+
+// matches applies the spatial parts of the filter. Polygons are stored as
+// JSON, so these tests run in memory.
+func (f PlaceFilter) matches(place models.Place) bool {
+    if f.exact() {
+        return true
+    }
+
+    rings, err := place.Polygon.OuterRings()
+    if err != nil {
+        return false
+    }
+    // A MultiPolygon matches when any of its parts does
+    for _, ring := range rings {
+        if f.BBox != nil && !geo.PolygonIntersectsBBox(ring, *f.BBox) {
+            continue
+        }
+        if f.Near != nil && geo.DistanceToPolygonKm(f.Near.Lat, f.Near.Lon, ring)*1000 > f.RadiusM {
+            continue
+        }
+        return true
+    }
+    return false
+}
+
+// placeSort whitelists the columns place lists can be sorted by.
+var placeSort = sortSpec{
+    Columns: map[string]string{
+        "place_id":   "place_id",
+        "place_name": "place_name",
+        "updated_at": "updated_at",
+    },
+    Default:   "place_id",
+    ID:        "place_id",
+    UpdatedAt: "updated_at",
+}
+
+// GetAllPlaces retrieves one page of the places matching the filter, along
+// with the cursor of the next page.
+func (pr *PlaceRepository) GetAllPlaces(filter PlaceFilter, opts ListOptions) ([]models.Place, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, pr.Operator)
+    conditions = append(conditions, visiblePlace("operator_id", len(args)))
+    q, err := opts.build(placeSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
     query := `
         SELECT 
             place_id, 
@@ -43,25 +152,37 @@ func (pr *PlaceRepository) GetAllPlaces() ([]models.Place, error) {
                         'coordinates', jsonb_build_array(polygon)
                     )
                 ELSE polygon
-            END as polygon
-        FROM places
-    `
-    
-    rows, err := pr.DB.Query(query)
+            END as polygon,
+            COALESCE(operator_id, ''),
+            version,
+            place_type,
+            rules,
+            schedule,
+            capacity,
+            deleted_at,
+            ` + q.SortKey + `
+        FROM places` + q.Where + q.OrderBy
+    if opts.Limit > 0 && filter.exact() {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := pr.DB.Query(query, q.Args...)
     if err != nil {
         log.Printf("Database query error: %v", err)
-        return nil, fmt.Errorf("database query error: %v", err)
+        return nil, nil, fmt.Errorf("database query error: %v", err)
     }
     defer rows.Close()
 
-    var places []models.Place
-    for rows.Next() {
+    places := []models.Place{}
+    var keys []cursor
+    for rows.Next() && !opts.full(len(places)) {
         var place models.Place
         var polygonBytes []byte
-        
-        if err := rows.Scan(&place.PlaceID, &place.PlaceName, &polygonBytes); err != nil {
+        var key string
+
+        if err := rows.Scan(&place.PlaceID, &place.PlaceName, &polygonBytes, &place.OperatorID, &place.Version, &place.Type, &place.Rules, &place.Schedule, &place.Capacity, &place.DeletedAt, &key); err != nil {
             log.Printf("Row scan error: %v", err)
-            return nil, fmt.Errorf("row scan error: %v", err)
+            return nil, nil, fmt.Errorf("row scan error: %v", err)
         }
 
         log.Printf("Raw polygon data: %s", string(polygonBytes))
@@ -76,37 +197,65 @@ func (pr *PlaceRepository) GetAllPlaces() ([]models.Place, error) {
                 }
             } else {
                 log.Printf("Polygon unmarshal error: %v, data: %s", err, string(polygonBytes))
-                return nil, fmt.Errorf("polygon unmarshal error: %v", err)
+                return nil, nil, fmt.Errorf("polygon unmarshal error: %v", err)
             }
         }
-        
+
+        if !filter.matches(place) {
+            continue
+        }
         places = append(places, place)
+        keys = append(keys, cursor{Key: key, ID: strconv.Itoa(place.PlaceID)})
     }
 
     if err = rows.Err(); err != nil {
         log.Printf("Row iteration error: %v", err)
-        return nil, fmt.Errorf("row iteration error: %v", err)
+        return nil, nil, fmt.Errorf("row iteration error: %v", err)
     }
 
-    return places, nil
+    places, next := trimPage(places, keys, opts, placeSort)
+    return places, next, nil
 }
 // GetPlaceByID retrieves a place by its ID.
 func (pr *PlaceRepository) GetPlaceByID(placeID int) (*models.Place, error) {
     var place models.Place
-    err := pr.DB.QueryRow("SELECT place_id, place_name, polygon FROM places WHERE place_id = $1", placeID).
-        Scan(&place.PlaceID, &place.PlaceName, &place.Polygon)
+    query := "SELECT place_id, place_name, polygon, COALESCE(operator_id, ''), version, place_type, rules, schedule, capacity FROM places WHERE place_id = $1 AND deleted_at IS NULL AND " + visiblePlace("operator_id", 2)
+    err := pr.DB.QueryRow(query, placeID, pr.Operator).
+        Scan(&place.PlaceID, &place.PlaceName, &place.Polygon, &place.OperatorID, &place.Version, &place.Type, &place.Rules, &place.Schedule, &place.Capacity)
     if err != nil {
-        return nil, err
+        return nil, classify(err, "place")
     }
     return &place, nil
 }
 
-// UpdatePlace updates an existing place.
+// UpdatePlace updates an existing place, closing its current version and
+// starting a new one. A scoped repository can only update its operator's
+// private places and cannot change who owns them.
 func (pr *PlaceRepository) UpdatePlace(placeID int, place models.Place) error {
-    res, err := pr.DB.Exec(`UPDATE places SET place_name = $1, polygon = $2 WHERE place_id = $3`,
-        place.PlaceName, place.Polygon, placeID)
+    tx, err := pr.DB.Begin()
     if err != nil {
-        return err
+        return fmt.Errorf("failed to begin place update: %v", err)
+    }
+    defer tx.Rollback()
+
+    // Only an unscoped update can hand the place to another operator
+    if pr.Operator == "" {
+        if err := provisionOperator(tx, place.OperatorID); err != nil {
+            return err
+        }
+    }
+
+    res, err := tx.Exec(`UPDATE places SET place_name = $1, polygon = $2,
+            operator_id = CASE WHEN $4::text = '' THEN NULLIF($5, '') ELSE operator_id END,
+            place_type = COALESCE(NULLIF($6, ''), 'stand'),
+            rules = $7,
+            schedule = $8,
+            capacity = $9,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE place_id = $3 AND deleted_at IS NULL AND `+operatorIs("operator_id", 4),
+        place.PlaceName, place.Polygon, placeID, pr.Operator, place.OperatorID, place.Type, place.Rules, place.Schedule, place.Capacity)
+    if err != nil {
+        return classify(err, "place")
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -114,17 +263,27 @@ func (pr *PlaceRepository) UpdatePlace(placeID int, place models.Place) error {
         return err
     }
     if rowsAffected == 0 {
-        return fmt.Errorf("place not found")
+        return notFound("place")
     }
 
+    if err := startPlaceVersion(tx, placeID); err != nil {
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit place update: %v", err)
+    }
     return nil
 }
 
-// DeletePlace deletes a place by its ID.
+// DeletePlace soft-deletes a place by its ID. The place keeps its mappings,
+// versions and dwell history until it is restored or purged. A scoped
+// repository can only delete its operator's private places. The deletion is
+// stamped with a UTC time taken in Go, which purges compare their cutoff with.
 func (pr *PlaceRepository) DeletePlace(placeID int) error {
-    res, err := pr.DB.Exec("DELETE FROM places WHERE place_id = $1", placeID)
+    res, err := pr.DB.Exec(`UPDATE places SET deleted_at = $3::timestamp
+        WHERE place_id = $1 AND deleted_at IS NULL AND `+operatorIs("operator_id", 2), placeID, pr.Operator, time.Now().UTC())
     if err != nil {
-        return err
+        return classify(err, "place")
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -132,8 +291,211 @@ func (pr *PlaceRepository) DeletePlace(placeID int) error {
         return err
     }
     if rowsAffected == 0 {
-        return fmt.Errorf("place not found")
+        return notFound("place")
     }
 
     return nil
-}
\ No newline at end of file
+}
+
+// RestorePlace undoes the soft delete of a place. It fails with ErrNotFound
+// when the place is not deleted.
+func (pr *PlaceRepository) RestorePlace(placeID int) error {
+    res, err := pr.DB.Exec(`UPDATE places SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+        WHERE place_id = $1 AND deleted_at IS NOT NULL AND `+operatorIs("operator_id", 2), placeID, pr.Operator)
+    if err != nil {
+        return classify(err, "place")
+    }
+
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return notFound("deleted place")
+    }
+    return nil
+}
+
+// GetOccupancy returns the operator's taxis currently inside each place,
+// keyed by place ID.
+func (pr *PlaceRepository) GetOccupancy() (map[int][]string, error) {
+    rows, err := pr.DB.Query(`
+        SELECT place_id, taxi_id
+        FROM taxi_durations
+        WHERE place_id IS NOT NULL AND `+ownedTaxi("taxi_id", 1)+`
+        ORDER BY place_id, taxi_id
+    `, pr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query occupancy: %v", err)
+    }
+    defer rows.Close()
+
+    occupancy := make(map[int][]string)
+    for rows.Next() {
+        var placeID int
+        var taxiID string
+        if err := rows.Scan(&placeID, &taxiID); err != nil {
+            return nil, fmt.Errorf("failed to scan occupancy: %v", err)
+        }
+        occupancy[placeID] = append(occupancy[placeID], taxiID)
+    }
+
+    return occupancy, rows.Err()
+}
+
+// GetOccupancyReport counts the taxis inside every visible place, or only in
+// placeID when it is set. Totals include the taxis of every operator; the
+// per-operator breakdown of a scoped repository only names its own operator.
+func (pr *PlaceRepository) GetOccupancyReport(placeID int) (*models.OccupancyReport, error) {
+    rows, err := pr.DB.Query(`
+        SELECT p.place_id, t.operator_id, COUNT(t.taxi_id)
+        FROM places p
+        LEFT JOIN taxi_durations d ON d.place_id = p.place_id
+        LEFT JOIN taxis t ON t.taxi_id = d.taxi_id
+        WHERE ($1 = 0 OR p.place_id = $1) AND p.deleted_at IS NULL AND `+visiblePlace("p.operator_id", 2)+`
+        GROUP BY p.place_id, t.operator_id
+        ORDER BY p.place_id
+    `, placeID, pr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query occupancy: %v", err)
+    }
+    defer rows.Close()
+
+    report := &models.OccupancyReport{
+        Places: []models.PlaceOccupancy{},
+        Total:  models.OccupancyCount{ByOperator: map[string]int{}},
+    }
+    for rows.Next() {
+        var id, count int
+        var operatorID sql.NullString
+        if err := rows.Scan(&id, &operatorID, &count); err != nil {
+            return nil, fmt.Errorf("failed to scan occupancy: %v", err)
+        }
+
+        n := len(report.Places)
+        if n == 0 || report.Places[n-1].PlaceID != id {
+            report.Places = append(report.Places, models.PlaceOccupancy{
+                PlaceID:        id,
+                OccupancyCount: models.OccupancyCount{ByOperator: map[string]int{}},
+            })
+            n++
+        }
+        place := &report.Places[n-1]
+        place.Total += count
+        report.Total.Total += count
+
+        if operatorID.Valid && (pr.Operator == "" || operatorID.String == pr.Operator) {
+            place.ByOperator[operatorID.String] += count
+            report.Total.ByOperator[operatorID.String] += count
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("occupancy iteration error: %v", err)
+    }
+
+    if placeID > 0 && len(report.Places) == 0 {
+        return nil, notFound("place")
+    }
+    return report, nil
+}
+
+// ImportPlaces creates or updates places by name in a single transaction.
+// A record whose name matches exactly one existing place updates it, an
+// unknown name creates a place, and a name that repeats in the batch or
+// matches several places is a conflict. Nothing is committed when any record
+// is invalid or conflicting, or when dryRun is set; the result then describes
+// what would happen. A scoped repository only matches and creates its
+// operator's private places.
+func (pr *PlaceRepository) ImportPlaces(records []importer.Record, dryRun bool) (*models.ImportResult, error) {
+    result := &models.ImportResult{
+        DryRun:    dryRun,
+        Created:   []models.ImportItem{},
+        Updated:   []models.ImportItem{},
+        Conflicts: []models.ImportItem{},
+        Invalid:   []models.ImportItem{},
+    }
+
+    tx, err := pr.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin import: %v", err)
+    }
+    defer tx.Rollback()
+
+    if err := provisionOperator(tx, pr.Operator); err != nil {
+        return nil, err
+    }
+
+    seen := make(map[string]int)
+    for _, rec := range records {
+        place := rec.Place
+        item := models.ImportItem{Index: rec.Index, PlaceName: place.PlaceName}
+
+        if rec.Err != nil {
+            item.Reason = rec.Err.Error()
+            result.Invalid = append(result.Invalid, item)
+            continue
+        }
+        if first, ok := seen[place.PlaceName]; ok {
+            item.Reason = fmt.Sprintf("duplicate name in import, first seen at record %d", first)
+            result.Conflicts = append(result.Conflicts, item)
+            continue
+        }
+        seen[place.PlaceName] = item.Index
+
+        rows, err := tx.Query("SELECT place_id FROM places WHERE place_name = $1 AND deleted_at IS NULL AND "+operatorIs("operator_id", 2)+" FOR UPDATE",
+            place.PlaceName, pr.Operator)
+        if err != nil {
+            return nil, fmt.Errorf("failed to look up place %q: %v", place.PlaceName, err)
+        }
+        var ids []int
+        for rows.Next() {
+            var id int
+            if err := rows.Scan(&id); err != nil {
+                rows.Close()
+                return nil, fmt.Errorf("failed to scan place id: %v", err)
+            }
+            ids = append(ids, id)
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return nil, fmt.Errorf("failed to look up place %q: %v", place.PlaceName, err)
+        }
+
+        switch len(ids) {
+        case 0:
+            err = tx.QueryRow(`INSERT INTO places (place_name, polygon, operator_id) VALUES ($1, $2, NULLIF($3, '')) RETURNING place_id`,
+                place.PlaceName, place.Polygon, pr.Operator).Scan(&item.PlaceID)
+            if err != nil {
+                return nil, fmt.Errorf("failed to create place %q: %w", place.PlaceName, classify(err, "place"))
+            }
+            if err := startPlaceVersion(tx, item.PlaceID); err != nil {
+                return nil, err
+            }
+            result.Created = append(result.Created, item)
+        case 1:
+            item.PlaceID = ids[0]
+            _, err = tx.Exec(`UPDATE places SET polygon = $1, updated_at = CURRENT_TIMESTAMP WHERE place_id = $2`,
+                place.Polygon, item.PlaceID)
+            if err != nil {
+                return nil, fmt.Errorf("failed to update place %q: %w", place.PlaceName, classify(err, "place"))
+            }
+            if err := startPlaceVersion(tx, item.PlaceID); err != nil {
+                return nil, err
+            }
+            result.Updated = append(result.Updated, item)
+        default:
+            item.Reason = fmt.Sprintf("name matches %d existing places", len(ids))
+            result.Conflicts = append(result.Conflicts, item)
+        }
+    }
+
+    if dryRun || len(result.Conflicts) > 0 || len(result.Invalid) > 0 {
+        return result, nil
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit import: %v", err)
+    }
+    result.Committed = true
+    return result, nil
+}
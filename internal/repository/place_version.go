@@ -0,0 +1,101 @@
+// internal/repository/place_version.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// startPlaceVersion closes the open version of a place and opens the next
+// one from the current places row, which must already hold the new name and
+// polygon. Both ranges meet at one UTC time taken in Go, as the validity
+// columns have no time zone and the session's need not be UTC.
+func startPlaceVersion(tx execer, placeID int) error {
+    now := time.Now().UTC()
+    _, err := tx.Exec(`UPDATE place_versions SET valid_to = $2::timestamp WHERE place_id = $1 AND valid_to IS NULL`, placeID, now)
+    if err != nil {
+        return fmt.Errorf("failed to close place version: %v", err)
+    }
+
+    _, err = tx.Exec(`
+        WITH next AS (
+            SELECT COALESCE(MAX(version), 0) + 1 AS version FROM place_versions WHERE place_id = $1
+        ), bumped AS (
+            UPDATE places p SET version = next.version
+            FROM next
+            WHERE p.place_id = $1
+            RETURNING p.place_id, p.version, p.place_name, p.polygon
+        )
+        INSERT INTO place_versions (place_id, version, place_name, polygon, valid_from)
+        SELECT place_id, version, place_name, polygon, $2::timestamp FROM bumped`, placeID, now)
+    if err != nil {
+        return fmt.Errorf("failed to open place version: %v", err)
+    }
+    return nil
+}
+
+const placeVersionColumns = `v.place_id, v.version, v.place_name, v.polygon, v.valid_from, v.valid_to`
+
+func scanPlaceVersion(scan func(...interface{}) error) (models.PlaceVersion, error) {
+    var v models.PlaceVersion
+    var validTo sql.NullTime
+    err := scan(&v.PlaceID, &v.Version, &v.PlaceName, &v.Polygon, &v.ValidFrom, &validTo)
+    if validTo.Valid {
+        v.ValidTo = &validTo.Time
+    }
+    return v, err
+}
+
+// GetPlaceVersions lists every version of a visible place, oldest first.
+func (pr *PlaceRepository) GetPlaceVersions(placeID int) ([]models.PlaceVersion, error) {
+    rows, err := pr.DB.Query(`
+        SELECT `+placeVersionColumns+`
+        FROM place_versions v
+        JOIN places p ON p.place_id = v.place_id
+        WHERE v.place_id = $1 AND `+visiblePlace("p.operator_id", 2)+`
+        ORDER BY v.version`, placeID, pr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query place versions: %v", err)
+    }
+    defer rows.Close()
+
+    versions := []models.PlaceVersion{}
+    for rows.Next() {
+        v, err := scanPlaceVersion(rows.Scan)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan place version: %v", err)
+        }
+        versions = append(versions, v)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("place version iteration error: %v", err)
+    }
+
+    if len(versions) == 0 {
+        return nil, notFound("place")
+    }
+    return versions, nil
+}
+
+// GetPlaceAt returns the version of a visible place in effect at a time.
+func (pr *PlaceRepository) GetPlaceAt(placeID int, at time.Time) (*models.PlaceVersion, error) {
+    v, err := scanPlaceVersion(pr.DB.QueryRow(`
+        SELECT `+placeVersionColumns+`
+        FROM place_versions v
+        JOIN places p ON p.place_id = v.place_id
+        WHERE v.place_id = $1 AND `+visiblePlace("p.operator_id", 2)+`
+          AND v.valid_from <= $3 AND (v.valid_to IS NULL OR v.valid_to > $3)`,
+        placeID, pr.Operator, at.UTC()).Scan)
+    if err != nil {
+        return nil, classify(err, "place version")
+    }
+    return &v, nil
+}
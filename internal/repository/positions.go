@@ -0,0 +1,76 @@
+// internal/repository/positions.go
+package repository
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+)
+
+// PositionQuery selects taxi positions for aggregation. Without From and To
+// it selects the current location of every taxi that is not offline;
+// otherwise the positions recorded in [From, To).
+type PositionQuery struct {
+    BBox *geo.BBox
+    From *time.Time
+    To   *time.Time
+}
+
+// history reports whether the query reads recorded positions.
+func (q PositionQuery) history() bool {
+    return q.From != nil || q.To != nil
+}
+
+// EachPosition calls fn for every position matching the query, stopping at
+// the first error fn returns. Positions are streamed rather than collected
+// since a history can hold millions of them.
+func (tr *TaxiRepository) EachPosition(q PositionQuery, fn func(taxiID string, lon, lat float64) error) error {
+    var conditions []string
+    var args []interface{}
+
+    table := "taxi_location t"
+    if q.history() {
+        table = "taxi_positions t"
+        if q.From != nil {
+            args = append(args, q.From.UTC())
+            conditions = append(conditions, fmt.Sprintf("t.recorded_at >= $%d", len(args)))
+        }
+        if q.To != nil {
+            args = append(args, q.To.UTC())
+            conditions = append(conditions, fmt.Sprintf("t.recorded_at < $%d", len(args)))
+        }
+    } else {
+        args = append(args, time.Now().Add(-tr.presence().OfflineAfter).UTC())
+        conditions = append(conditions, fmt.Sprintf("t.updated_at > $%d", len(args)), "t.deleted_at IS NULL")
+    }
+    if q.BBox != nil {
+        args = append(args, q.BBox.MinLon, q.BBox.MaxLon, q.BBox.MinLat, q.BBox.MaxLat)
+        n := len(args)
+        conditions = append(conditions, fmt.Sprintf("t.longitude BETWEEN $%d AND $%d AND t.latitude BETWEEN $%d AND $%d", n-3, n-2, n-1, n))
+    }
+    conditions, args = tr.scope(conditions, args)
+
+    query := "SELECT t.taxi_id, t.longitude, t.latitude FROM " + table + " WHERE " + strings.Join(conditions, " AND ")
+    rows, err := tr.DB.Query(query, args...)
+    if err != nil {
+        return fmt.Errorf("failed to query positions: %v", err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var taxiID string
+        var lon, lat float64
+        if err := rows.Scan(&taxiID, &lon, &lat); err != nil {
+            return fmt.Errorf("failed to scan position: %v", err)
+        }
+        if err := fn(taxiID, lon, lat); err != nil {
+            return err
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("position iteration error: %v", err)
+    }
+    return nil
+}
@@ -0,0 +1,80 @@
+// internal/repository/purge.go
+package repository
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// DefaultPurgeRetention is how long soft-deleted rows are kept when a purge
+// does not say otherwise.
+const DefaultPurgeRetention = 30 * 24 * time.Hour
+
+// Purge permanently removes the places and taxi locations soft-deleted more
+// than retention ago, in one transaction. A purged place takes its mappings,
+// dwell history, queue and versions with it, and taxis last seen inside it are
+// left outside any place. A purged taxi location takes its dwell state and
+// position history with it; violations keep the trail they closed with. A
+// scoped repository only purges its operator's private places and the
+// locations of its taxis.
+func (r *Repository) Purge(retention time.Duration) (*models.PurgeResult, error) {
+    now := time.Now().UTC()
+    result := &models.PurgeResult{Cutoff: now.Add(-retention)}
+
+    tx, err := r.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin purge: %v", err)
+    }
+    defer tx.Rollback()
+
+    purgedPlaces := `SELECT place_id FROM places WHERE deleted_at < $1 AND ` + operatorIs("operator_id", 2)
+    placeOperator := r.PlaceRepository.Operator
+    for _, stmt := range []string{
+        `DELETE FROM mapping WHERE place_id IN (` + purgedPlaces + `)`,
+        `DELETE FROM taxi_mapping WHERE place_id IN (` + purgedPlaces + `)`,
+    } {
+        if _, err := tx.Exec(stmt, result.Cutoff, placeOperator); err != nil {
+            return nil, fmt.Errorf("failed to purge place dependents: %v", err)
+        }
+    }
+    _, err = tx.Exec(`UPDATE taxi_durations SET place_id = NULL, place_version = NULL, updated_at = $3::timestamp
+        WHERE place_id IN (`+purgedPlaces+`)`, result.Cutoff, placeOperator, now)
+    if err != nil {
+        return nil, fmt.Errorf("failed to purge place dependents: %v", err)
+    }
+    res, err := tx.Exec(`DELETE FROM places WHERE deleted_at < $1 AND `+operatorIs("operator_id", 2), result.Cutoff, placeOperator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to purge places: %v", err)
+    }
+    if n, err := res.RowsAffected(); err == nil {
+        result.Places = int(n)
+    }
+
+    taxiOperator := r.TaxiRepository.Operator
+    purgedTaxis := `SELECT taxi_id FROM taxi_location WHERE deleted_at < $1 AND ` + ownedTaxi("taxi_id", 2)
+    _, err = tx.Exec(`DELETE FROM taxi_durations WHERE taxi_id IN (`+purgedTaxis+`)`, result.Cutoff, taxiOperator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to purge taxi dependents: %v", err)
+    }
+    res, err = tx.Exec(`DELETE FROM taxi_positions WHERE taxi_id IN (`+purgedTaxis+`)`, result.Cutoff, taxiOperator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to purge taxi positions: %v", err)
+    }
+    if n, err := res.RowsAffected(); err == nil {
+        result.Positions = int(n)
+    }
+    res, err = tx.Exec(`DELETE FROM taxi_location WHERE deleted_at < $1 AND `+ownedTaxi("taxi_id", 2), result.Cutoff, taxiOperator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to purge taxi locations: %v", err)
+    }
+    if n, err := res.RowsAffected(); err == nil {
+        result.TaxiLocations = int(n)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit purge: %v", err)
+    }
+    return result, nil
+}
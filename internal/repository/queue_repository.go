@@ -0,0 +1,134 @@
+// internal/repository/queue_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// QueueRepository handles the FIFO queue of taxis waiting at each place.
+type QueueRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to one operator's taxis.
+// Queues are shared, so positions still count the taxis of every operator.
+func (qr *QueueRepository) ForOperator(operatorID string) *QueueRepository {
+    scoped := *qr
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// Enqueue puts a taxi at the back of a place's queue. A taxi that is already
+// queued at the same place keeps its position; a taxi queued elsewhere is
+// moved to the back of the new place's queue.
+func (qr *QueueRepository) Enqueue(placeID int, taxiID string) error {
+    query := `
+        INSERT INTO place_queue (taxi_id, place_id, place_version, entered_at)
+        VALUES ($1, $2, (SELECT version FROM places WHERE place_id = $2), CURRENT_TIMESTAMP)
+        ON CONFLICT (taxi_id) DO UPDATE
+        SET place_id = EXCLUDED.place_id,
+            place_version = EXCLUDED.place_version,
+            entered_at = EXCLUDED.entered_at,
+            dispatched_at = NULL
+        WHERE place_queue.place_id <> EXCLUDED.place_id
+    `
+    _, err := qr.DB.Exec(query, taxiID, placeID)
+    if err != nil {
+        return fmt.Errorf("failed to enqueue taxi: %w", classify(err, "queue entry"))
+    }
+    return nil
+}
+
+// Leave removes a taxi from whichever queue it is in.
+func (qr *QueueRepository) Leave(taxiID string) error {
+    _, err := qr.DB.Exec("DELETE FROM place_queue WHERE taxi_id = $1", taxiID)
+    if err != nil {
+        return fmt.Errorf("failed to remove taxi from queue: %w", err)
+    }
+    return nil
+}
+
+// GetQueue retrieves the operator's waiting taxis of a place in arrival order.
+func (qr *QueueRepository) GetQueue(placeID int) ([]models.QueueEntry, error) {
+    query := `
+        SELECT place_id, COALESCE(place_version, 0), taxi_id, entered_at, position
+        FROM (
+            SELECT place_id, place_version, taxi_id, entered_at,
+                ROW_NUMBER() OVER (ORDER BY entered_at, taxi_id) AS position
+            FROM place_queue
+            WHERE place_id = $1 AND dispatched_at IS NULL
+        ) q
+        WHERE ` + ownedTaxi("taxi_id", 2) + `
+        ORDER BY position
+    `
+    rows, err := qr.DB.Query(query, placeID, qr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query queue: %v", err)
+    }
+    defer rows.Close()
+
+    entries := []models.QueueEntry{}
+    for rows.Next() {
+        var entry models.QueueEntry
+        if err := rows.Scan(&entry.PlaceID, &entry.PlaceVersion, &entry.TaxiID, &entry.EnteredAt, &entry.Position); err != nil {
+            return nil, fmt.Errorf("failed to scan queue entry: %v", err)
+        }
+        entries = append(entries, entry)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("queue iteration error: %v", err)
+    }
+
+    return entries, nil
+}
+
+// GetPosition retrieves a taxi's current position in its place's queue.
+func (qr *QueueRepository) GetPosition(taxiID string) (*models.QueueEntry, error) {
+    var entry models.QueueEntry
+    query := `
+        SELECT place_id, COALESCE(place_version, 0), taxi_id, entered_at, position
+        FROM (
+            SELECT place_id, place_version, taxi_id, entered_at,
+                ROW_NUMBER() OVER (PARTITION BY place_id ORDER BY entered_at, taxi_id) AS position
+            FROM place_queue
+            WHERE dispatched_at IS NULL
+        ) q
+        WHERE taxi_id = $1 AND ` + ownedTaxi("taxi_id", 2) + `
+    `
+    err := qr.DB.QueryRow(query, taxiID, qr.Operator).
+        Scan(&entry.PlaceID, &entry.PlaceVersion, &entry.TaxiID, &entry.EnteredAt, &entry.Position)
+    if err != nil {
+        return nil, classify(err, "queue entry")
+    }
+    return &entry, nil
+}
+
+// DispatchHead pops the operator's first taxi in a place's queue. The row is kept,
+// marked as dispatched, so the scheduler does not re-queue the taxi while it
+// is still inside the place; it is removed once the taxi exits.
+func (qr *QueueRepository) DispatchHead(placeID int) (*models.QueueEntry, error) {
+    entry := models.QueueEntry{Position: 1}
+    query := `
+        UPDATE place_queue
+        SET dispatched_at = CURRENT_TIMESTAMP
+        WHERE taxi_id = (
+            SELECT taxi_id
+            FROM place_queue
+            WHERE place_id = $1 AND dispatched_at IS NULL AND ` + ownedTaxi("taxi_id", 2) + `
+            ORDER BY entered_at, taxi_id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING place_id, COALESCE(place_version, 0), taxi_id, entered_at
+    `
+    err := qr.DB.QueryRow(query, placeID, qr.Operator).Scan(&entry.PlaceID, &entry.PlaceVersion, &entry.TaxiID, &entry.EnteredAt)
+    if err != nil {
+        return nil, classify(err, "queue entry")
+    }
+    return &entry, nil
+}
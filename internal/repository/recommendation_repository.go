@@ -0,0 +1,175 @@
+// internal/repository/recommendation_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// RecommendationRepository stores the rebalancing moves proposed after the
+// last scheduler run.
+type RecommendationRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the recommendations
+// of one operator's taxis.
+func (rr *RecommendationRepository) ForOperator(operatorID string) *RecommendationRepository {
+    scoped := *rr
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// recommendationKey identifies a proposed move across runs.
+type recommendationKey struct {
+    taxiID string
+    to     int
+}
+
+// ReplaceRecommendations replaces every stored recommendation with recs in
+// one transaction. A move that was already proposed keeps its creation time;
+// the moves that were not are returned with their IDs.
+func (rr *RecommendationRepository) ReplaceRecommendations(recs []models.Recommendation) ([]models.Recommendation, error) {
+    tx, err := rr.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin replacing recommendations: %v", err)
+    }
+    defer tx.Rollback()
+
+    rows, err := tx.Query("SELECT taxi_id, to_place_id, created_at FROM recommendations")
+    if err != nil {
+        return nil, fmt.Errorf("failed to query recommendations: %v", err)
+    }
+    previous := map[recommendationKey]time.Time{}
+    for rows.Next() {
+        var key recommendationKey
+        var createdAt time.Time
+        if err := rows.Scan(&key.taxiID, &key.to, &createdAt); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan recommendation: %v", err)
+        }
+        previous[key] = createdAt
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("recommendation iteration error: %v", err)
+    }
+
+    if _, err := tx.Exec("DELETE FROM recommendations"); err != nil {
+        return nil, fmt.Errorf("failed to clear recommendations: %v", err)
+    }
+
+    fresh := []models.Recommendation{}
+    for _, rec := range recs {
+        var createdAt *time.Time
+        if t, ok := previous[recommendationKey{rec.TaxiID, rec.ToPlaceID}]; ok {
+            createdAt = &t
+        }
+        err := tx.QueryRow(`
+            INSERT INTO recommendations (rank, taxi_id, from_place_id, to_place_id, distance_km, reason, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, CURRENT_TIMESTAMP))
+            RETURNING id, created_at`,
+            rec.Rank, rec.TaxiID, rec.FromPlaceID, rec.ToPlaceID, rec.DistanceKm, rec.Reason, createdAt).Scan(&rec.ID, &rec.CreatedAt)
+        if err != nil {
+            return nil, fmt.Errorf("failed to store recommendation: %w", classify(err, "recommendation"))
+        }
+        if createdAt == nil {
+            fresh = append(fresh, rec)
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit recommendations: %v", err)
+    }
+    return fresh, nil
+}
+
+// RecommendationFilter narrows down which recommendations are returned. The
+// zero value matches every recommendation.
+type RecommendationFilter struct {
+    TaxiID      string
+    FromPlaceID int
+    ToPlaceID   int
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f RecommendationFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.TaxiID != "" {
+        args = append(args, f.TaxiID)
+        conditions = append(conditions, fmt.Sprintf("r.taxi_id = $%d", len(args)))
+    }
+    if f.FromPlaceID > 0 {
+        args = append(args, f.FromPlaceID)
+        conditions = append(conditions, fmt.Sprintf("r.from_place_id = $%d", len(args)))
+    }
+    if f.ToPlaceID > 0 {
+        args = append(args, f.ToPlaceID)
+        conditions = append(conditions, fmt.Sprintf("r.to_place_id = $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// recommendationSort whitelists the columns recommendation lists can be
+// sorted by.
+var recommendationSort = sortSpec{
+    Columns: map[string]string{
+        "rank":        "r.rank",
+        "distance_km": "r.distance_km",
+        "created_at":  "r.created_at",
+    },
+    Default:   "rank",
+    ID:        "r.id",
+    UpdatedAt: "r.created_at",
+}
+
+// GetRecommendations retrieves one page of the current recommendations
+// matching the filter, along with the cursor of the next page.
+func (rr *RecommendationRepository) GetRecommendations(filter RecommendationFilter, opts ListOptions) ([]models.Recommendation, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, rr.Operator)
+    conditions = append(conditions, ownedTaxi("r.taxi_id", len(args)))
+
+    q, err := opts.build(recommendationSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `
+        SELECT r.id, r.rank, r.taxi_id, r.from_place_id, r.to_place_id, r.distance_km, r.reason, r.created_at, ` + q.SortKey + `
+        FROM recommendations r` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := rr.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query recommendations: %v", err)
+    }
+    defer rows.Close()
+
+    recs := []models.Recommendation{}
+    var keys []cursor
+    for rows.Next() {
+        var rec models.Recommendation
+        var key string
+        if err := rows.Scan(&rec.ID, &rec.Rank, &rec.TaxiID, &rec.FromPlaceID, &rec.ToPlaceID, &rec.DistanceKm, &rec.Reason, &rec.CreatedAt, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan recommendation: %v", err)
+        }
+        recs = append(recs, rec)
+        keys = append(keys, cursor{Key: key, ID: strconv.FormatInt(rec.ID, 10)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("recommendation iteration error: %v", err)
+    }
+
+    recs, next := trimPage(recs, keys, opts, recommendationSort)
+    return recs, next, nil
+}
@@ -10,9 +10,33 @@ type CountersRepository struct {
 }
 
 type Repository struct {
-    DB                  *sql.DB
-    TaxiRepository      *TaxiRepository
-    PlaceRepository     *PlaceRepository
-    MappingRepository   *MappingRepository
-    CountersRepository  *CountersRepository 
-}
\ No newline at end of file
+    DB                       *sql.DB
+    TaxiRepository           *TaxiRepository
+    TaxiRegistryRepository   *TaxiRegistryRepository
+    PlaceRepository          *PlaceRepository
+    MappingRepository        *MappingRepository
+    QueueRepository          *QueueRepository
+    CountersRepository       *CountersRepository
+    EventRepository          *EventRepository
+    DwellRepository          *DwellRepository
+    ViolationRepository      *ViolationRepository
+    AssignmentRepository     *AssignmentRepository
+    RecommendationRepository *RecommendationRepository
+}
+
+// ForOperator returns a copy of the repository whose sub-repositories are
+// all scoped to one operator.
+func (r *Repository) ForOperator(operatorID string) *Repository {
+    scoped := *r
+    scoped.TaxiRepository = r.TaxiRepository.ForOperator(operatorID)
+    scoped.TaxiRegistryRepository = r.TaxiRegistryRepository.ForOperator(operatorID)
+    scoped.PlaceRepository = r.PlaceRepository.ForOperator(operatorID)
+    scoped.MappingRepository = r.MappingRepository.ForOperator(operatorID)
+    scoped.QueueRepository = r.QueueRepository.ForOperator(operatorID)
+    scoped.EventRepository = r.EventRepository.ForOperator(operatorID)
+    scoped.DwellRepository = r.DwellRepository.ForOperator(operatorID)
+    scoped.ViolationRepository = r.ViolationRepository.ForOperator(operatorID)
+    scoped.AssignmentRepository = r.AssignmentRepository.ForOperator(operatorID)
+    scoped.RecommendationRepository = r.RecommendationRepository.ForOperator(operatorID)
+    return &scoped
+}
@@ -0,0 +1,266 @@
+// internal/repository/snapshot_repository.go
+package repository
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// Snapshot collects the current state from the GetAll* methods of every repository.
+func (r *Repository) Snapshot() (*models.Snapshot, error) {
+    snap := models.Snapshot{CreatedAt: time.Now().UTC()}
+    var err error
+
+    if snap.Places, _, err = r.PlaceRepository.GetAllPlaces(PlaceFilter{}, ListOptions{}); err != nil {
+        return nil, fmt.Errorf("failed to export places: %v", err)
+    }
+    if snap.Registry, _, err = r.TaxiRegistryRepository.GetAllRegisteredTaxis(ListOptions{}); err != nil {
+        return nil, fmt.Errorf("failed to export taxi registry: %v", err)
+    }
+    if snap.Taxis, _, err = r.TaxiRepository.GetAllTaxis(TaxiFilter{}, ListOptions{}); err != nil {
+        return nil, fmt.Errorf("failed to export taxis: %v", err)
+    }
+    if snap.Mappings, _, err = r.MappingRepository.GetAllMappings(ListOptions{}); err != nil {
+        return nil, fmt.Errorf("failed to export mappings: %v", err)
+    }
+    if snap.Durations, err = r.MappingRepository.GetAllTaxiDurations(); err != nil {
+        return nil, fmt.Errorf("failed to export taxi durations: %v", err)
+    }
+    if snap.DwellSessions, err = r.DwellRepository.GetDwellSessions(DwellFilter{IncludeOpen: true}); err != nil {
+        return nil, fmt.Errorf("failed to export dwell sessions: %v", err)
+    }
+
+    return &snap, nil
+}
+
+// Restore writes a snapshot back in a single transaction. Rows are upserted
+// by their IDs; with replace set, existing rows are removed first so the
+// database ends up matching the snapshot exactly.
+func (r *Repository) Restore(snap *models.Snapshot, replace bool) error {
+    tx, err := r.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to begin restore: %v", err)
+    }
+    defer tx.Rollback()
+
+    if replace {
+        for _, table := range []string{"mapping", "place_queue", "dwell_sessions", "taxi_durations", "taxi_location", "taxis", "places"} {
+            if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+                return fmt.Errorf("failed to clear %s: %v", table, err)
+            }
+        }
+    }
+
+    // Operators are not part of snapshots; make sure every one referenced exists
+    for _, id := range referencedOperators(snap) {
+        _, err := tx.Exec(`INSERT INTO operators (operator_id, name) VALUES ($1, $1) ON CONFLICT DO NOTHING`, id)
+        if err != nil {
+            return fmt.Errorf("failed to restore operator %s: %v", id, err)
+        }
+    }
+
+    // A place that is new or differs from the snapshot gets a new version
+    for _, p := range snap.Places {
+        res, err := tx.Exec(`
+            INSERT INTO places (place_id, place_name, polygon, operator_id)
+            VALUES ($1, $2, $3, NULLIF($4, ''))
+            ON CONFLICT (place_id) DO UPDATE
+            SET place_name = EXCLUDED.place_name,
+                polygon = EXCLUDED.polygon,
+                operator_id = EXCLUDED.operator_id,
+                updated_at = CURRENT_TIMESTAMP
+            WHERE (places.place_name, places.polygon, places.operator_id)
+                IS DISTINCT FROM (EXCLUDED.place_name, EXCLUDED.polygon, EXCLUDED.operator_id)`,
+            p.PlaceID, p.PlaceName, p.Polygon, p.OperatorID)
+        if err != nil {
+            return fmt.Errorf("failed to restore place %d: %v", p.PlaceID, err)
+        }
+        if n, _ := res.RowsAffected(); n > 0 {
+            if err := startPlaceVersion(tx, p.PlaceID); err != nil {
+                return fmt.Errorf("failed to restore place %d: %v", p.PlaceID, err)
+            }
+        }
+        // Type, rules, schedule and capacity are not versioned. Snapshots only hold
+        // live places, so also undo a soft delete made since
+        _, err = tx.Exec(`UPDATE places SET place_type = COALESCE(NULLIF($2, ''), 'stand'), rules = $3, schedule = $4, capacity = $5, deleted_at = NULL
+            WHERE place_id = $1`,
+            p.PlaceID, p.Type, p.Rules, p.Schedule, p.Capacity)
+        if err != nil {
+            return fmt.Errorf("failed to restore place %d: %v", p.PlaceID, err)
+        }
+    }
+    if _, err := tx.Exec(`SELECT setval(pg_get_serial_sequence('places', 'place_id'), COALESCE(MAX(place_id), 1)) FROM places`); err != nil {
+        return fmt.Errorf("failed to reset place sequence: %v", err)
+    }
+
+    for _, t := range snap.Registry {
+        _, err := tx.Exec(`
+            INSERT INTO taxis (taxi_id, plate_number, fleet, vehicle_class, status, created_at, operator_id)
+            VALUES ($1, $2, NULLIF($3, ''), COALESCE(NULLIF($4, ''), 'standard'), COALESCE(NULLIF($5, ''), 'active'), $6,
+                COALESCE(NULLIF($7, ''), 'default'))
+            ON CONFLICT (taxi_id) DO UPDATE
+            SET plate_number = EXCLUDED.plate_number,
+                operator_id = EXCLUDED.operator_id,
+                fleet = EXCLUDED.fleet,
+                vehicle_class = EXCLUDED.vehicle_class,
+                status = EXCLUDED.status,
+                updated_at = CURRENT_TIMESTAMP`,
+            t.TaxiID, t.PlateNumber, t.Fleet, t.VehicleClass, t.Status, t.CreatedAt, t.OperatorID)
+        if err != nil {
+            return fmt.Errorf("failed to restore registered taxi %s: %v", t.TaxiID, err)
+        }
+    }
+
+    // Snapshots taken before the registry existed carry no registry entries,
+    // so register every taxi they refer to under its ID and the default operator
+    for _, id := range referencedTaxis(snap) {
+        _, err := tx.Exec(`
+            INSERT INTO taxis (taxi_id, plate_number, operator_id) VALUES ($1, $1, 'default')
+            ON CONFLICT DO NOTHING`, id)
+        if err != nil {
+            return fmt.Errorf("failed to register taxi %s: %v", id, err)
+        }
+    }
+
+    // Locations keep the time they were last reported, which their presence
+    // status is derived from; only snapshots without one count as reported now
+    now := time.Now().UTC()
+    for _, t := range snap.Taxis {
+        updatedAt := now
+        if !t.UpdatedAt.IsZero() {
+            updatedAt = t.UpdatedAt.UTC()
+        }
+        _, err := tx.Exec(`
+            INSERT INTO taxi_location (taxi_id, longitude, latitude, updated_at)
+            VALUES ($1, $2, $3, $4::timestamp)
+            ON CONFLICT (taxi_id) DO UPDATE
+            SET longitude = EXCLUDED.longitude,
+                latitude = EXCLUDED.latitude,
+                updated_at = EXCLUDED.updated_at,
+                deleted_at = NULL`,
+            t.TaxiID, t.Longitude, t.Latitude, updatedAt)
+        if err != nil {
+            return fmt.Errorf("failed to restore taxi %s: %v", t.TaxiID, err)
+        }
+    }
+
+    for _, m := range snap.Mappings {
+        _, err := tx.Exec(`
+            INSERT INTO mapping (id, place_id, taxi_id)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (id) DO UPDATE
+            SET place_id = EXCLUDED.place_id,
+                taxi_id = EXCLUDED.taxi_id,
+                updated_at = CURRENT_TIMESTAMP`,
+            m.ID, m.PlaceID, m.TaxiID)
+        if err != nil {
+            return fmt.Errorf("failed to restore mapping %d: %v", m.ID, err)
+        }
+    }
+    if _, err := tx.Exec(`SELECT setval(pg_get_serial_sequence('mapping', 'id'), COALESCE(MAX(id), 1)) FROM mapping`); err != nil {
+        return fmt.Errorf("failed to reset mapping sequence: %v", err)
+    }
+
+    for _, d := range snap.Durations {
+        _, err := tx.Exec(`
+            INSERT INTO taxi_durations (taxi_id, place_id, place_version, updated_at)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (taxi_id) DO UPDATE
+            SET place_id = EXCLUDED.place_id,
+                place_version = EXCLUDED.place_version,
+                updated_at = EXCLUDED.updated_at`,
+            d.TaxiID, d.PlaceID, d.PlaceVersion, d.UpdatedAt.UTC())
+        if err != nil {
+            return fmt.Errorf("failed to restore taxi duration %s: %v", d.TaxiID, err)
+        }
+    }
+
+    // A taxi has one open session at most, so an open session restored over
+    // a different one ends that one. Sessions at places that were deleted
+    // before the snapshot was taken have nothing to refer to and are skipped
+    for _, s := range snap.DwellSessions {
+        var endedAt *time.Time
+        if s.EndedAt != nil {
+            utc := s.EndedAt.UTC()
+            endedAt = &utc
+        }
+        if endedAt == nil {
+            _, err := tx.Exec(`
+                UPDATE dwell_sessions SET ended_at = GREATEST(started_at, $3)
+                WHERE taxi_id = $1 AND ended_at IS NULL AND id <> $2`,
+                s.TaxiID, s.ID, s.StartedAt.UTC())
+            if err != nil {
+                return fmt.Errorf("failed to restore dwell session %d: %v", s.ID, err)
+            }
+        }
+        _, err := tx.Exec(`
+            INSERT INTO dwell_sessions (id, taxi_id, place_id, place_version, started_at, ended_at)
+            SELECT $1::bigint, $2::text, $3::integer, $4::integer, $5::timestamp, $6::timestamp
+            WHERE EXISTS (SELECT 1 FROM places WHERE place_id = $3::integer)
+            ON CONFLICT (id) DO UPDATE
+            SET taxi_id = EXCLUDED.taxi_id,
+                place_id = EXCLUDED.place_id,
+                place_version = EXCLUDED.place_version,
+                started_at = EXCLUDED.started_at,
+                ended_at = EXCLUDED.ended_at`,
+            s.ID, s.TaxiID, s.PlaceID, s.PlaceVersion, s.StartedAt.UTC(), endedAt)
+        if err != nil {
+            return fmt.Errorf("failed to restore dwell session %d: %v", s.ID, err)
+        }
+    }
+    if _, err := tx.Exec(`SELECT setval(pg_get_serial_sequence('dwell_sessions', 'id'), COALESCE(MAX(id), 1)) FROM dwell_sessions`); err != nil {
+        return fmt.Errorf("failed to reset dwell session sequence: %v", err)
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit restore: %v", err)
+    }
+    return nil
+}
+
+// referencedTaxis lists the IDs of every taxi a snapshot refers to outside
+// its registry entries.
+func referencedTaxis(snap *models.Snapshot) []string {
+    seen := make(map[string]bool)
+    var ids []string
+    add := func(id string) {
+        if id != "" && !seen[id] {
+            seen[id] = true
+            ids = append(ids, id)
+        }
+    }
+    for _, t := range snap.Taxis {
+        add(t.TaxiID)
+    }
+    for _, m := range snap.Mappings {
+        add(m.TaxiID)
+    }
+    for _, d := range snap.Durations {
+        add(d.TaxiID)
+    }
+    for _, s := range snap.DwellSessions {
+        add(s.TaxiID)
+    }
+    return ids
+}
+
+// referencedOperators lists the operators that own places or taxis in a snapshot.
+func referencedOperators(snap *models.Snapshot) []string {
+    seen := map[string]bool{"default": true}
+    ids := []string{"default"}
+    add := func(id string) {
+        if id != "" && !seen[id] {
+            seen[id] = true
+            ids = append(ids, id)
+        }
+    }
+    for _, p := range snap.Places {
+        add(p.OperatorID)
+    }
+    for _, t := range snap.Registry {
+        add(t.OperatorID)
+    }
+    return ids
+}
@@ -0,0 +1,176 @@
+// internal/repository/taxi_registry_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// TaxiRegistryRepository handles CRUD operations for registered taxis.
+type TaxiRegistryRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to one operator's taxis.
+func (rr *TaxiRegistryRepository) ForOperator(operatorID string) *TaxiRegistryRepository {
+    scoped := *rr
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// registrySort whitelists the columns registry lists can be sorted by.
+var registrySort = sortSpec{
+    Columns: map[string]string{
+        "taxi_id":      "taxi_id",
+        "plate_number": "plate_number",
+        "fleet":        "COALESCE(fleet, '')",
+        "status":       "status",
+        "updated_at":   "updated_at",
+    },
+    Default:   "taxi_id",
+    ID:        "taxi_id",
+    UpdatedAt: "updated_at",
+}
+
+const registryColumns = `taxi_id, plate_number, COALESCE(fleet, ''), vehicle_class, status, operator_id, created_at, updated_at`
+
+func scanRegistryTaxi(scan func(...interface{}) error, extra ...interface{}) (models.Taxi, error) {
+    var taxi models.Taxi
+    dest := append([]interface{}{
+        &taxi.TaxiID, &taxi.PlateNumber, &taxi.Fleet, &taxi.VehicleClass, &taxi.Status, &taxi.OperatorID, &taxi.CreatedAt, &taxi.UpdatedAt,
+    }, extra...)
+    return taxi, scan(dest...)
+}
+
+// RegisterTaxi adds a taxi to the registry, provisioning its operator if it
+// is new. Empty vehicle class and status take their defaults. A scoped
+// repository registers the taxi under its own operator; unscoped,
+// taxi.OperatorID is required.
+func (rr *TaxiRegistryRepository) RegisterTaxi(taxi models.Taxi) (*models.Taxi, error) {
+    if rr.Operator != "" {
+        taxi.OperatorID = rr.Operator
+    }
+
+    tx, err := rr.DB.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to begin taxi registration: %v", err)
+    }
+    defer tx.Rollback()
+
+    if err := provisionOperator(tx, taxi.OperatorID); err != nil {
+        return nil, err
+    }
+    query := `
+        INSERT INTO taxis (taxi_id, plate_number, fleet, vehicle_class, status, operator_id)
+        VALUES ($1, $2, NULLIF($3, ''), COALESCE(NULLIF($4, ''), 'standard'), COALESCE(NULLIF($5, ''), 'active'), $6)
+        RETURNING ` + registryColumns
+    created, err := scanRegistryTaxi(tx.QueryRow(query,
+        taxi.TaxiID, taxi.PlateNumber, taxi.Fleet, taxi.VehicleClass, taxi.Status, taxi.OperatorID).Scan)
+    if err != nil {
+        return nil, fmt.Errorf("failed to register taxi: %w", classify(err, "taxi"))
+    }
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to commit taxi registration: %v", err)
+    }
+    return &created, nil
+}
+
+// GetAllRegisteredTaxis retrieves one page of registered taxis, along with
+// the cursor of the next page.
+func (rr *TaxiRegistryRepository) GetAllRegisteredTaxis(opts ListOptions) ([]models.Taxi, *string, error) {
+    q, err := opts.build(registrySort, []string{operatorIs("operator_id", 1)}, []interface{}{rr.Operator})
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `SELECT ` + registryColumns + `, ` + q.SortKey + ` FROM taxis` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := rr.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query taxis: %v", err)
+    }
+    defer rows.Close()
+
+    taxis := []models.Taxi{}
+    var keys []cursor
+    for rows.Next() {
+        var key string
+        taxi, err := scanRegistryTaxi(rows.Scan, &key)
+        if err != nil {
+            return nil, nil, fmt.Errorf("failed to scan taxi: %v", err)
+        }
+        taxis = append(taxis, taxi)
+        keys = append(keys, cursor{Key: key, ID: taxi.TaxiID})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("taxi iteration error: %v", err)
+    }
+
+    taxis, next := trimPage(taxis, keys, opts, registrySort)
+    return taxis, next, nil
+}
+
+// GetRegisteredTaxi retrieves a registered taxi by its ID.
+func (rr *TaxiRegistryRepository) GetRegisteredTaxi(taxiID string) (*models.Taxi, error) {
+    taxi, err := scanRegistryTaxi(rr.DB.QueryRow(`SELECT `+registryColumns+` FROM taxis WHERE taxi_id = $1 AND `+operatorIs("operator_id", 2), taxiID, rr.Operator).Scan)
+    if err != nil {
+        return nil, classify(err, "taxi")
+    }
+    return &taxi, nil
+}
+
+// UpdateRegisteredTaxi replaces the registry details of a taxi.
+func (rr *TaxiRegistryRepository) UpdateRegisteredTaxi(taxi models.Taxi) (*models.Taxi, error) {
+    query := `
+        UPDATE taxis
+        SET plate_number = $2,
+            fleet = NULLIF($3, ''),
+            vehicle_class = COALESCE(NULLIF($4, ''), 'standard'),
+            status = COALESCE(NULLIF($5, ''), 'active'),
+            updated_at = CURRENT_TIMESTAMP
+        WHERE taxi_id = $1 AND ` + operatorIs("operator_id", 6) + `
+        RETURNING ` + registryColumns
+    updated, err := scanRegistryTaxi(rr.DB.QueryRow(query,
+        taxi.TaxiID, taxi.PlateNumber, taxi.Fleet, taxi.VehicleClass, taxi.Status, rr.Operator).Scan)
+    if err != nil {
+        return nil, classify(err, "taxi")
+    }
+    return &updated, nil
+}
+
+// DeleteRegisteredTaxi removes a taxi from the registry together with its
+// location and dwell state. It fails with ErrConflict while mappings still
+// reference the taxi.
+func (rr *TaxiRegistryRepository) DeleteRegisteredTaxi(taxiID string) error {
+    res, err := rr.DB.Exec("DELETE FROM taxis WHERE taxi_id = $1 AND "+operatorIs("operator_id", 2), taxiID, rr.Operator)
+    if err != nil {
+        return classify(err, "taxi")
+    }
+
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return notFound("taxi")
+    }
+    return nil
+}
+
+// IsRegistered reports whether a taxi with the given ID is registered to the
+// operator.
+func (rr *TaxiRegistryRepository) IsRegistered(taxiID string) (bool, error) {
+    var exists bool
+    query := "SELECT EXISTS (SELECT 1 FROM taxis WHERE taxi_id = $1 AND " + operatorIs("operator_id", 2) + ")"
+    err := rr.DB.QueryRow(query, taxiID, rr.Operator).Scan(&exists)
+    if err != nil {
+        return false, fmt.Errorf("failed to check taxi: %v", err)
+    }
+    return exists, nil
+}
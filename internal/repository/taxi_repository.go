@@ -5,88 +5,297 @@ import (
     "database/sql"
     "fmt"
     "log"
+    "sort"
+    "strings"
+    "time"
 
 
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
     "github.com/SangBejoo/parking-space-monitor/internal/models"
 )
 
 type TaxiRepository struct {
-    DB *sql.DB
+    DB       *sql.DB
+    Operator string // empty means unscoped
+
+    // Presence decides when taxis count as stale or offline; the zero value
+    // uses models.DefaultPresence.
+    Presence models.Presence
+}
+
+// presence returns the configured presence thresholds.
+func (tr *TaxiRepository) presence() models.Presence {
+    if tr.Presence == (models.Presence{}) {
+        return models.DefaultPresence
+    }
+    return tr.Presence
 }
 
-// CreateTaxi creates or updates a taxi's location in the database
+// withStatus sets the presence status of a taxi from its last update.
+func (tr *TaxiRepository) withStatus(taxi *models.TaxiLocation, now time.Time) {
+    taxi.Status = tr.presence().Status(taxi.UpdatedAt, now)
+}
+
+// ForOperator returns a copy of the repository scoped to one operator's taxis.
+func (tr *TaxiRepository) ForOperator(operatorID string) *TaxiRepository {
+    scoped := *tr
+    scoped.Operator = operatorID
+    return &scoped
+}
 
+// scope adds the operator condition to the conditions of a taxi_location query.
+func (tr *TaxiRepository) scope(conditions []string, args []interface{}) ([]string, []interface{}) {
+    args = append(args, tr.Operator)
+    return append(conditions, ownedTaxi("t.taxi_id", len(args))), args
+}
+
+// recordPosition completes a statement whose upserted CTE returns changed
+// taxi locations by appending them to the position history.
+//
+// Locations are stamped with a UTC time taken in Go rather than the
+// database's clock: updated_at has no time zone and is compared with Go's
+// clock to derive presence, and the session time zone need not be UTC.
+const recordPosition = `
+        INSERT INTO taxi_positions (taxi_id, longitude, latitude, recorded_at)
+        SELECT taxi_id, longitude, latitude, updated_at FROM upserted`
+
+// CreateTaxi creates or updates a taxi's location in the database, restoring
+// it if it was soft-deleted. It fails with ErrNotFound when the taxi belongs
+// to another operator.
 func (tr *TaxiRepository) CreateTaxi(location models.TaxiLocation) error {
     query := `
-        INSERT INTO taxi_location (taxi_id, longitude, latitude, updated_at)
-        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
-        ON CONFLICT (taxi_id) DO UPDATE
-        SET longitude = EXCLUDED.longitude,
-            latitude = EXCLUDED.latitude,
-            updated_at = EXCLUDED.updated_at;
-    `
-    _, err := tr.DB.Exec(query, location.TaxiID, location.Longitude, location.Latitude)
+        WITH upserted AS (
+            INSERT INTO taxi_location (taxi_id, longitude, latitude, updated_at)
+            SELECT $1::text, $2::double precision, $3::double precision, $5::timestamp
+            WHERE ` + ownedTaxi("$1::text", 4) + `
+            ON CONFLICT (taxi_id) DO UPDATE
+            SET longitude = EXCLUDED.longitude,
+                latitude = EXCLUDED.latitude,
+                updated_at = EXCLUDED.updated_at,
+                deleted_at = NULL
+            RETURNING taxi_id, longitude, latitude, updated_at
+        )
+        ` + recordPosition
+    res, err := tr.DB.Exec(query, location.TaxiID, location.Longitude, location.Latitude, tr.Operator, time.Now().UTC())
     if err != nil {
-        return fmt.Errorf("failed to create taxi location: %w", err)
+        return fmt.Errorf("failed to create taxi location: %w", classify(err, "taxi"))
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return notFound("taxi")
     }
     return nil
 }
 
-// UpdateTaxiLocation updates a taxi's location in the database.
+// UpdateTaxiLocation updates a taxi's location in the database. Taxis of
+// other operators and soft-deleted locations are left untouched.
 func (tr *TaxiRepository) UpdateTaxiLocation(taxiID string, longitude, latitude float64) error {
     query := `
-        INSERT INTO taxi_location (taxi_id, longitude, latitude, updated_at)
-        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
-        ON CONFLICT (taxi_id) DO UPDATE
-        SET longitude = EXCLUDED.longitude,
-            latitude = EXCLUDED.latitude,
-            updated_at = EXCLUDED.updated_at;
-    `
-    _, err := tr.DB.Exec(query, taxiID, longitude, latitude)
+        WITH upserted AS (
+            INSERT INTO taxi_location (taxi_id, longitude, latitude, updated_at)
+            SELECT $1::text, $2::double precision, $3::double precision, $5::timestamp
+            WHERE ` + ownedTaxi("$1::text", 4) + `
+            ON CONFLICT (taxi_id) DO UPDATE
+            SET longitude = EXCLUDED.longitude,
+                latitude = EXCLUDED.latitude,
+                updated_at = EXCLUDED.updated_at
+            WHERE taxi_location.deleted_at IS NULL
+            RETURNING taxi_id, longitude, latitude, updated_at
+        )
+        ` + recordPosition
+    _, err := tr.DB.Exec(query, taxiID, longitude, latitude, tr.Operator, time.Now().UTC())
     if err != nil {
         log.Printf("Error updating taxi location: %v", err)
     }
     return err
 }
 
-// GetAllTaxis retrieves all taxi locations from the database.
-func (tr *TaxiRepository) GetAllTaxis() ([]models.TaxiLocation, error) {
-    rows, err := tr.DB.Query("SELECT taxi_id, longitude, latitude FROM taxi_location")
+// TaxiFilter narrows down which taxis are returned. The zero value matches every taxi.
+type TaxiFilter struct {
+    BBox          *geo.BBox
+    Near          *geo.LatLon
+    RadiusM       float64 // used together with Near
+    PlaceID       int     // only taxis currently at this place
+    ExcludeMapped bool    // skip taxis currently at any place
+
+    // IncludeDeleted also returns soft-deleted locations.
+    IncludeDeleted bool
+
+    // Status keeps only taxis with this presence status.
+    Status string
+}
+
+// conditions builds the SQL conditions for the filter. The radius is applied
+// as a bounding box here and refined with the exact distance by matches.
+func (f TaxiFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    boxes := []geo.BBox{}
+    if f.BBox != nil {
+        boxes = append(boxes, *f.BBox)
+    }
+    if f.Near != nil && f.RadiusM > 0 {
+        boxes = append(boxes, geo.BBoxAround(f.Near.Lat, f.Near.Lon, f.RadiusM/1000))
+    }
+    for _, box := range boxes {
+        args = append(args, box.MinLon, box.MaxLon, box.MinLat, box.MaxLat)
+        n := len(args)
+        conditions = append(conditions, fmt.Sprintf("t.longitude BETWEEN $%d AND $%d AND t.latitude BETWEEN $%d AND $%d", n-3, n-2, n-1, n))
+    }
+    if f.PlaceID > 0 {
+        args = append(args, f.PlaceID)
+        conditions = append(conditions, fmt.Sprintf("d.place_id = $%d", len(args)))
+    }
+    if f.ExcludeMapped {
+        conditions = append(conditions, "d.place_id IS NULL")
+    }
+    if !f.IncludeDeleted {
+        conditions = append(conditions, "t.deleted_at IS NULL")
+    }
+
+    return conditions, args
+}
+
+// exact reports whether conditions alone decide the filter, so SQL can apply LIMIT.
+func (f TaxiFilter) exact() bool {
+    return f.Near == nil || f.RadiusM <= 0
+}
+
+// matches applies the parts of the filter that cannot be expressed exactly in SQL.
+func (f TaxiFilter) matches(taxi models.TaxiLocation) bool {
+    if f.Near != nil && f.RadiusM > 0 {
+        return geo.HaversineKm(f.Near.Lat, f.Near.Lon, taxi.Latitude, taxi.Longitude)*1000 <= f.RadiusM
+    }
+    return true
+}
+
+// taxiSort whitelists the columns taxi lists can be sorted by.
+var taxiSort = sortSpec{
+    Columns: map[string]string{
+        "taxi_id":    "t.taxi_id",
+        "updated_at": "t.updated_at",
+        "longitude":  "t.longitude",
+        "latitude":   "t.latitude",
+    },
+    Default:   "taxi_id",
+    ID:        "t.taxi_id",
+    UpdatedAt: "t.updated_at",
+}
+
+// statusCondition adds the condition selecting taxis with a presence status.
+func (tr *TaxiRepository) statusCondition(status string, now time.Time, conditions []string, args []interface{}) ([]string, []interface{}) {
+    p := tr.presence()
+    staleSince, offlineSince := now.Add(-p.StaleAfter).UTC(), now.Add(-p.OfflineAfter).UTC()
+    switch status {
+    case models.TaxiOnline:
+        args = append(args, staleSince)
+        conditions = append(conditions, fmt.Sprintf("t.updated_at > $%d", len(args)))
+    case models.TaxiStale:
+        args = append(args, staleSince, offlineSince)
+        conditions = append(conditions, fmt.Sprintf("t.updated_at <= $%d AND t.updated_at > $%d", len(args)-1, len(args)))
+    case models.TaxiOffline:
+        args = append(args, offlineSince)
+        conditions = append(conditions, fmt.Sprintf("t.updated_at <= $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// GetAllTaxis retrieves one page of the taxi locations matching the filter,
+// along with the cursor of the next page. Each taxi carries its presence
+// status.
+func (tr *TaxiRepository) GetAllTaxis(filter TaxiFilter, opts ListOptions) ([]models.TaxiLocation, *string, error) {
+    now := time.Now()
+    conditions, args := tr.scope(filter.conditions())
+    conditions, args = tr.statusCondition(filter.Status, now, conditions, args)
+    q, err := opts.build(taxiSort, conditions, args)
     if err != nil {
-        return nil, err
+        return nil, nil, err
+    }
+
+    query := `
+        SELECT t.taxi_id, t.longitude, t.latitude, t.updated_at, t.deleted_at, ` + q.SortKey + `
+        FROM taxi_location t
+        LEFT JOIN taxi_durations d ON d.taxi_id = t.taxi_id` + q.Where + q.OrderBy
+    if opts.Limit > 0 && filter.exact() {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := tr.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, err
     }
     defer rows.Close()
 
-    var taxis []models.TaxiLocation
-    for rows.Next() {
+    taxis := []models.TaxiLocation{}
+    var keys []cursor
+    for rows.Next() && !opts.full(len(taxis)) {
         var taxi models.TaxiLocation
-        if err := rows.Scan(&taxi.TaxiID, &taxi.Longitude, &taxi.Latitude); err != nil {
-            return nil, err
+        var key string
+        if err := rows.Scan(&taxi.TaxiID, &taxi.Longitude, &taxi.Latitude, &taxi.UpdatedAt, &taxi.DeletedAt, &key); err != nil {
+            return nil, nil, err
+        }
+        tr.withStatus(&taxi, now)
+        if !filter.matches(taxi) {
+            continue
         }
         taxis = append(taxis, taxi)
+        keys = append(keys, cursor{Key: key, ID: taxi.TaxiID})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, err
     }
 
-    return taxis, nil
+    taxis, next := trimPage(taxis, keys, opts, taxiSort)
+    return taxis, next, nil
 }
 
 // GetTaxiByID retrieves a taxi location by its ID.
 func (tr *TaxiRepository) GetTaxiByID(taxiID string) (*models.TaxiLocation, error) {
     var taxi models.TaxiLocation
-    err := tr.DB.QueryRow("SELECT taxi_id, longitude, latitude FROM taxi_location WHERE taxi_id = $1", taxiID).
-        Scan(&taxi.TaxiID, &taxi.Longitude, &taxi.Latitude)
+    query := "SELECT taxi_id, longitude, latitude, updated_at FROM taxi_location t WHERE t.taxi_id = $1 AND t.deleted_at IS NULL AND " + ownedTaxi("t.taxi_id", 2)
+    err := tr.DB.QueryRow(query, taxiID, tr.Operator).
+        Scan(&taxi.TaxiID, &taxi.Longitude, &taxi.Latitude, &taxi.UpdatedAt)
     if err != nil {
-        return nil, err
+        return nil, classify(err, "taxi")
     }
+    tr.withStatus(&taxi, time.Now())
     return &taxi, nil
 }
 
+// GetCurrentPlaces returns the place each taxi is currently inside, keyed by taxi ID.
+func (tr *TaxiRepository) GetCurrentPlaces() (map[string]int, error) {
+    query := "SELECT taxi_id, place_id FROM taxi_durations WHERE place_id IS NOT NULL AND " + ownedTaxi("taxi_id", 1)
+    rows, err := tr.DB.Query(query, tr.Operator)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    places := make(map[string]int)
+    for rows.Next() {
+        var taxiID string
+        var placeID int
+        if err := rows.Scan(&taxiID, &placeID); err != nil {
+            return nil, err
+        }
+        places[taxiID] = placeID
+    }
+
+    return places, rows.Err()
+}
+
 // UpdateTaxi updates an existing taxi location.
 func (tr *TaxiRepository) UpdateTaxi(taxiID string, location models.TaxiLocation) error {
-    res, err := tr.DB.Exec(`UPDATE taxi_location SET longitude = $1, latitude = $2, updated_at = CURRENT_TIMESTAMP 
-        WHERE taxi_id = $3`,
-        location.Longitude, location.Latitude, taxiID)
+    res, err := tr.DB.Exec(`
+        WITH upserted AS (
+            UPDATE taxi_location SET longitude = $1, latitude = $2, updated_at = $5::timestamp
+            WHERE taxi_id = $3 AND deleted_at IS NULL AND `+ownedTaxi("taxi_id", 4)+`
+            RETURNING taxi_id, longitude, latitude, updated_at
+        )
+        `+recordPosition,
+        location.Longitude, location.Latitude, taxiID, tr.Operator, time.Now().UTC())
     if err != nil {
-        return err
+        return classify(err, "taxi")
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -94,21 +303,29 @@ func (tr *TaxiRepository) UpdateTaxi(taxiID string, location models.TaxiLocation
         return err
     }
     if rowsAffected == 0 {
-        return fmt.Errorf("taxi not found")
+        return notFound("taxi")
     }
 
     return nil
 }
 
-// DeleteTaxi deletes a taxi location by its ID.
-// DeleteTaxi in taxi_repository.go
+// DeleteTaxi soft-deletes a taxi location by its ID. The taxi leaves its
+// queue and the place it was in, since the scheduler no longer tracks it; a
+// restored location is placed again on the next run.
 func (tr *TaxiRepository) DeleteTaxi(taxiID string) error {
     log.Printf("Attempting to delete taxi with ID: %s", taxiID)
+
+    tx, err := tr.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to begin taxi deletion: %v", err)
+    }
+    defer tx.Rollback()
     
-    res, err := tr.DB.Exec("DELETE FROM taxi_location WHERE taxi_id = $1", taxiID)
+    res, err := tx.Exec(`UPDATE taxi_location SET deleted_at = $3::timestamp
+        WHERE taxi_id = $1 AND deleted_at IS NULL AND `+ownedTaxi("taxi_id", 2), taxiID, tr.Operator, time.Now().UTC())
     if err != nil {
         log.Printf("Database error when deleting taxi %s: %v", taxiID, err)
-        return fmt.Errorf("database error: %v", err)
+        return fmt.Errorf("database error: %w", classify(err, "taxi"))
     }
 
     rowsAffected, err := res.RowsAffected()
@@ -119,9 +336,127 @@ func (tr *TaxiRepository) DeleteTaxi(taxiID string) error {
     
     if rowsAffected == 0 {
         log.Printf("No taxi found with ID: %s", taxiID)
-        return fmt.Errorf("taxi not found")
+        return notFound("taxi")
+    }
+
+    if _, err := tx.Exec("DELETE FROM place_queue WHERE taxi_id = $1", taxiID); err != nil {
+        return fmt.Errorf("failed to remove taxi from queue: %v", err)
+    }
+    if err := resetTaxiDuration(tx, taxiID, tr.Operator); err != nil {
+        return fmt.Errorf("failed to reset taxi duration: %v", err)
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to commit taxi deletion: %v", err)
     }
 
     log.Printf("Successfully deleted taxi %s, rows affected: %d", taxiID, rowsAffected)
     return nil
-}
\ No newline at end of file
+}
+
+// RestoreTaxi undoes the soft delete of a taxi location. It fails with
+// ErrNotFound when the location is not deleted.
+func (tr *TaxiRepository) RestoreTaxi(taxiID string) error {
+    res, err := tr.DB.Exec(`UPDATE taxi_location SET deleted_at = NULL, updated_at = $3::timestamp
+        WHERE taxi_id = $1 AND deleted_at IS NOT NULL AND `+ownedTaxi("taxi_id", 2), taxiID, tr.Operator, time.Now().UTC())
+    if err != nil {
+        return classify(err, "taxi")
+    }
+
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected == 0 {
+        return notFound("deleted taxi")
+    }
+    return nil
+}
+
+// NearestQuery describes a nearest-taxi lookup.
+type NearestQuery struct {
+    Latitude      float64
+    Longitude     float64
+    Limit         int
+    MaxKm         float64 // 0 means no distance limit
+    PlaceID       int     // only taxis currently at this place; 0 means any
+    ExcludeMapped bool    // skip taxis currently at any place
+}
+
+// FindNearest returns the taxis closest to a point by great-circle distance.
+// Candidates are prefiltered in SQL with a bounding box around MaxKm and then
+// ranked in memory.
+func (tr *TaxiRepository) FindNearest(q NearestQuery) ([]models.NearbyTaxi, error) {
+    filter := TaxiFilter{PlaceID: q.PlaceID, ExcludeMapped: q.ExcludeMapped}
+    if q.MaxKm > 0 {
+        filter.Near = &geo.LatLon{Lat: q.Latitude, Lon: q.Longitude}
+        filter.RadiusM = q.MaxKm * 1000
+    }
+
+    query := `
+        SELECT t.taxi_id, t.longitude, t.latitude, t.updated_at, d.place_id
+        FROM taxi_location t
+        LEFT JOIN taxi_durations d ON d.taxi_id = t.taxi_id
+    `
+    conditions, args := tr.scope(filter.conditions())
+    if len(conditions) > 0 {
+        query += " WHERE " + strings.Join(conditions, " AND ")
+    }
+
+    rows, err := tr.DB.Query(query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query nearby taxis: %v", err)
+    }
+    defer rows.Close()
+
+    now := time.Now()
+    taxis := []models.NearbyTaxi{}
+    for rows.Next() {
+        var taxi models.NearbyTaxi
+        var placeID sql.NullInt64
+        if err := rows.Scan(&taxi.TaxiID, &taxi.Longitude, &taxi.Latitude, &taxi.UpdatedAt, &placeID); err != nil {
+            return nil, fmt.Errorf("failed to scan nearby taxi: %v", err)
+        }
+        tr.withStatus(&taxi.TaxiLocation, now)
+        if placeID.Valid {
+            id := int(placeID.Int64)
+            taxi.PlaceID = &id
+        }
+
+        taxi.DistanceKm = geo.HaversineKm(q.Latitude, q.Longitude, taxi.Latitude, taxi.Longitude)
+        if q.MaxKm > 0 && taxi.DistanceKm > q.MaxKm {
+            continue
+        }
+        taxis = append(taxis, taxi)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("nearby taxi iteration error: %v", err)
+    }
+
+    sort.Slice(taxis, func(i, j int) bool {
+        return taxis[i].DistanceKm < taxis[j].DistanceKm
+    })
+    if q.Limit > 0 && len(taxis) > q.Limit {
+        taxis = taxis[:q.Limit]
+    }
+
+    return taxis, nil
+}
+
+// SetPresence stores the presence status the scheduler last saw for a taxi
+// and returns the previous one. changed is false when the status was already
+// stored.
+func (tr *TaxiRepository) SetPresence(taxiID, status string) (previous string, changed bool, err error) {
+    err = tr.DB.QueryRow(`
+        UPDATE taxi_location t SET presence = $2
+        FROM (SELECT taxi_id, presence FROM taxi_location WHERE taxi_id = $1 FOR UPDATE) old
+        WHERE t.taxi_id = old.taxi_id AND t.presence <> $2 AND `+ownedTaxi("t.taxi_id", 3)+`
+        RETURNING old.presence`, taxiID, status, tr.Operator).Scan(&previous)
+    if err == sql.ErrNoRows {
+        return "", false, nil
+    }
+    if err != nil {
+        return "", false, fmt.Errorf("failed to update taxi presence: %v", err)
+    }
+    return previous, true, nil
+}
@@ -0,0 +1,47 @@
+// internal/repository/taxi_repository_test.go
+package repository
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// The thresholds are bound as UTC wall clocks, matching how updated_at is
+// stamped, whatever the zone of now.
+func TestTaxiStatusCondition(t *testing.T) {
+    tr := &TaxiRepository{}
+    now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+    staleSince := time.Date(2026, 3, 2, 2, 55, 0, 0, time.UTC)
+    offlineSince := time.Date(2026, 3, 2, 2, 30, 0, 0, time.UTC)
+
+    tests := []struct {
+        status    string
+        condition string
+        args      []time.Time
+    }{
+        {"", "", nil},
+        {models.TaxiOnline, "t.updated_at > $2", []time.Time{staleSince}},
+        {models.TaxiStale, "t.updated_at <= $2 AND t.updated_at > $3", []time.Time{staleSince, offlineSince}},
+        {models.TaxiOffline, "t.updated_at <= $2", []time.Time{offlineSince}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.status, func(t *testing.T) {
+            conditions, args := tr.statusCondition(tt.status, now, []string{"x = $1"}, []interface{}{"x"})
+            if got := strings.Join(conditions[1:], " AND "); got != tt.condition {
+                t.Errorf("got condition %q, want %q", got, tt.condition)
+            }
+            if len(args) != 1+len(tt.args) {
+                t.Fatalf("got args %v, want %v", args[1:], tt.args)
+            }
+            for i, want := range tt.args {
+                got := args[1+i].(time.Time)
+                if got != want {
+                    t.Errorf("arg %d = %v, want %v", i, got, want)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,43 @@
+// internal/repository/tenant.go
+package repository
+
+import "fmt"
+
+// Repositories carry the operator they act for. A repository with an empty
+// Operator is unscoped and sees every operator's data; the scheduler, the
+// snapshot commands and the aggregate Repository use it that way. Request
+// handlers obtain a scoped copy with ForOperator.
+
+// ownedTaxi returns a condition restricting the taxi ID in column to the
+// taxis of the operator bound to parameter $n. An empty operator matches
+// every taxi.
+func ownedTaxi(column string, n int) string {
+    return fmt.Sprintf("($%d::text = '' OR %s IN (SELECT taxi_id FROM taxis WHERE operator_id = $%d::text))", n, column, n)
+}
+
+// visiblePlace returns a condition restricting the place operator in column
+// to shared places and the private places of the operator bound to $n.
+func visiblePlace(column string, n int) string {
+    return fmt.Sprintf("($%d::text = '' OR %s IS NULL OR %s = $%d::text)", n, column, column, n)
+}
+
+// operatorIs returns a condition restricting the operator in column to the
+// operator bound to $n. It is used for the taxi registry and for changes to
+// places, so shared places can only be changed unscoped.
+func operatorIs(column string, n int) string {
+    return fmt.Sprintf("($%d::text = '' OR %s = $%d::text)", n, column, n)
+}
+
+// provisionOperator creates an operator, named after its ID, the first time a
+// taxi or place is stored for it. Principals carry their operator, so any
+// operator they name becomes known this way. An empty ID does nothing.
+func provisionOperator(tx execer, operatorID string) error {
+    if operatorID == "" {
+        return nil
+    }
+    _, err := tx.Exec(`INSERT INTO operators (operator_id, name) VALUES ($1, $1) ON CONFLICT DO NOTHING`, operatorID)
+    if err != nil {
+        return fmt.Errorf("failed to provision operator %s: %v", operatorID, err)
+    }
+    return nil
+}
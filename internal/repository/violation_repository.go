@@ -0,0 +1,298 @@
+// internal/repository/violation_repository.go
+package repository
+
+import (
+    "database/sql"
+    "fmt"
+    "strconv"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// ViolationRepository stores the rule violations found by the scheduler.
+type ViolationRepository struct {
+    DB       *sql.DB
+    Operator string // empty means unscoped
+}
+
+// ForOperator returns a copy of the repository scoped to the violations of
+// one operator's taxis.
+func (vr *ViolationRepository) ForOperator(operatorID string) *ViolationRepository {
+    scoped := *vr
+    scoped.Operator = operatorID
+    return &scoped
+}
+
+// RecordViolation stores a violation unless the same rule was already broken
+// in the same dwell session, referencing the version of the place in effect
+// now. It is detected at a UTC time taken in Go. It returns the stored
+// violation, or nil when it was a repeat.
+func (vr *ViolationRepository) RecordViolation(v models.Violation) (*models.Violation, error) {
+    var placeVersion sql.NullInt64
+    err := vr.DB.QueryRow(`
+        INSERT INTO violations (taxi_id, place_id, place_version, dwell_session_id, rule, message, detected_at)
+        SELECT $1::text, $2::integer, (SELECT version FROM places WHERE place_id = $2::integer), $3::bigint, $4::text, $5::text, $7::timestamp
+        WHERE `+ownedTaxi("$1::text", 6)+`
+        ON CONFLICT (dwell_session_id, rule) DO NOTHING
+        RETURNING id, place_version, detected_at`,
+        v.TaxiID, v.PlaceID, v.DwellSessionID, v.Rule, v.Message, vr.Operator, time.Now().UTC()).Scan(&v.ID, &placeVersion, &v.DetectedAt)
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to record violation: %w", classify(err, "violation"))
+    }
+    if placeVersion.Valid {
+        version := int(placeVersion.Int64)
+        v.PlaceVersion = &version
+    }
+    return &v, nil
+}
+
+// violationColumns selects a violation in the order scanViolation reads it.
+const violationColumns = `
+    v.id, v.taxi_id, v.place_id, v.place_version, v.dwell_session_id, v.rule, v.message, v.detected_at, v.status, v.closed_at,
+    v.evidence, COALESCE(v.dispute_reason, ''), COALESCE(v.disputed_by, ''), v.disputed_at,
+    COALESCE(v.resolution, ''), COALESCE(v.resolution_note, ''), COALESCE(v.resolved_by, ''), v.resolved_at, v.updated_at`
+
+// trailPoints aggregates the positions of taxi taxiCol recorded between
+// fromExpr and toExpr into a JSON trail. Timestamps are rendered with their
+// zone so they read back as RFC 3339.
+func trailPoints(taxiCol, fromExpr, toExpr string) string {
+    return `(SELECT COALESCE(jsonb_agg(jsonb_build_object(
+                'longitude', p.longitude, 'latitude', p.latitude, 'recorded_at', p.recorded_at AT TIME ZONE 'UTC')
+                ORDER BY p.recorded_at), '[]'::jsonb)
+            FROM taxi_positions p
+            WHERE p.taxi_id = ` + taxiCol + ` AND p.recorded_at >= ` + fromExpr + ` AND p.recorded_at <= ` + toExpr + `)`
+}
+
+// violationStart is when the stay behind a violation began, falling back to
+// its detection when the dwell session is gone.
+const violationStart = `COALESCE((SELECT started_at FROM dwell_sessions WHERE id = v.dwell_session_id), v.detected_at)`
+
+// scanViolation reads one row selected with violationColumns.
+func scanViolation(row interface{ Scan(...interface{}) error }, v *models.Violation, extra ...interface{}) error {
+    var placeVersion, sessionID sql.NullInt64
+    var evidence []byte
+    dest := []interface{}{&v.ID, &v.TaxiID, &v.PlaceID, &placeVersion, &sessionID, &v.Rule, &v.Message, &v.DetectedAt, &v.Status, &v.ClosedAt,
+        &evidence, &v.DisputeReason, &v.DisputedBy, &v.DisputedAt,
+        &v.Resolution, &v.ResolutionNote, &v.ResolvedBy, &v.ResolvedAt, &v.UpdatedAt}
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return err
+    }
+    if placeVersion.Valid {
+        version := int(placeVersion.Int64)
+        v.PlaceVersion = &version
+    }
+    if sessionID.Valid {
+        v.DwellSessionID = &sessionID.Int64
+    }
+    trail, err := models.ParseTrail(evidence)
+    if err != nil {
+        return fmt.Errorf("invalid evidence of violation %d: %v", v.ID, err)
+    }
+    v.Evidence = trail
+    return nil
+}
+
+// CloseViolations closes the violations whose taxi has left the place,
+// fixing the position trail of the stay as their evidence. Violations
+// disputed while the taxi was still inside get their evidence too but stay
+// disputed. It returns the violations it closed.
+func (vr *ViolationRepository) CloseViolations() ([]models.Violation, error) {
+    closedAt := `COALESCE((SELECT ended_at FROM dwell_sessions WHERE id = v.dwell_session_id), NOW())`
+    rows, err := vr.DB.Query(`
+        UPDATE violations v
+        SET status = CASE WHEN v.status = 'open' THEN 'closed' ELSE v.status END,
+            closed_at = `+closedAt+`,
+            evidence = `+trailPoints("v.taxi_id", violationStart, closedAt)+`,
+            updated_at = NOW()
+        WHERE v.closed_at IS NULL AND v.status IN ('open', 'disputed')
+          AND NOT EXISTS (SELECT 1 FROM dwell_sessions s WHERE s.id = v.dwell_session_id AND s.ended_at IS NULL)
+          AND `+ownedTaxi("v.taxi_id", 1)+`
+        RETURNING `+violationColumns, vr.Operator)
+    if err != nil {
+        return nil, fmt.Errorf("failed to close violations: %v", err)
+    }
+    defer rows.Close()
+
+    closed := []models.Violation{}
+    for rows.Next() {
+        var v models.Violation
+        if err := scanViolation(rows, &v); err != nil {
+            return nil, fmt.Errorf("failed to scan violation: %v", err)
+        }
+        closed = append(closed, v)
+    }
+    return closed, rows.Err()
+}
+
+// ViolationFilter narrows down which violations are returned. The zero value
+// matches every violation.
+type ViolationFilter struct {
+    Status  string
+    Rule    string
+    PlaceID int
+    TaxiID  string
+    Since   *time.Time
+}
+
+// conditions builds the SQL conditions for the filter.
+func (f ViolationFilter) conditions() ([]string, []interface{}) {
+    var conditions []string
+    var args []interface{}
+
+    if f.Status != "" {
+        args = append(args, f.Status)
+        conditions = append(conditions, fmt.Sprintf("v.status = $%d", len(args)))
+    }
+    if f.Rule != "" {
+        args = append(args, f.Rule)
+        conditions = append(conditions, fmt.Sprintf("v.rule = $%d", len(args)))
+    }
+    if f.PlaceID > 0 {
+        args = append(args, f.PlaceID)
+        conditions = append(conditions, fmt.Sprintf("v.place_id = $%d", len(args)))
+    }
+    if f.TaxiID != "" {
+        args = append(args, f.TaxiID)
+        conditions = append(conditions, fmt.Sprintf("v.taxi_id = $%d", len(args)))
+    }
+    if f.Since != nil {
+        args = append(args, f.Since.UTC())
+        conditions = append(conditions, fmt.Sprintf("v.detected_at >= $%d", len(args)))
+    }
+    return conditions, args
+}
+
+// violationSort whitelists the columns violation lists can be sorted by.
+var violationSort = sortSpec{
+    Columns: map[string]string{
+        "id":          "v.id",
+        "detected_at": "v.detected_at",
+        "updated_at":  "v.updated_at",
+    },
+    Default:   "id",
+    ID:        "v.id",
+    UpdatedAt: "v.updated_at",
+}
+
+// GetViolations retrieves one page of the violations matching the filter,
+// along with the cursor of the next page. Open violations are listed without
+// evidence; GetViolation fills in their trail so far.
+func (vr *ViolationRepository) GetViolations(filter ViolationFilter, opts ListOptions) ([]models.Violation, *string, error) {
+    conditions, args := filter.conditions()
+    args = append(args, vr.Operator)
+    conditions = append(conditions, ownedTaxi("v.taxi_id", len(args)))
+
+    q, err := opts.build(violationSort, conditions, args)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    query := `SELECT ` + violationColumns + `, ` + q.SortKey + ` FROM violations v` + q.Where + q.OrderBy
+    if opts.Limit > 0 {
+        query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+    }
+
+    rows, err := vr.DB.Query(query, q.Args...)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to query violations: %v", err)
+    }
+    defer rows.Close()
+
+    violations := []models.Violation{}
+    var keys []cursor
+    for rows.Next() {
+        var v models.Violation
+        var key string
+        if err := scanViolation(rows, &v, &key); err != nil {
+            return nil, nil, fmt.Errorf("failed to scan violation: %v", err)
+        }
+        violations = append(violations, v)
+        keys = append(keys, cursor{Key: key, ID: strconv.FormatInt(v.ID, 10)})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, nil, fmt.Errorf("violation iteration error: %v", err)
+    }
+
+    violations, next := trimPage(violations, keys, opts, violationSort)
+    return violations, next, nil
+}
+
+// GetViolation retrieves a violation by its ID. A violation that has not
+// closed yet carries the trail of the stay so far as its evidence.
+func (vr *ViolationRepository) GetViolation(id int64) (*models.Violation, error) {
+    query := `
+        SELECT ` + violationColumns + `,
+            CASE WHEN v.closed_at IS NULL THEN ` + trailPoints("v.taxi_id", violationStart, "NOW()") + ` END
+        FROM violations v
+        WHERE v.id = $1 AND ` + ownedTaxi("v.taxi_id", 2)
+    var v models.Violation
+    var live []byte
+    if err := scanViolation(vr.DB.QueryRow(query, id, vr.Operator), &v, &live); err != nil {
+        return nil, classify(err, "violation")
+    }
+    if live != nil {
+        trail, err := models.ParseTrail(live)
+        if err != nil {
+            return nil, fmt.Errorf("invalid trail of violation %d: %v", v.ID, err)
+        }
+        v.Evidence = trail
+    }
+    return &v, nil
+}
+
+// DisputeViolation marks an open or closed violation as disputed by actor. A
+// violation disputed while open still closes with its evidence when the taxi
+// leaves. Disputing a violation that was already disputed or resolved fails
+// with ErrConflict.
+func (vr *ViolationRepository) DisputeViolation(id int64, actor, reason string) error {
+    query := `
+        UPDATE violations
+        SET status = 'disputed', dispute_reason = $2, disputed_by = $3, disputed_at = NOW(), updated_at = NOW()
+        WHERE id = $1 AND status IN ('open', 'closed') AND ` + ownedTaxi("taxi_id", 4)
+    res, err := vr.DB.Exec(query, id, reason, actor, vr.Operator)
+    if err != nil {
+        return classify(err, "violation")
+    }
+    return vr.transitioned(res, id, "disputed")
+}
+
+// ResolveViolation records the outcome of a violation. Only closed violations,
+// and disputed ones whose stay has ended, can be resolved, so every resolved
+// violation carries its evidence; anything else fails with ErrConflict.
+func (vr *ViolationRepository) ResolveViolation(id int64, actor, resolution, note string) error {
+    query := `
+        UPDATE violations
+        SET status = 'resolved', resolution = $2, resolution_note = NULLIF($3, ''), resolved_by = $4,
+            resolved_at = NOW(), updated_at = NOW()
+        WHERE id = $1 AND status IN ('closed', 'disputed') AND closed_at IS NOT NULL AND ` + ownedTaxi("taxi_id", 5)
+    res, err := vr.DB.Exec(query, id, resolution, note, actor, vr.Operator)
+    if err != nil {
+        return classify(err, "violation")
+    }
+    return vr.transitioned(res, id, "resolved")
+}
+
+// transitioned tells a status change that matched no row apart from a
+// violation that does not exist.
+func (vr *ViolationRepository) transitioned(res sql.Result, id int64, to string) error {
+    rowsAffected, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rowsAffected > 0 {
+        return nil
+    }
+
+    v, err := vr.GetViolation(id)
+    if err != nil {
+        return err
+    }
+    if v.Status == models.ViolationDisputed && v.ClosedAt == nil {
+        return fmt.Errorf("violation %d is disputed and cannot be %s until its taxi leaves the place: %w", id, to, ErrConflict)
+    }
+    return fmt.Errorf("violation %d is %s and cannot be %s: %w", id, v.Status, to, ErrConflict)
+}
@@ -1,9 +1,14 @@
 package scheduler
 
 import (
+    "encoding/json"
     "log"
     "sync"
+    "time"
 
+    "github.com/SangBejoo/parking-space-monitor/internal/geo"
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+    "github.com/SangBejoo/parking-space-monitor/internal/rebalance"
     "github.com/SangBejoo/parking-space-monitor/internal/repository"
 )
 
@@ -11,6 +16,14 @@ import (
 type Scheduler struct {
     Repo  *repository.Repository
     Mutex sync.Mutex
+
+    // ExcludeStale takes stale taxis out of their place like offline ones,
+    // instead of only flagging them.
+    ExcludeStale bool
+
+    // NotifyRebalancing records an event for every new rebalancing
+    // recommendation, so drivers following the event feed learn of it.
+    NotifyRebalancing bool
 }
 
 // ProcessTaxi processes a taxi's location and updates it in the database.
@@ -26,29 +39,6 @@ func (s *Scheduler) ProcessTaxi(taxiID string, longitude, latitude float64) {
         log.Printf("Error updating taxi location: %v", err)
     }
 }
-// Point represents a geographic coordinate.
-type Point struct {
-    X float64 // longitude
-    Y float64 // latitude
-}
-
-// isPointInPolygon checks if a point lies within a polygon using the ray-casting algorithm.
-func isPointInPolygon(longitude, latitude float64, polygon []Point) bool {
-    intersects := false
-    j := len(polygon) - 1
-    for i := 0; i < len(polygon); i++ {
-        xi, yi := polygon[i].X, polygon[i].Y
-        xj, yj := polygon[j].X, polygon[j].Y
-
-        intersect := ((yi > latitude) != (yj > latitude)) &&
-            (longitude < (xj-xi)*(latitude-yi)/(yj-yi)+xi)
-        if intersect {
-            intersects = !intersects
-        }
-        j = i
-    }
-    return intersects
-}
 
 // NewScheduler creates a new Scheduler instance.
 func NewScheduler(repo *repository.Repository) *Scheduler {
@@ -63,51 +53,60 @@ func (s *Scheduler) MapTaxiLocations() {
     defer s.Mutex.Unlock()
     log.Println("Starting taxi location mapping...")
 
-    taxis, err := s.Repo.TaxiRepository.GetAllTaxis()
+    taxis, _, err := s.Repo.TaxiRepository.GetAllTaxis(repository.TaxiFilter{}, repository.ListOptions{})
     if err != nil {
         log.Printf("Error getting taxis: %v", err)
         return
     }
 
-    places, err := s.Repo.PlaceRepository.GetAllPlaces()
+    places, _, err := s.Repo.PlaceRepository.GetAllPlaces(repository.PlaceFilter{}, repository.ListOptions{})
     if err != nil {
         log.Printf("Error getting places: %v", err)
         return
     }
 
+    // Places outside their schedule are inactive: taxis in them are treated
+    // as outside any place, and their rules are not enforced
+    active := places[:0]
+    now := time.Now()
+    for _, place := range places {
+        if place.IsOpen(now) {
+            active = append(active, place)
+        } else {
+            log.Printf("Place %s is closed", place.PlaceName)
+        }
+    }
+    places = active
+
     for _, taxi := range taxis {
         log.Printf("Processing taxi %s at coordinates (%.2f, %.2f)",
             taxi.TaxiID, taxi.Longitude, taxi.Latitude)
 
+        s.trackPresence(taxi)
+        if taxi.Status == models.TaxiOffline || (taxi.Status == models.TaxiStale && s.ExcludeStale) {
+            // A taxi that stopped reporting no longer occupies its place
+            log.Printf("Taxi %s is %s, last seen %s", taxi.TaxiID, taxi.Status, taxi.UpdatedAt)
+            s.leavePlace(taxi.TaxiID)
+            continue
+        }
+        if taxi.Status == models.TaxiStale {
+            log.Printf("Taxi %s is stale, last seen %s", taxi.TaxiID, taxi.UpdatedAt)
+        }
+
         matched := false
         for _, place := range places {
             log.Printf("Checking against place %s with polygon: %v",
                 place.PlaceName, place.Polygon)
 
-            // Convert GeoJSONPolygon to []Point
-            var polygon []Point
-            if len(place.Polygon.Coordinates) > 0 {
-                for _, coord := range place.Polygon.Coordinates[0] {
-                    if len(coord) >= 2 {
-                        lon, err1 := coord[0].Float64()
-                        lat, err2 := coord[1].Float64()
-                        if err1 != nil || err2 != nil {
-                            log.Printf("Error converting coordinate to float64 for place %s", place.PlaceName)
-                            continue
-                        }
-                        polygon = append(polygon, Point{
-                            X: lon,
-                            Y: lat,
-                        })
-                    }
-                }
-            } else {
-                log.Printf("No coordinates found for place %s", place.PlaceName)
+            // Convert GeoJSONPolygon to []geo.Point, one ring per part
+            polygons, err := place.Polygon.OuterRings()
+            if err != nil {
+                log.Printf("Invalid polygon for place %s: %v", place.PlaceName, err)
                 continue
             }
 
-            // Check if taxi is within polygon
-            if isPointInPolygon(taxi.Longitude, taxi.Latitude, polygon) {
+            // Check if taxi is within any part of the polygon
+            if geo.PointInAnyPolygon(taxi.Longitude, taxi.Latitude, polygons) {
                 matched = true
                 log.Printf("Taxi %s is within %s", taxi.TaxiID, place.PlaceName)
 
@@ -117,19 +116,231 @@ func (s *Scheduler) MapTaxiLocations() {
                     log.Printf("Error updating taxi duration: %v", err)
                 }
 
+                // Join the place's queue; a taxi already queued here keeps its position.
+                // Places that are not for waiting, like no-parking zones, have no queue
+                if models.PlaceTypeQueues(place.Type) {
+                    if err := s.Repo.QueueRepository.Enqueue(place.PlaceID, taxi.TaxiID); err != nil {
+                        log.Printf("Error enqueueing taxi: %v", err)
+                    }
+                } else if err := s.Repo.QueueRepository.Leave(taxi.TaxiID); err != nil {
+                    log.Printf("Error removing taxi from queue: %v", err)
+                }
+
                 break
             }
         }
 
         if !matched {
             log.Printf("Taxi %s does not match any place", taxi.TaxiID)
-            // Reset duration if taxi moved out
-            err := s.Repo.MappingRepository.ResetTaxiDuration(taxi.TaxiID)
+            s.leavePlace(taxi.TaxiID)
+        }
+    }
+
+    s.checkRules(places)
+    s.closeViolations()
+    s.expireOffers()
+
+    // Sample occupancy for the history once every taxi is placed
+    if err := s.Repo.PlaceRepository.RecordOccupancy(); err != nil {
+        log.Printf("Error recording occupancy: %v", err)
+    }
+
+    s.recommendMoves(places, taxis)
+
+    log.Println("Mapping process completed")
+}
+
+// leavePlace takes a taxi out of the place and queue it was in.
+func (s *Scheduler) leavePlace(taxiID string) {
+    // Reset duration if taxi moved out
+    err := s.Repo.MappingRepository.ResetTaxiDuration(taxiID)
+    if err != nil {
+        log.Printf("Error resetting taxi duration: %v", err)
+    }
+
+    // Leave the queue once the taxi exits the place
+    if err := s.Repo.QueueRepository.Leave(taxiID); err != nil {
+        log.Printf("Error removing taxi from queue: %v", err)
+    }
+}
+
+// trackPresence stores the presence status of a taxi and records an event
+// when it changed since the last run.
+func (s *Scheduler) trackPresence(taxi models.TaxiLocation) {
+    previous, changed, err := s.Repo.TaxiRepository.SetPresence(taxi.TaxiID, taxi.Status)
+    if err != nil {
+        log.Printf("Error updating presence of taxi %s: %v", taxi.TaxiID, err)
+        return
+    }
+    if !changed {
+        return
+    }
+
+    data, _ := json.Marshal(map[string]interface{}{"previous": previous, "last_seen": taxi.UpdatedAt})
+    event := models.Event{Type: "taxi." + taxi.Status, TaxiID: taxi.TaxiID, Data: data}
+    if err := s.Repo.EventRepository.Record(event); err != nil {
+        log.Printf("Error recording event: %v", err)
+    }
+}
+
+// checkRules records a violation for every rule broken by a taxi inside a
+// place, once per stay and rule, and raises an event for each new one.
+func (s *Scheduler) checkRules(places []models.Place) {
+    byID := make(map[int]models.Place, len(places))
+    for _, place := range places {
+        byID[place.PlaceID] = place
+    }
+
+    sessions, err := s.Repo.DwellRepository.GetDwellSessions(repository.DwellFilter{OpenOnly: true})
+    if err != nil {
+        log.Printf("Error getting dwell sessions: %v", err)
+        return
+    }
+    registered, _, err := s.Repo.TaxiRegistryRepository.GetAllRegisteredTaxis(repository.ListOptions{})
+    if err != nil {
+        log.Printf("Error getting registered taxis: %v", err)
+        return
+    }
+    fleets := make(map[string]string, len(registered))
+    for _, taxi := range registered {
+        fleets[taxi.TaxiID] = taxi.Fleet
+    }
+
+    now := time.Now()
+    for _, session := range sessions {
+        place, ok := byID[session.PlaceID]
+        if !ok {
+            continue
+        }
+        var rules models.PlaceRules
+        if place.Rules != nil {
+            rules = *place.Rules
+        }
+
+        for _, breach := range rules.Check(place.Type, fleets[session.TaxiID], session.Duration(now), now) {
+            sessionID := session.ID
+            violation, err := s.Repo.ViolationRepository.RecordViolation(models.Violation{
+                TaxiID:         session.TaxiID,
+                PlaceID:        place.PlaceID,
+                DwellSessionID: &sessionID,
+                Rule:           breach.Rule,
+                Message:        breach.Message,
+            })
             if err != nil {
-                log.Printf("Error resetting taxi duration: %v", err)
+                log.Printf("Error recording violation: %v", err)
+                continue
+            }
+            if violation == nil {
+                continue
+            }
+
+            log.Printf("Taxi %s broke rule %s at %s: %s", violation.TaxiID, violation.Rule, place.PlaceName, violation.Message)
+            data, _ := json.Marshal(map[string]interface{}{"violation_id": violation.ID, "rule": violation.Rule, "message": violation.Message})
+            placeID := place.PlaceID
+            event := models.Event{Type: models.EventViolation, TaxiID: violation.TaxiID, PlaceID: &placeID, Data: data}
+            if err := s.Repo.EventRepository.Record(event); err != nil {
+                log.Printf("Error recording event: %v", err)
             }
         }
     }
+}
 
-    log.Println("Mapping process completed")
-}
\ No newline at end of file
+// closeViolations closes the violations of taxis that have left the place and
+// raises an event for each.
+func (s *Scheduler) closeViolations() {
+    closed, err := s.Repo.ViolationRepository.CloseViolations()
+    if err != nil {
+        log.Printf("Error closing violations: %v", err)
+        return
+    }
+
+    for _, violation := range closed {
+        log.Printf("Closed violation %d of taxi %s with %d trail points", violation.ID, violation.TaxiID, len(violation.Evidence))
+        data, _ := json.Marshal(map[string]interface{}{"violation_id": violation.ID, "rule": violation.Rule, "closed_at": violation.ClosedAt})
+        placeID := violation.PlaceID
+        event := models.Event{Type: models.EventViolationClosed, TaxiID: violation.TaxiID, PlaceID: &placeID, Data: data}
+        if err := s.Repo.EventRepository.Record(event); err != nil {
+            log.Printf("Error recording event: %v", err)
+        }
+    }
+}
+
+// expireOffers cancels the offers drivers did not answer in time and logs
+// where each booking went next.
+func (s *Scheduler) expireOffers() {
+    outcomes, err := s.Repo.AssignmentRepository.ExpireOffers()
+    if err != nil {
+        log.Printf("Error expiring offers: %v", err)
+    }
+
+    for _, outcome := range outcomes {
+        expired := outcome.Cancelled
+        if outcome.Reoffered != nil {
+            log.Printf("Offer %d of booking %s to taxi %s expired, offered to taxi %s",
+                expired.ID, expired.BookingRef, expired.TaxiID, outcome.Reoffered.TaxiID)
+        } else {
+            log.Printf("Offer %d of booking %s to taxi %s expired, no taxi left at place %d",
+                expired.ID, expired.BookingRef, expired.TaxiID, expired.PlaceID)
+        }
+    }
+}
+
+// recommendMoves replaces the rebalancing recommendations with moves from the
+// open places that overflow to those below target, and raises an event for
+// each new one when NotifyRebalancing is set.
+func (s *Scheduler) recommendMoves(places []models.Place, locations []models.TaxiLocation) {
+    registered, _, err := s.Repo.TaxiRegistryRepository.GetAllRegisteredTaxis(repository.ListOptions{})
+    if err != nil {
+        log.Printf("Error getting registered taxis: %v", err)
+        return
+    }
+    operators := make(map[string]string, len(registered))
+    for _, taxi := range registered {
+        operators[taxi.TaxiID] = taxi.OperatorID
+    }
+    taxis := make(map[string]rebalance.Taxi, len(locations))
+    for _, location := range locations {
+        taxis[location.TaxiID] = rebalance.Taxi{Longitude: location.Longitude, Latitude: location.Latitude, OperatorID: operators[location.TaxiID]}
+    }
+
+    queues := make(map[int][]models.QueueEntry)
+    for _, place := range places {
+        if place.Capacity == nil {
+            continue
+        }
+        queue, err := s.Repo.QueueRepository.GetQueue(place.PlaceID)
+        if err != nil {
+            log.Printf("Error getting queue of place %s: %v", place.PlaceName, err)
+            return
+        }
+        queues[place.PlaceID] = queue
+    }
+
+    recommendations := rebalance.Recommend(places, queues, taxis)
+    fresh, err := s.Repo.RecommendationRepository.ReplaceRecommendations(recommendations)
+    if err != nil {
+        log.Printf("Error storing recommendations: %v", err)
+        return
+    }
+    log.Printf("Recommended %d moves, %d new", len(recommendations), len(fresh))
+
+    for _, rec := range fresh {
+        log.Printf("Taxi %s should move from place %d to place %d, %.3f km away (%s)",
+            rec.TaxiID, rec.FromPlaceID, rec.ToPlaceID, rec.DistanceKm, rec.Reason)
+        if !s.NotifyRebalancing {
+            continue
+        }
+        data, _ := json.Marshal(map[string]interface{}{
+            "recommendation_id": rec.ID,
+            "from_place_id":     rec.FromPlaceID,
+            "to_place_id":       rec.ToPlaceID,
+            "distance_km":       rec.DistanceKm,
+            "reason":            rec.Reason,
+        })
+        placeID := rec.ToPlaceID
+        event := models.Event{Type: models.EventRebalance, TaxiID: rec.TaxiID, PlaceID: &placeID, Data: data}
+        if err := s.Repo.EventRepository.Record(event); err != nil {
+            log.Printf("Error recording event: %v", err)
+        }
+    }
+}
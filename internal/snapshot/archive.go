@@ -0,0 +1,243 @@
+// internal/snapshot/archive.go
+package snapshot
+
+import (
+    "archive/tar"
+    "bufio"
+    "bytes"
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// FormatName identifies snapshot archives in their manifest.
+const FormatName = "parking-space-monitor-snapshot"
+
+// Version is the archive layout written by this build. Read accepts any
+// version up to and including it. Version 2 added the taxi registry and
+// string taxi IDs on mappings, version 3 dwell sessions.
+const Version = 3
+
+// Manifest is stored as manifest.json at the start of every archive.
+type Manifest struct {
+    Format    string         `json:"format"`
+    Version   int            `json:"version"`
+    CreatedAt time.Time      `json:"created_at"`
+    Counts    map[string]int `json:"counts"`
+}
+
+// Archive entries, one NDJSON file per entity.
+const (
+    manifestFile  = "manifest.json"
+    placesFile    = "places.ndjson"
+    registryFile  = "registry.ndjson"
+    taxisFile     = "taxis.ndjson"
+    mappingsFile  = "mappings.ndjson"
+    durationsFile = "taxi_durations.ndjson"
+    sessionsFile  = "dwell_sessions.ndjson"
+)
+
+// Write encodes a snapshot as a gzipped tar archive.
+func Write(w io.Writer, snap *models.Snapshot) error {
+    gz := gzip.NewWriter(w)
+    tw := tar.NewWriter(gz)
+
+    entities := []struct {
+        name string
+        rows interface{}
+    }{
+        {placesFile, snap.Places},
+        {registryFile, snap.Registry},
+        {taxisFile, snap.Taxis},
+        {mappingsFile, snap.Mappings},
+        {durationsFile, snap.Durations},
+        {sessionsFile, snap.DwellSessions},
+    }
+
+    manifest := Manifest{
+        Format:    FormatName,
+        Version:   Version,
+        CreatedAt: snap.CreatedAt,
+        Counts: map[string]int{
+            placesFile:    len(snap.Places),
+            registryFile:  len(snap.Registry),
+            taxisFile:     len(snap.Taxis),
+            mappingsFile:  len(snap.Mappings),
+            durationsFile: len(snap.Durations),
+            sessionsFile:  len(snap.DwellSessions),
+        },
+    }
+    b, err := json.MarshalIndent(manifest, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := writeEntry(tw, manifestFile, b, snap.CreatedAt); err != nil {
+        return err
+    }
+
+    for _, e := range entities {
+        b, err := ndjson(e.rows)
+        if err != nil {
+            return fmt.Errorf("failed to encode %s: %v", e.name, err)
+        }
+        if err := writeEntry(tw, e.name, b, snap.CreatedAt); err != nil {
+            return err
+        }
+    }
+
+    if err := tw.Close(); err != nil {
+        return err
+    }
+    return gz.Close()
+}
+
+// Read decodes an archive produced by Write.
+func Read(r io.Reader) (*models.Snapshot, error) {
+    gz, err := gzip.NewReader(r)
+    if err != nil {
+        return nil, fmt.Errorf("not a snapshot archive: %v", err)
+    }
+    defer gz.Close()
+
+    snap := &models.Snapshot{}
+    var manifest *Manifest
+
+    tr := tar.NewReader(gz)
+    for {
+        hdr, err := tr.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("corrupt snapshot archive: %v", err)
+        }
+
+        switch hdr.Name {
+        case manifestFile:
+            manifest = &Manifest{}
+            if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+                return nil, fmt.Errorf("invalid manifest: %v", err)
+            }
+            if manifest.Format != FormatName {
+                return nil, fmt.Errorf("unexpected archive format %q", manifest.Format)
+            }
+            if manifest.Version < 1 || manifest.Version > Version {
+                return nil, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+            }
+            snap.Version = manifest.Version
+            snap.CreatedAt = manifest.CreatedAt
+        case placesFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                var p models.Place
+                err := dec.Decode(&p)
+                snap.Places = append(snap.Places, p)
+                return err
+            })
+        case registryFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                var t models.Taxi
+                err := dec.Decode(&t)
+                snap.Registry = append(snap.Registry, t)
+                return err
+            })
+        case taxisFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                var t models.TaxiLocation
+                err := dec.Decode(&t)
+                snap.Taxis = append(snap.Taxis, t)
+                return err
+            })
+        case mappingsFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                m, err := decodeMapping(dec, snap.Version)
+                snap.Mappings = append(snap.Mappings, m)
+                return err
+            })
+        case durationsFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                var d models.TaxiDuration
+                err := dec.Decode(&d)
+                snap.Durations = append(snap.Durations, d)
+                return err
+            })
+        case sessionsFile:
+            err = readNDJSON(tr, func(dec *json.Decoder) error {
+                var s models.DwellSession
+                err := dec.Decode(&s)
+                snap.DwellSessions = append(snap.DwellSessions, s)
+                return err
+            })
+        }
+        if err != nil {
+            return nil, fmt.Errorf("invalid %s: %v", hdr.Name, err)
+        }
+    }
+
+    if manifest == nil {
+        return nil, fmt.Errorf("snapshot archive has no %s", manifestFile)
+    }
+    return snap, nil
+}
+
+// decodeMapping decodes one mapping. Version 1 archives numbered taxis on
+// mappings, which become their registry IDs.
+func decodeMapping(dec *json.Decoder, version int) (models.Mapping, error) {
+    if version >= 2 {
+        var m models.Mapping
+        err := dec.Decode(&m)
+        return m, err
+    }
+
+    var legacy struct {
+        models.Mapping
+        TaxiID json.Number `json:"taxi_id"`
+    }
+    err := dec.Decode(&legacy)
+    m := legacy.Mapping
+    m.TaxiID = legacy.TaxiID.String()
+    return m, err
+}
+
+func writeEntry(tw *tar.Writer, name string, b []byte, modTime time.Time) error {
+    hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(b)), ModTime: modTime}
+    if err := tw.WriteHeader(hdr); err != nil {
+        return err
+    }
+    _, err := tw.Write(b)
+    return err
+}
+
+// ndjson encodes each element of a slice on its own line.
+func ndjson(rows interface{}) ([]byte, error) {
+    var items []json.RawMessage
+    b, err := json.Marshal(rows)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(b, &items); err != nil {
+        return nil, err
+    }
+
+    var buf bytes.Buffer
+    for _, item := range items {
+        buf.Write(item)
+        buf.WriteByte('\n')
+    }
+    return buf.Bytes(), nil
+}
+
+// readNDJSON calls decode once per line until the input is exhausted.
+func readNDJSON(r io.Reader, decode func(*json.Decoder) error) error {
+    dec := json.NewDecoder(bufio.NewReader(r))
+    dec.UseNumber()
+    for dec.More() {
+        if err := decode(dec); err != nil {
+            return err
+        }
+    }
+    return nil
+}
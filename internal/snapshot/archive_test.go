@@ -0,0 +1,158 @@
+// internal/snapshot/archive_test.go
+package snapshot
+
+import (
+    "archive/tar"
+    "bytes"
+    "compress/gzip"
+    "encoding/json"
+    "reflect"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/SangBejoo/parking-space-monitor/internal/models"
+)
+
+// archive builds a snapshot archive from raw entries, in order.
+func archive(t *testing.T, entries ...[2]string) *bytes.Buffer {
+    t.Helper()
+    var buf bytes.Buffer
+    gz := gzip.NewWriter(&buf)
+    tw := tar.NewWriter(gz)
+    for _, e := range entries {
+        if err := writeEntry(tw, e[0], []byte(e[1]), time.Time{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := tw.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if err := gz.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return &buf
+}
+
+func manifestOf(version int) [2]string {
+    return [2]string{manifestFile, `{"format":"` + FormatName + `","version":` + strconv.Itoa(version) + `,"created_at":"2026-03-01T00:00:00Z"}`}
+}
+
+func TestRoundTrip(t *testing.T) {
+    created := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+    ended := created.Add(45 * time.Minute)
+    offered := created.Add(5 * time.Minute)
+    place, version := 1, 2
+
+    var polygon models.GeoJSONPolygon
+    if err := json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[[[106.8,-6.2],[106.81,-6.2],[106.81,-6.19],[106.8,-6.2]]]}`), &polygon); err != nil {
+        t.Fatal(err)
+    }
+
+    snap := &models.Snapshot{
+        Version:   Version,
+        CreatedAt: created,
+        Places: []models.Place{
+            {PlaceID: 1, PlaceName: "Bandara", Polygon: polygon, Version: 2, Type: "stand"},
+            {PlaceID: 2, PlaceName: "Depo", Polygon: polygon, OperatorID: "op-1", Version: 1, Type: "depot"},
+        },
+        Registry: []models.Taxi{
+            {TaxiID: "T1", PlateNumber: "B 1234 XY", Status: "active", CreatedAt: created, UpdatedAt: created},
+        },
+        Taxis: []models.TaxiLocation{
+            {TaxiID: "T1", Longitude: 106.805, Latitude: -6.195, UpdatedAt: created},
+        },
+        Mappings: []models.Mapping{
+            {ID: 1, PlaceID: 1, TaxiID: "T1", Status: "offered", OfferedAt: &offered},
+        },
+        Durations: []models.TaxiDuration{
+            {TaxiID: "T1", PlaceID: &place, PlaceVersion: &version, UpdatedAt: created},
+        },
+        DwellSessions: []models.DwellSession{
+            {ID: 1, TaxiID: "T1", PlaceID: 1, PlaceVersion: &version, StartedAt: created, EndedAt: &ended},
+            {ID: 2, TaxiID: "T1", PlaceID: 1, PlaceVersion: &version, StartedAt: ended},
+        },
+    }
+
+    var buf bytes.Buffer
+    if err := Write(&buf, snap); err != nil {
+        t.Fatal(err)
+    }
+    got, err := Read(&buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(got, snap) {
+        t.Errorf("read back\n%+v\nwant\n%+v", got, snap)
+    }
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+    var buf bytes.Buffer
+    if err := Write(&buf, &models.Snapshot{CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+        t.Fatal(err)
+    }
+    got, err := Read(&buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Version != Version || len(got.Places)+len(got.Registry)+len(got.Taxis)+len(got.Mappings)+len(got.Durations)+len(got.DwellSessions) != 0 {
+        t.Errorf("got %+v", got)
+    }
+}
+
+// Version 1 archives numbered taxis on mappings.
+func TestReadVersion1Mappings(t *testing.T) {
+    got, err := Read(archive(t, manifestOf(1), [2]string{mappingsFile, `{"id":1,"place_id":1,"taxi_id":42}` + "\n"}))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(got.Mappings) != 1 || got.Mappings[0].TaxiID != "42" {
+        t.Errorf("got %+v", got.Mappings)
+    }
+}
+
+func TestReadRejects(t *testing.T) {
+    tests := []struct {
+        name  string
+        input *bytes.Buffer
+        err   string
+    }{
+        {"not gzip", bytes.NewBufferString("places.ndjson"), "not a snapshot archive"},
+        {"no manifest", archive(t, [2]string{placesFile, ""}), "has no manifest.json"},
+        {"other format", archive(t, [2]string{manifestFile, `{"format":"backup","version":1}`}), `unexpected archive format "backup"`},
+        {"newer version", archive(t, manifestOf(Version + 1)), "unsupported snapshot version " + strconv.Itoa(Version+1)},
+        {"version 0", archive(t, manifestOf(0)), "unsupported snapshot version 0"},
+        {"invalid manifest", archive(t, [2]string{manifestFile, `{"format":`}), "invalid manifest"},
+        {"truncated place", archive(t, manifestOf(Version),
+            [2]string{placesFile, `{"place_id":1,"place_name":"Bandara"}` + "\n" + `{"place_id":2,"place_na`}), "invalid places.ndjson"},
+        {"truncated dwell session", archive(t, manifestOf(Version),
+            [2]string{sessionsFile, `{"id":1,"taxi_id":"T1","place_id":1,"started_at":"2026-03-01T`}), "invalid dwell_sessions.ndjson"},
+        {"mistyped taxi", archive(t, manifestOf(Version),
+            [2]string{taxisFile, `{"taxi_id":"T1","longitude":"east"}`}), "invalid taxis.ndjson"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            snap, err := Read(tt.input)
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("got %+v, %v, want error %q", snap, err, tt.err)
+            }
+        })
+    }
+}
+
+// An archive cut short fails rather than reading as a smaller snapshot.
+func TestReadTruncatedArchive(t *testing.T) {
+    var buf bytes.Buffer
+    snap := &models.Snapshot{CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+    for i := 0; i < 50; i++ {
+        snap.Taxis = append(snap.Taxis, models.TaxiLocation{TaxiID: "T" + strconv.Itoa(i), UpdatedAt: snap.CreatedAt})
+    }
+    if err := Write(&buf, snap); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := Read(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); err == nil {
+        t.Error("truncated archive read without an error")
+    }
+}
@@ -0,0 +1,156 @@
+// internal/validation/validation.go
+package validation
+
+import (
+    "fmt"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// FieldError describes one invalid field of a request payload.
+type FieldError struct {
+    Field   string `json:"field"`
+    Code    string `json:"code"`
+    Message string `json:"message"`
+}
+
+// Validator is implemented by field types that know how to check themselves,
+// such as polygons.
+type Validator interface {
+    Validate() error
+}
+
+// idPattern is the accepted format of string identifiers.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,63}$`)
+
+// IsID reports whether s has the accepted format of string identifiers.
+func IsID(s string) bool {
+    return idPattern.MatchString(s)
+}
+
+// Struct validates a struct against the rules in its `validate` tags and
+// returns one error per failing field, named after its JSON key. Rules are
+// comma-separated:
+//
+//	required   the field is not its zero value
+//	min=N      a number is at least N
+//	max=N      a number is at most N
+//	maxlen=N   a string has at most N characters
+//	lat        a number is a latitude in [-90, 90]
+//	lon        a number is a longitude in [-180, 180]
+//	id         a string matches the identifier format
+//	oneof=a|b  a non-empty string is one of the listed values
+//
+// Fields whose type implements Validator are also checked with Validate.
+func Struct(v interface{}) []FieldError {
+    rv := reflect.Indirect(reflect.ValueOf(v))
+    rt := rv.Type()
+
+    var errs []FieldError
+    for i := 0; i < rt.NumField(); i++ {
+        sf := rt.Field(i)
+        if !sf.IsExported() {
+            continue
+        }
+        fv := rv.Field(i)
+        name := jsonName(sf)
+
+        if sf.Anonymous && fv.Kind() == reflect.Struct {
+            errs = append(errs, Struct(fv.Interface())...)
+            continue
+        }
+
+        if err := checkRules(fv, sf.Tag.Get("validate")); err != nil {
+            err.Field = name
+            errs = append(errs, *err)
+            continue
+        }
+
+        if fv.IsZero() {
+            continue
+        }
+        if validator, ok := fv.Interface().(Validator); ok {
+            if err := validator.Validate(); err != nil {
+                errs = append(errs, FieldError{Field: name, Code: "invalid", Message: err.Error()})
+            }
+        }
+    }
+    return errs
+}
+
+func checkRules(fv reflect.Value, tag string) *FieldError {
+    if tag == "" {
+        return nil
+    }
+
+    for _, rule := range strings.Split(tag, ",") {
+        name, arg, _ := strings.Cut(rule, "=")
+        switch name {
+        case "required":
+            if fv.IsZero() {
+                return &FieldError{Code: "required", Message: "is required"}
+            }
+        case "min", "max":
+            limit, _ := strconv.ParseFloat(arg, 64)
+            n, ok := number(fv)
+            if !ok {
+                continue
+            }
+            if name == "min" && n < limit {
+                return &FieldError{Code: "out_of_range", Message: fmt.Sprintf("must be at least %s", arg)}
+            }
+            if name == "max" && n > limit {
+                return &FieldError{Code: "out_of_range", Message: fmt.Sprintf("must be at most %s", arg)}
+            }
+        case "maxlen":
+            limit, _ := strconv.Atoi(arg)
+            if fv.Kind() == reflect.String && len([]rune(fv.String())) > limit {
+                return &FieldError{Code: "too_long", Message: fmt.Sprintf("must be at most %d characters", limit)}
+            }
+        case "lat", "lon":
+            limit := 90.0
+            if name == "lon" {
+                limit = 180
+            }
+            if n, ok := number(fv); ok && (n < -limit || n > limit) {
+                return &FieldError{Code: "out_of_range", Message: fmt.Sprintf("must be between -%v and %v", limit, limit)}
+            }
+        case "oneof":
+            if fv.Kind() == reflect.String && fv.String() != "" {
+                allowed := strings.Split(arg, "|")
+                found := false
+                for _, a := range allowed {
+                    found = found || fv.String() == a
+                }
+                if !found {
+                    return &FieldError{Code: "invalid_value", Message: "must be one of " + strings.Join(allowed, ", ")}
+                }
+            }
+        case "id":
+            if fv.Kind() == reflect.String && fv.String() != "" && !idPattern.MatchString(fv.String()) {
+                return &FieldError{Code: "invalid_format", Message: "must be 1-64 letters, digits, '_', '-', '.' or ':' and start with a letter or digit"}
+            }
+        }
+    }
+    return nil
+}
+
+func number(fv reflect.Value) (float64, bool) {
+    switch fv.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(fv.Int()), true
+    case reflect.Float32, reflect.Float64:
+        return fv.Float(), true
+    }
+    return 0, false
+}
+
+func jsonName(sf reflect.StructField) string {
+    name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+    if name == "" || name == "-" {
+        return sf.Name
+    }
+    return name
+}
@@ -0,0 +1,179 @@
+// internal/validation/validation_test.go
+package validation
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+type area struct {
+    Rings int
+}
+
+func (a area) Validate() error {
+    if a.Rings < 0 {
+        return errors.New("negative ring count")
+    }
+    return nil
+}
+
+type Common struct {
+    Operator string  `json:"operator" validate:"required,id"`
+}
+
+type request struct {
+    Common
+    Name     string  `json:"name" validate:"required,maxlen=5"`
+    Count    int     `json:"count" validate:"min=1,max=10"`
+    Ratio    float64 `json:"ratio,omitempty" validate:"min=0.5"`
+    Lat      float64 `json:"lat" validate:"lat"`
+    Lon      float64 `json:"lon" validate:"lon"`
+    Kind     string  `json:"kind" validate:"oneof=car|van"`
+    TaxiID   string  `json:"taxi_id" validate:"id"`
+    Note     *string `json:"note" validate:"required"`
+    Area     area    `json:"area"`
+    Loose    string  `json:"-" validate:"required"`
+    Unknown  string  `json:"unknown" validate:"uppercase,maxlen=2"`
+    internal string  `validate:"required"`
+}
+
+// valid returns a request that passes every rule.
+func valid() request {
+    note := "n"
+    return request{
+        Common: Common{Operator: "op-1"},
+        Name:   "Gate",
+        Count:  5,
+        Ratio:  1,
+        Lat:    -6.2,
+        Lon:    106.8,
+        Note:   &note,
+        Loose:  "x",
+    }
+}
+
+func TestStruct(t *testing.T) {
+    tests := []struct {
+        name   string
+        change func(r *request)
+        want   []FieldError // Message is not compared
+    }{
+        {"valid", func(r *request) {}, nil},
+
+        {"required string", func(r *request) { r.Name = "" }, []FieldError{{Field: "name", Code: "required"}}},
+        {"required pointer", func(r *request) { r.Note = nil }, []FieldError{{Field: "note", Code: "required"}}},
+        {"required pointer to a zero value", func(r *request) { empty := ""; r.Note = &empty }, nil},
+        {"required in an embedded struct", func(r *request) { r.Operator = "" }, []FieldError{{Field: "operator", Code: "required"}}},
+        {"field named json -", func(r *request) { r.Loose = "" }, []FieldError{{Field: "Loose", Code: "required"}}},
+
+        {"zero without required", func(r *request) { r.Kind, r.TaxiID, r.Lat, r.Lon = "", "", 0, 0 }, nil},
+        {"zero below min without required", func(r *request) { r.Count = 0 }, []FieldError{{Field: "count", Code: "out_of_range"}}},
+
+        {"min", func(r *request) { r.Count = 1 }, nil},
+        {"below min", func(r *request) { r.Count = -1 }, []FieldError{{Field: "count", Code: "out_of_range"}}},
+        {"max", func(r *request) { r.Count = 10 }, nil},
+        {"above max", func(r *request) { r.Count = 11 }, []FieldError{{Field: "count", Code: "out_of_range"}}},
+        {"fractional min", func(r *request) { r.Ratio = 0.4 }, []FieldError{{Field: "ratio", Code: "out_of_range"}}},
+
+        {"maxlen", func(r *request) { r.Name = "Gates" }, nil},
+        {"above maxlen", func(r *request) { r.Name = "Gate 7" }, []FieldError{{Field: "name", Code: "too_long"}}},
+        {"maxlen counts characters", func(r *request) { r.Name = "Gérbä" }, nil},
+
+        {"lat bounds", func(r *request) { r.Lat, r.Lon = -90, 180 }, nil},
+        {"lat out of range", func(r *request) { r.Lat = 90.1 }, []FieldError{{Field: "lat", Code: "out_of_range"}}},
+        {"lon out of range", func(r *request) { r.Lon = -180.1 }, []FieldError{{Field: "lon", Code: "out_of_range"}}},
+
+        {"oneof", func(r *request) { r.Kind = "van" }, nil},
+        {"not oneof", func(r *request) { r.Kind = "bus" }, []FieldError{{Field: "kind", Code: "invalid_value"}}},
+        {"oneof is case sensitive", func(r *request) { r.Kind = "Car" }, []FieldError{{Field: "kind", Code: "invalid_value"}}},
+
+        {"id", func(r *request) { r.TaxiID = "T-1" }, nil},
+        {"bad id", func(r *request) { r.TaxiID = "T 1" }, []FieldError{{Field: "taxi_id", Code: "invalid_format"}}},
+        {"bad id in an embedded struct", func(r *request) { r.Operator = "-op" }, []FieldError{{Field: "operator", Code: "invalid_format"}}},
+
+        {"unknown rule is ignored", func(r *request) { r.Unknown = "ab" }, nil},
+        {"rules after an unknown rule apply", func(r *request) { r.Unknown = "abc" }, []FieldError{{Field: "unknown", Code: "too_long"}}},
+        {"unexported field is ignored", func(r *request) { r.internal = "" }, nil},
+
+        {"validator", func(r *request) { r.Area.Rings = -1 }, []FieldError{{Field: "area", Code: "invalid"}}},
+
+        {"one error per field", func(r *request) { r.Name = ""; r.Count = 0; r.Kind = "bus" }, []FieldError{
+            {Field: "name", Code: "required"},
+            {Field: "count", Code: "out_of_range"},
+            {Field: "kind", Code: "invalid_value"},
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := valid()
+            tt.change(&r)
+
+            got := Struct(r)
+            for i := range got {
+                if got[i].Message == "" {
+                    t.Errorf("%s has no message", got[i].Field)
+                }
+                got[i].Message = ""
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("got %+v, want %+v", got, tt.want)
+            }
+
+            // A pointer to the struct is validated the same way
+            ptr := Struct(&r)
+            if len(ptr) != len(got) {
+                t.Errorf("pointer got %+v, value got %+v", ptr, got)
+            }
+        })
+    }
+}
+
+func TestStructMessages(t *testing.T) {
+    r := valid()
+    r.Count, r.Lat, r.Kind, r.Area.Rings = 11, 91, "bus", -1
+    messages := map[string]string{}
+    for _, e := range Struct(r) {
+        messages[e.Field] = e.Message
+    }
+    want := map[string]string{
+        "count": "must be at most 10",
+        "lat":   "must be between -90 and 90",
+        "kind":  "must be one of car, van",
+        "area":  "negative ring count",
+    }
+    if !reflect.DeepEqual(messages, want) {
+        t.Errorf("got %v, want %v", messages, want)
+    }
+}
+
+func TestIsID(t *testing.T) {
+    tests := []struct {
+        id string
+        ok bool
+    }{
+        {"T1", true},
+        {"a", true},
+        {"7", true},
+        {"taxi_01.jkt:north-2", true},
+        {strings.Repeat("a", 64), true},
+        {strings.Repeat("a", 65), false},
+        {"", false},
+        {"-T1", false},
+        {"_T1", false},
+        {".T1", false},
+        {":T1", false},
+        {"T 1", false},
+        {"T/1", false},
+        {"taksi-é", false},
+        {"T1\n", false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.id, func(t *testing.T) {
+            if got := IsID(tt.id); got != tt.ok {
+                t.Errorf("IsID(%q) = %v, want %v", tt.id, got, tt.ok)
+            }
+        })
+    }
+}
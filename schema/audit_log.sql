@@ -0,0 +1,18 @@
+-- Every create, update and delete made through the API, with the state of
+-- the entity before and after the change.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor VARCHAR(255) NOT NULL,
+    actor_role VARCHAR(16) NOT NULL,
+    operator_id VARCHAR(64),
+    request_id VARCHAR(64),
+    action VARCHAR(16) NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(64),
+    before JSONB,
+    after JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at, id);
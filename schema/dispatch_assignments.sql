@@ -0,0 +1,34 @@
+-- Dispatch assignments: a mapping made for a booking is offered to the taxi
+-- at the head of a place's queue and moves through offered, accepted,
+-- picked_up or cancelled. Mappings made directly by a dispatcher start out
+-- accepted.
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'accepted';
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS booking_ref VARCHAR(255);
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS pickup_note TEXT;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS offered_at TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS picked_up_at TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS cancel_reason VARCHAR(16);
+ALTER TABLE mapping DROP CONSTRAINT IF EXISTS mapping_status_check;
+ALTER TABLE mapping ADD CONSTRAINT mapping_status_check
+    CHECK (status IN ('offered', 'accepted', 'picked_up', 'cancelled'));
+
+-- A taxi holds at most one open offer at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mapping_offered_taxi ON mapping (taxi_id) WHERE status = 'offered';
+CREATE INDEX IF NOT EXISTS idx_mapping_booking ON mapping (booking_ref) WHERE booking_ref IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_mapping_expires ON mapping (expires_at) WHERE status = 'offered';
+
+-- Every status change of a mapping, oldest first
+CREATE TABLE IF NOT EXISTS mapping_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    mapping_id INTEGER NOT NULL REFERENCES mapping(id) ON DELETE CASCADE,
+    from_status VARCHAR(16),
+    to_status VARCHAR(16) NOT NULL,
+    reason VARCHAR(16),
+    actor VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mapping_transitions_mapping ON mapping_transitions (mapping_id, id);
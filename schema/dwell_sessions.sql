@@ -0,0 +1,21 @@
+-- Dwell sessions: one row per stay of a taxi inside a place. A taxi has at
+-- most one open session (ended_at IS NULL) at a time.
+CREATE TABLE IF NOT EXISTS dwell_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    taxi_id VARCHAR(255) NOT NULL REFERENCES taxis(taxi_id) ON DELETE CASCADE,
+    place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    place_version INTEGER,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dwell_sessions_open ON dwell_sessions (taxi_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_dwell_sessions_place ON dwell_sessions (place_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_dwell_sessions_taxi ON dwell_sessions (taxi_id, started_at);
+
+-- Open a session for every taxi currently inside a place
+INSERT INTO dwell_sessions (taxi_id, place_id, place_version, started_at)
+SELECT d.taxi_id, d.place_id, d.place_version, d.updated_at
+FROM taxi_durations d
+WHERE d.place_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM dwell_sessions s WHERE s.taxi_id = d.taxi_id AND s.ended_at IS NULL);
@@ -0,0 +1,25 @@
+-- Events raised by the scheduler, such as a taxi going offline. Each event
+-- belongs to the operator of its taxi.
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    type VARCHAR(64) NOT NULL,
+    operator_id VARCHAR(64) REFERENCES operators(operator_id),
+    taxi_id VARCHAR(255) REFERENCES taxis(taxi_id) ON DELETE CASCADE,
+    place_id INTEGER REFERENCES places(place_id) ON DELETE SET NULL,
+    place_version INTEGER,
+    data JSONB
+);
+
+-- Events about a place reference the place version in effect when raised
+ALTER TABLE events ADD COLUMN IF NOT EXISTS place_version INTEGER;
+UPDATE events e SET place_version = v.version
+FROM place_versions v
+WHERE e.place_version IS NULL AND v.place_id = e.place_id
+  AND v.valid_from <= e.created_at AND (v.valid_to IS NULL OR v.valid_to > e.created_at);
+
+CREATE INDEX IF NOT EXISTS idx_events_type ON events (type, created_at);
+CREATE INDEX IF NOT EXISTS idx_events_taxi ON events (taxi_id, created_at);
+
+-- Last presence status the scheduler saw for each taxi, to detect changes
+ALTER TABLE taxi_location ADD COLUMN IF NOT EXISTS presence VARCHAR(16) NOT NULL DEFAULT 'online';
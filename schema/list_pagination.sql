@@ -0,0 +1,17 @@
+
+-- Track modification times on every listed resource so list endpoints can
+-- sort and sync by updated_at.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE mapping ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE taxi_location SET updated_at = CURRENT_TIMESTAMP WHERE updated_at IS NULL;
+UPDATE places SET updated_at = CURRENT_TIMESTAMP WHERE updated_at IS NULL;
+UPDATE mapping SET updated_at = CURRENT_TIMESTAMP WHERE updated_at IS NULL;
+
+ALTER TABLE taxi_location ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE places ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE mapping ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_taxi_location_updated_at ON taxi_location (updated_at, taxi_id);
+CREATE INDEX IF NOT EXISTS idx_places_updated_at ON places (updated_at, place_id);
+CREATE INDEX IF NOT EXISTS idx_mapping_updated_at ON mapping (updated_at, id);
@@ -0,0 +1,17 @@
+-- Occupancy samples per place, aggregated into minute, hour and day buckets.
+-- The scheduler adds samples to minute buckets; compaction rolls closed
+-- buckets into the next resolution and marks them rolled_up, after which they
+-- may be removed once past their retention.
+CREATE TABLE IF NOT EXISTS occupancy_samples (
+    place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    resolution VARCHAR(16) NOT NULL CHECK (resolution IN ('minute', 'hour', 'day')),
+    bucket_start TIMESTAMP NOT NULL,
+    samples INTEGER NOT NULL,
+    min_count INTEGER NOT NULL,
+    max_count INTEGER NOT NULL,
+    sum_count BIGINT NOT NULL,
+    rolled_up BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (place_id, resolution, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_occupancy_samples_pending ON occupancy_samples (resolution, bucket_start) WHERE NOT rolled_up;
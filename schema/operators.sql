@@ -0,0 +1,26 @@
+-- Operators are the taxi companies sharing the platform. Every taxi belongs
+-- to one operator; places are either shared (no operator) or private to one.
+-- Operators are created the first time a taxi or place is stored for them.
+CREATE TABLE IF NOT EXISTS operators (
+    operator_id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing taxis are assigned to a default operator.
+INSERT INTO operators (operator_id, name) VALUES ('default', 'Default operator')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE taxis ADD COLUMN IF NOT EXISTS operator_id VARCHAR(64);
+UPDATE taxis SET operator_id = 'default' WHERE operator_id IS NULL;
+ALTER TABLE taxis ALTER COLUMN operator_id SET NOT NULL;
+ALTER TABLE taxis DROP CONSTRAINT IF EXISTS taxis_operator_id_fkey;
+ALTER TABLE taxis
+    ADD CONSTRAINT taxis_operator_id_fkey FOREIGN KEY (operator_id) REFERENCES operators(operator_id);
+
+-- Places stay shared unless an operator is set.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS operator_id VARCHAR(64)
+    REFERENCES operators(operator_id);
+
+CREATE INDEX IF NOT EXISTS idx_taxis_operator ON taxis (operator_id, taxi_id);
+CREATE INDEX IF NOT EXISTS idx_places_operator ON places (operator_id);
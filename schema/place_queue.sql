@@ -0,0 +1,11 @@
+
+CREATE TABLE IF NOT EXISTS place_queue (
+    taxi_id VARCHAR(255) PRIMARY KEY,
+    place_id INTEGER NOT NULL,
+    entered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP,
+    FOREIGN KEY (taxi_id) REFERENCES taxi_location(taxi_id) ON DELETE CASCADE,
+    FOREIGN KEY (place_id) REFERENCES places(place_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_place_queue_place ON place_queue (place_id, entered_at);
@@ -0,0 +1,29 @@
+-- Place types and per-place rules, and the violations the scheduler records
+-- when a taxi breaks them.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS place_type VARCHAR(32) NOT NULL DEFAULT 'stand';
+ALTER TABLE places ADD COLUMN IF NOT EXISTS rules JSONB;
+ALTER TABLE places DROP CONSTRAINT IF EXISTS places_place_type_check;
+ALTER TABLE places ADD CONSTRAINT places_place_type_check
+    CHECK (place_type IN ('stand', 'no_parking', 'depot', 'holding_lot'));
+
+CREATE TABLE IF NOT EXISTS violations (
+    id BIGSERIAL PRIMARY KEY,
+    taxi_id VARCHAR(255) NOT NULL REFERENCES taxis(taxi_id) ON DELETE CASCADE,
+    place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    place_version INTEGER,
+    dwell_session_id BIGINT REFERENCES dwell_sessions(id) ON DELETE SET NULL,
+    rule VARCHAR(32) NOT NULL,
+    message TEXT NOT NULL,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (dwell_session_id, rule)
+);
+
+-- Violations reference the place version in effect when detected
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS place_version INTEGER;
+UPDATE violations x SET place_version = v.version
+FROM place_versions v
+WHERE x.place_version IS NULL AND v.place_id = x.place_id
+  AND v.valid_from <= x.detected_at AND (v.valid_to IS NULL OR v.valid_to > x.detected_at);
+
+CREATE INDEX IF NOT EXISTS idx_violations_place ON violations (place_id, detected_at);
+CREATE INDEX IF NOT EXISTS idx_violations_taxi ON violations (taxi_id, detected_at);
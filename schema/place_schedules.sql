@@ -0,0 +1,3 @@
+-- Calendar schedules of places: weekly windows in a time zone with exception
+-- dates. Places without a schedule are always open.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS schedule JSONB;
@@ -0,0 +1,36 @@
+-- Place versioning: every change to a place's name or polygon starts a new
+-- version, so history can be read as it was at any time. The places row
+-- always holds the current version.
+CREATE TABLE IF NOT EXISTS place_versions (
+    place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    place_name VARCHAR(255) NOT NULL,
+    polygon JSONB NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP,
+    PRIMARY KEY (place_id, version),
+    CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+-- At most one open version per place.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_place_versions_current
+    ON place_versions (place_id) WHERE valid_to IS NULL;
+CREATE INDEX IF NOT EXISTS idx_place_versions_range
+    ON place_versions (place_id, valid_from, valid_to);
+
+ALTER TABLE places ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Existing places become version 1, valid since tracking began.
+INSERT INTO place_versions (place_id, version, place_name, polygon, valid_from)
+SELECT place_id, 1, place_name, polygon, TIMESTAMP '1970-01-01'
+FROM places
+ON CONFLICT DO NOTHING;
+
+-- Dwell and queue records reference the place version they happened in.
+ALTER TABLE taxi_durations ADD COLUMN IF NOT EXISTS place_version INTEGER;
+UPDATE taxi_durations d SET place_version = p.version
+FROM places p WHERE d.place_id = p.place_id;
+
+ALTER TABLE place_queue ADD COLUMN IF NOT EXISTS place_version INTEGER;
+UPDATE place_queue q SET place_version = p.version
+FROM places p WHERE q.place_id = p.place_id;
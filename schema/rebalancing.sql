@@ -0,0 +1,19 @@
+-- Rebalancing between stands. Places may declare how many taxis they hold
+-- and how many demand needs waiting there; after every scheduler run the
+-- recommended moves from overflowing places to places below target replace
+-- the previous set.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS capacity JSONB;
+
+CREATE TABLE IF NOT EXISTS recommendations (
+    id BIGSERIAL PRIMARY KEY,
+    rank INTEGER NOT NULL,
+    taxi_id VARCHAR(255) NOT NULL REFERENCES taxis(taxi_id) ON DELETE CASCADE,
+    from_place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    to_place_id INTEGER NOT NULL REFERENCES places(place_id) ON DELETE CASCADE,
+    distance_km DOUBLE PRECISION NOT NULL,
+    reason VARCHAR(32) NOT NULL CHECK (reason IN ('over_capacity', 'over_target')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (taxi_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recommendations_to_place ON recommendations (to_place_id);
@@ -0,0 +1,7 @@
+-- Soft delete: deleted places and taxi locations are hidden from lists and
+-- lookups until they are restored or purged.
+ALTER TABLE places ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE taxi_location ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_places_deleted_at ON places (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_taxi_location_deleted_at ON taxi_location (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,7 @@
+
+CREATE TABLE IF NOT EXISTS taxi_durations (
+    taxi_id VARCHAR(255) PRIMARY KEY,
+    place_id INTEGER,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (place_id) REFERENCES places(place_id)
+);
@@ -0,0 +1,79 @@
+-- Taxi registry: the single identity every location, mapping, queue and
+-- dwell record refers to. Safe to run more than once.
+CREATE TABLE IF NOT EXISTS taxis (
+    taxi_id VARCHAR(255) PRIMARY KEY,
+    plate_number VARCHAR(255) NOT NULL UNIQUE,
+    fleet VARCHAR(255),
+    vehicle_class VARCHAR(64) NOT NULL DEFAULT 'standard',
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'inactive', 'suspended')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Registries created by earlier runs used shorter columns than the IDs they
+-- are filled from.
+ALTER TABLE taxis ALTER COLUMN taxi_id TYPE VARCHAR(255);
+ALTER TABLE taxis ALTER COLUMN plate_number TYPE VARCHAR(255);
+
+-- Reconcile existing data. Taxi IDs double as plate numbers until the
+-- registry is edited.
+INSERT INTO taxis (taxi_id, plate_number)
+SELECT taxi_id, taxi_id FROM taxi_location
+ON CONFLICT DO NOTHING;
+
+INSERT INTO taxis (taxi_id, plate_number)
+SELECT taxi_id, taxi_id FROM taxi_durations
+ON CONFLICT DO NOTHING;
+
+INSERT INTO taxis (taxi_id, plate_number)
+SELECT DISTINCT taxi_id, taxi_id FROM taxi_mapping
+ON CONFLICT DO NOTHING;
+
+-- The legacy taxi table identified taxis by nomor_taxi, which is also the ID
+-- taxi_location and taxi_durations use, and mapping.taxi_id pointed at its
+-- integer id. Move mappings to the registry ID and drop the legacy table.
+-- Mappings whose taxi no longer exists keep their history under an inactive
+-- placeholder taxi named legacy-<id>.
+DO $$
+BEGIN
+    IF to_regclass('taxi') IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO taxis (taxi_id, plate_number)
+    SELECT nomor_taxi, nomor_taxi FROM taxi
+    ON CONFLICT DO NOTHING;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'mapping' AND column_name = 'taxi_id' AND data_type = 'integer') THEN
+        INSERT INTO taxis (taxi_id, plate_number, status)
+        SELECT DISTINCT 'legacy-' || m.taxi_id, 'legacy-' || m.taxi_id, 'inactive'
+        FROM mapping m
+        WHERE NOT EXISTS (SELECT 1 FROM taxi t WHERE t.id = m.taxi_id)
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE mapping ADD COLUMN IF NOT EXISTS registry_taxi_id VARCHAR(255);
+        UPDATE mapping m SET registry_taxi_id = COALESCE(
+            (SELECT t.nomor_taxi FROM taxi t WHERE t.id = m.taxi_id),
+            'legacy-' || m.taxi_id);
+        ALTER TABLE mapping DROP COLUMN taxi_id;
+        ALTER TABLE mapping RENAME COLUMN registry_taxi_id TO taxi_id;
+        ALTER TABLE mapping ALTER COLUMN taxi_id SET NOT NULL;
+    END IF;
+
+    DROP TABLE taxi;
+END $$;
+
+ALTER TABLE mapping DROP CONSTRAINT IF EXISTS mapping_taxi_id_fkey;
+ALTER TABLE mapping
+    ADD CONSTRAINT mapping_taxi_id_fkey FOREIGN KEY (taxi_id) REFERENCES taxis(taxi_id);
+ALTER TABLE taxi_location DROP CONSTRAINT IF EXISTS taxi_location_taxi_id_fkey;
+ALTER TABLE taxi_location
+    ADD CONSTRAINT taxi_location_taxi_id_fkey FOREIGN KEY (taxi_id) REFERENCES taxis(taxi_id) ON DELETE CASCADE;
+ALTER TABLE taxi_durations DROP CONSTRAINT IF EXISTS taxi_durations_taxi_id_fkey;
+ALTER TABLE taxi_durations
+    ADD CONSTRAINT taxi_durations_taxi_id_fkey FOREIGN KEY (taxi_id) REFERENCES taxis(taxi_id) ON DELETE CASCADE;
+ALTER TABLE taxi_mapping DROP CONSTRAINT IF EXISTS taxi_mapping_taxi_id_fkey;
+ALTER TABLE taxi_mapping
+    ADD CONSTRAINT taxi_mapping_taxi_id_fkey FOREIGN KEY (taxi_id) REFERENCES taxis(taxi_id) ON DELETE CASCADE;
@@ -0,0 +1,32 @@
+-- Violation lifecycle and evidence. A violation is open while the taxi is
+-- still inside the place and closed once it leaves, when its position trail
+-- is copied into evidence. Drivers and dispatchers may dispute it and admins
+-- resolve it.
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'open';
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS evidence JSONB;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS dispute_reason TEXT;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS disputed_by VARCHAR(255);
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS resolution VARCHAR(16);
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS resolution_note TEXT;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS resolved_by VARCHAR(255);
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE violations DROP CONSTRAINT IF EXISTS violations_status_check;
+ALTER TABLE violations ADD CONSTRAINT violations_status_check
+    CHECK (status IN ('open', 'closed', 'disputed', 'resolved'));
+
+CREATE INDEX IF NOT EXISTS idx_violations_status ON violations (status, detected_at);
+
+-- Every reported taxi position, for evidence trails and historical heatmaps
+CREATE TABLE IF NOT EXISTS taxi_positions (
+    id BIGSERIAL PRIMARY KEY,
+    taxi_id VARCHAR(255) NOT NULL REFERENCES taxis(taxi_id) ON DELETE CASCADE,
+    longitude DOUBLE PRECISION NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_taxi_positions_taxi ON taxi_positions (taxi_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_taxi_positions_recorded ON taxi_positions (recorded_at);